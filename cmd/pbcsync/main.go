@@ -1,14 +1,20 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"log/slog"
 	"os"
+	_ "time/tzdata"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/fakecloud"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/healthcheck"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/history"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/version"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
 )
 
 func main() {
@@ -16,11 +22,24 @@ func main() {
 
 	cmd := command.New()
 	cmd.AddCommand("sync", sync.New(factory.Factory))
+	cmd.AddCommand("status", sync.NewStatus(factory.StatusFactory))
+	cmd.AddCommand("list", sync.NewList(factory.ListFactory))
 	cmd.AddCommand("version", version.New())
+	cmd.AddCommand("healthcheck", healthcheck.New())
+	cmd.AddCommand("history", history.New())
+	cmd.AddHiddenCommand("fake-cloud", fakecloud.New())
 
 	if err := cmd.Run(os.Args[1:]); err != nil {
 		slog.Error(err.Error())
 
+		if errors.Is(err, shutdown.ErrAborted) {
+			os.Exit(shutdown.ExitCodeAborted)
+		}
+
+		if errors.Is(err, sync.ErrOutOfSync) {
+			os.Exit(sync.ExitCodeOutOfSync)
+		}
+
 		os.Exit(1)
 	}
 
@@ -1,6 +1,11 @@
 package sync
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+)
 
 type Option func(*App)
 
@@ -9,3 +14,19 @@ func WithDownloader(downloader func(ctx context.Context, url, destination string
 		app.downloader = downloader
 	}
 }
+
+// WithMetrics sets the metrics of synchronization runs.
+func WithMetrics(m *metrics.Metrics) func(app *App) {
+	return func(app *App) {
+		app.metrics = m
+	}
+}
+
+// WithLock guards the directory by the lock file during the synchronization.
+// wait is how long to wait for the lock held by another process, zero fails immediately.
+func WithLock(path string, wait time.Duration) func(app *App) {
+	return func(app *App) {
+		app.lockPath = path
+		app.lockWait = wait
+	}
+}
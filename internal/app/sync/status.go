@@ -0,0 +1,96 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/download"
+)
+
+// Status is the difference between the books in the cloud and the files in the directory.
+type Status struct {
+	// Total is how many books are in the cloud.
+	Total int `json:"total"`
+	// Local is how many files are in the directory.
+	Local int `json:"local"`
+	// Missing are the books of the cloud which aren't in the directory, they are downloaded by the sync.
+	Missing []string `json:"missing"`
+	// LocalOnly are the files of the directory which aren't in the cloud.
+	LocalOnly []string `json:"local_only"`
+	// Updated are the files whose size differs from the book in the cloud or which are older than it.
+	// The sync doesn't download them again.
+	Updated []string `json:"updated"`
+}
+
+// InSync reports whether every book of the cloud is in the directory.
+// The updated books don't count, the sync doesn't download them again.
+func (s Status) InSync() bool {
+	return len(s.Missing) == 0
+}
+
+// Status compares the books in the cloud with the files in the directory without downloading.
+// The hidden files and the partial downloads aren't counted as local.
+func (a App) Status(ctx context.Context) (Status, error) {
+	exist, err := readDir(a.dir)
+	if err != nil {
+		return Status{}, fmt.Errorf("read exists files: %w", err)
+	}
+
+	bks, err := a.books.Books(ctx)
+	if err != nil {
+		return Status{}, fmt.Errorf("get books: %w", err)
+	}
+
+	st := Status{
+		Total:     len(bks),
+		Missing:   []string{},
+		LocalOnly: []string{},
+		Updated:   []string{},
+	}
+
+	cloud := make(map[string]struct{}, len(bks))
+
+	for _, bk := range bks {
+		name := norm.NFC.String(bk.FileName)
+		cloud[name] = struct{}{}
+
+		entry, ok := exist.f[name]
+		if !ok {
+			st.Missing = append(st.Missing, bk.FileName)
+
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			slog.WarnContext(ctx, "failed to stat file", "path", entry.Name(), "error", err)
+
+			continue
+		}
+
+		if bk.Size > 0 && info.Size() != bk.Size || info.ModTime().Before(bk.Updated) {
+			st.Updated = append(st.Updated, bk.FileName)
+		}
+	}
+
+	for name, entry := range exist.f {
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, download.PartSuffix) {
+			continue
+		}
+
+		st.Local++
+
+		if _, ok := cloud[name]; !ok {
+			st.LocalOnly = append(st.LocalOnly, entry.Name())
+		}
+	}
+
+	slices.Sort(st.LocalOnly)
+
+	return st, nil
+}
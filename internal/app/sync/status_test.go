@@ -0,0 +1,104 @@
+package sync_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync/mocks"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+)
+
+func TestApp_Status(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	modTime := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	for name, content := range map[string]string{
+		"same.epub":         "1234",
+		"resized.epub":      "12",
+		"outdated.epub":     "1234",
+		"local.pdf":         "1234",
+		"partial.epub.part": "12",
+		lock.FileName:       "1",
+	} {
+		path := filepath.Join(dir, name)
+
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".pbcsync"), 0o700))
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{
+			{FileName: "same.epub", Size: 4, Updated: modTime.Add(-time.Hour)},
+			{FileName: "resized.epub", Size: 4},
+			{FileName: "outdated.epub", Size: 4, Updated: modTime.Add(time.Hour)},
+			{FileName: "missing.epub", Size: 4},
+		}, nil)
+
+	st, err := sync.New(booksMock, dir).Status(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, sync.Status{
+		Total:     4,
+		Local:     4,
+		Missing:   []string{"missing.epub"},
+		LocalOnly: []string{"local.pdf"},
+		Updated:   []string{"resized.epub", "outdated.epub"},
+	}, st)
+	assert.False(t, st.InSync())
+}
+
+func TestApp_Status_InSync(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{{FileName: "exist.txt"}}, nil)
+
+	st, err := sync.New(booksMock, "testdata").Status(t.Context())
+	require.NoError(t, err)
+
+	assert.True(t, st.InSync())
+	assert.Equal(t, []string{"й.txt"}, st.LocalOnly)
+}
+
+func TestStatus_InSync(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		status sync.Status
+		expect bool
+	}{
+		{name: "empty", expect: true},
+		{name: "local only", status: sync.Status{LocalOnly: []string{"local.pdf"}}, expect: true},
+		{name: "updated", status: sync.Status{Updated: []string{"outdated.epub"}}, expect: true},
+		{name: "missing", status: sync.Status{Missing: []string{"missing.epub"}}, expect: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expect, tt.status.InSync())
+		})
+	}
+}
@@ -2,16 +2,25 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/text/unicode/norm"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/download"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
 )
 
 //go:generate mockgen -source $GOFILE -typed -destination mocks/$GOFILE -package mocks -typed -mock_names books=Books
@@ -23,6 +32,9 @@ type App struct {
 	books      books
 	dir        string
 	downloader func(ctx context.Context, url, destination string) error
+	metrics    *metrics.Metrics
+	lockPath   string
+	lockWait   time.Duration
 }
 
 func New(books books, dir string, opts ...Option) *App {
@@ -39,21 +51,44 @@ func New(books books, dir string, opts ...Option) *App {
 	return a
 }
 
-func (a App) Sync(ctx context.Context) error {
+func (a App) Sync(ctx context.Context) (err error) {
+	defer func() {
+		// The runs stopped by the shutdown or the cancellation are neither successful nor failed.
+		if !errors.Is(err, shutdown.ErrDrained) && ctx.Err() == nil {
+			a.metrics.RunFinished(time.Now(), err)
+		}
+	}()
+
+	if a.lockPath != "" {
+		l, err := lock.Acquire(ctx, a.lockPath, a.lockWait)
+		if err != nil {
+			return fmt.Errorf("lock directory: %w", err)
+		}
+
+		defer func() {
+			if err := l.Release(); err != nil {
+				slog.WarnContext(ctx, "failed to release lock", "error", err)
+			}
+		}()
+	}
+
 	exist, err := readDir(a.dir)
 	if err != nil {
 		return fmt.Errorf("read exists files: %w", err)
 	}
 
-	slog.Info("start sync")
+	slog.InfoContext(ctx, "start sync")
 
 	bks, err := a.books.Books(ctx)
 	if err != nil {
 		return fmt.Errorf("get books: %w", err)
 	}
 
+	rep := report.FromContext(ctx)
+	rep.SetTotal(len(bks))
+
 	if len(bks) == 0 {
-		slog.Warn("no books found")
+		slog.WarnContext(ctx, "no books found")
 
 		return nil
 	}
@@ -61,28 +96,54 @@ func (a App) Sync(ctx context.Context) error {
 	var skipped int
 
 	for _, bk := range bks {
+		// The running download is finished, but no new ones are started.
+		if shutdown.IsDraining(ctx) {
+			slog.InfoContext(ctx, "sync stopped by shutdown", "total", len(bks))
+
+			return shutdown.ErrDrained
+		}
+
 		if exist.exist(bk.FileName) {
-			slog.Debug("skipped book, this is exists", "name", bk.FileName)
+			slog.DebugContext(ctx, "skipped book, this is exists", logging.Book(bk.FileName))
 
 			skipped++
 
+			rep.Skip(bk.FileName)
+			a.metrics.BookSkipped()
+
 			continue
 		}
 
 		path := filepath.Join(a.dir, bk.FileName)
 
-		slog.Debug("download", "file_name", bk.FileName, "path", path, "link", bk.Link)
+		slog.DebugContext(ctx, "download", logging.Book(bk.FileName), "path", path, "link", redact.URL(bk.Link))
+
+		start := time.Now()
 
 		if err = a.downloader(ctx, bk.Link, path); err != nil {
+			rep.Fail(bk.FileName, err)
+
 			return fmt.Errorf("download %s: %w", bk.FileName, err)
 		}
+
+		rep.Download(bk.FileName, fileSize(path), time.Since(start))
 	}
 
-	slog.Info("finished sync", "total", len(bks), "skipped", skipped)
+	slog.InfoContext(ctx, "finished sync", "total", len(bks), "skipped", skipped)
 
 	return nil
 }
 
+// fileSize returns the size of the downloaded file, it is zero if the downloader hasn't created the file.
+func fileSize(path string) int64 {
+	st, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return st.Size()
+}
+
 func readDir(dir string) (files, error) {
 	f := files{}
 
@@ -91,19 +152,20 @@ func readDir(dir string) (files, error) {
 		return files{}, fmt.Errorf("read dir: %w", err)
 	}
 
-	f.f = make(map[string]struct{}, len(fls))
+	f.f = make(map[string]fs.DirEntry, len(fls))
 
 	for _, fl := range fls {
 		if !fl.IsDir() {
-			f.f[norm.NFC.String(fl.Name())] = struct{}{}
+			f.f[norm.NFC.String(fl.Name())] = fl
 		}
 	}
 
 	return f, nil
 }
 
+// files is the index of the files in the directory by the normalized names.
 type files struct {
-	f map[string]struct{}
+	f map[string]fs.DirEntry
 }
 
 func (e files) exist(file string) bool {
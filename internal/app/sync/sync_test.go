@@ -1,15 +1,28 @@
 package sync_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
 	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync/mocks"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
 )
 
 func TestApp_Sync(t *testing.T) {
@@ -116,3 +129,201 @@ func TestApp_Sync_EmptyBooks(t *testing.T) {
 	err := app.Sync(t.Context())
 	assert.NoError(t, err)
 }
+
+func TestApp_Sync_Drained(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	ctx, drain := shutdown.WithDrain(t.Context())
+
+	var downloaded []string
+
+	m := metrics.New()
+
+	opts := []sync.Option{
+		sync.WithDownloader(func(_ context.Context, _, destination string) error {
+			downloaded = append(downloaded, destination)
+
+			// The shutdown starts in the middle of the download.
+			drain()
+
+			return nil
+		}),
+		sync.WithMetrics(m),
+	}
+
+	app := sync.New(booksMock, "testdata", opts...)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{
+			{FileName: "first.txt", Link: "https://foo/first"},
+			{FileName: "second.txt", Link: "https://foo/second"},
+		}, nil)
+
+	err := app.Sync(ctx)
+	assert.ErrorIs(t, err, shutdown.ErrDrained)
+	assert.Equal(t, []string{"testdata/first.txt"}, downloaded)
+	assertNoRuns(t, m)
+}
+
+func TestApp_Sync_Canceled(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	m := metrics.New()
+	app := sync.New(booksMock, "testdata", sync.WithMetrics(m))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return(nil, context.Canceled)
+
+	err := app.Sync(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assertNoRuns(t, m)
+}
+
+// assertNoRuns checks that the interrupted run isn't counted by the result.
+func assertNoRuns(t *testing.T, m *metrics.Metrics) {
+	t.Helper()
+
+	var b strings.Builder
+
+	_, err := m.Registry().WriteTo(&b)
+	require.NoError(t, err)
+
+	assert.Contains(t, b.String(), `pbcsync_runs_total{result="error"} 0`+"\n")
+	assert.Contains(t, b.String(), `pbcsync_runs_total{result="success"} 0`+"\n")
+}
+
+func TestApp_Sync_Report(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	opts := []sync.Option{
+		sync.WithDownloader(func(context.Context, string, string) error { return nil }),
+	}
+
+	app := sync.New(booksMock, "testdata", opts...)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{
+			{FileName: "exist.txt", Link: "https://foo/exist"},
+			{FileName: "first.txt", Link: "https://foo/first"},
+			{FileName: "second.txt", Link: "https://foo/second"},
+		}, nil)
+
+	collector := &report.Collector{}
+
+	err := app.Sync(report.NewContext(t.Context(), collector))
+	assert.NoError(t, err)
+	assert.Equal(t, report.Counts{Total: 3, Skipped: 1, Downloaded: 2}, collector.Counts())
+	assert.Equal(t, []string{"first.txt", "second.txt"}, collector.Books())
+}
+
+func TestApp_Sync_Report_Outcomes(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	dir := t.TempDir()
+	errDownload := errors.New("connection reset")
+
+	opts := []sync.Option{
+		sync.WithDownloader(func(_ context.Context, url, destination string) error {
+			if url == "https://foo/second" {
+				return errDownload
+			}
+
+			return os.WriteFile(destination, []byte("content"), 0o600)
+		}),
+	}
+
+	app := sync.New(booksMock, dir, opts...)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{
+			{FileName: "first.txt", Link: "https://foo/first"},
+			{FileName: "second.txt", Link: "https://foo/second"},
+		}, nil)
+
+	collector := &report.Collector{}
+
+	err := app.Sync(report.NewContext(t.Context(), collector))
+	require.ErrorIs(t, err, errDownload)
+
+	results := collector.Results()
+	require.Len(t, results, 2)
+
+	assert.Equal(t, report.OutcomeDownloaded, results[0].Outcome)
+	assert.Equal(t, int64(len("content")), results[0].Bytes)
+	assert.Equal(t, report.Book{Name: "second.txt", Outcome: report.OutcomeFailed, Reason: "connection reset"}, results[1])
+}
+
+func TestApp_Sync_Locked(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	path := filepath.Join(t.TempDir(), lock.FileName)
+
+	held, err := lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = held.Release() })
+
+	app := sync.New(booksMock, "testdata", sync.WithLock(path, 0))
+
+	err = app.Sync(t.Context())
+	require.ErrorIs(t, err, lock.ErrLocked)
+	require.ErrorContains(t, err, "lock directory: directory is locked by PID")
+}
+
+func TestApp_Sync_Redaction(t *testing.T) {
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	var buf bytes.Buffer
+
+	// The handler without the redaction checks that the sync doesn't rely on it.
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	// The closed server refuses the download, so the error has the link.
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	const secret = "s3cr3t-signature"
+
+	mockCtrl := gomock.NewController(t)
+	booksMock := mocks.NewBooks(mockCtrl)
+
+	booksMock.EXPECT().
+		Books(gomock.Any()).
+		Return([]domain.Book{
+			{FileName: "first.txt", Link: srv.URL + "/first.txt?X-Amz-Signature=" + secret},
+		}, nil)
+
+	app := sync.New(booksMock, "testdata")
+
+	err := app.Sync(t.Context())
+	require.ErrorContains(t, err, "X-Amz-Signature=REDACTED")
+	assert.NotContains(t, err.Error(), secret)
+
+	slog.Error("sync failed", "error", err)
+
+	assert.Contains(t, buf.String(), "level=DEBUG msg=download")
+	assert.NotContains(t, buf.String(), secret)
+}
@@ -0,0 +1,76 @@
+// Package apiurl allows to use the PocketBook Cloud API from another base URL,
+// e.g. a regional endpoint, a caching proxy or a local stand-in server.
+package apiurl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pc "github.com/micronull/pocketbook-cloud-client"
+)
+
+// Default is the base URL used by the PocketBook Cloud client.
+const Default = pc.DefaultScheme + "://" + pc.DefaultHost + pc.DefaultPath
+
+var errInvalidURL = errors.New("invalid api url")
+
+// Parse validates the base URL of the API.
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidURL, err)
+	}
+
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("%w: scheme must be http or https", errInvalidURL)
+	case u.Host == "":
+		return nil, fmt.Errorf("%w: host is required", errInvalidURL)
+	case u.RawQuery != "" || u.Fragment != "":
+		return nil, fmt.Errorf("%w: query and fragment are not allowed", errInvalidURL)
+	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	return u, nil
+}
+
+// Transport redirects requests to the default API base URL to another base URL.
+// Other requests are passed as is.
+type Transport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+// NewTransport construct for [Transport].
+// next - transport to perform requests, [http.DefaultTransport] if nil.
+func NewTransport(base *url.URL, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{
+		base: base,
+		next: next,
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != pc.DefaultHost || !strings.HasPrefix(req.URL.Path, pc.DefaultPath) {
+		return t.next.RoundTrip(req)
+	}
+
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.base.Scheme
+	r.URL.Host = t.base.Host
+	r.URL.Path = t.base.Path + strings.TrimPrefix(req.URL.Path, pc.DefaultPath)
+	r.URL.RawPath = ""
+	r.Host = ""
+
+	return t.next.RoundTrip(r)
+}
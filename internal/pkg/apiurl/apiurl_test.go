@@ -0,0 +1,101 @@
+package apiurl_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/apiurl"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		raw      string
+		expected string
+		err      string
+	}{
+		{
+			name:     "default",
+			raw:      apiurl.Default,
+			expected: apiurl.Default,
+		},
+		{
+			name:     "trailing slash",
+			raw:      "http://localhost:8080/api",
+			expected: "http://localhost:8080/api/",
+		},
+		{
+			name: "scheme",
+			raw:  "ftp://localhost/api/",
+			err:  "invalid api url: scheme must be http or https",
+		},
+		{
+			name: "host",
+			raw:  "https:///api/",
+			err:  "invalid api url: host is required",
+		},
+		{
+			name: "query",
+			raw:  "https://localhost/api/?foo=bar",
+			err:  "invalid api url: query and fragment are not allowed",
+		},
+		{
+			name: "malformed",
+			raw:  "https://local host/",
+			err:  `invalid api url: parse "https://local host/": invalid character " " in host name`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := apiurl.Parse(tt.raw)
+
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, got.String())
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		got = append(got, req.URL.RequestURI())
+	}))
+
+	t.Cleanup(srv.Close)
+
+	base, err := apiurl.Parse(srv.URL + "/proxy/")
+	require.NoError(t, err)
+
+	client := &http.Client{Transport: apiurl.NewTransport(base, nil)}
+
+	for _, u := range []string{
+		"https://cloud.pocketbook.digital/api/v1.0/books?limit=1",
+		srv.URL + "/other",
+	} {
+		rsp, err := client.Get(u)
+		require.NoError(t, err)
+
+		_ = rsp.Body.Close()
+	}
+
+	assert.Equal(t, []string{"/proxy/books?limit=1", "/other"}, got)
+}
@@ -0,0 +1,23 @@
+// Package clock abstracts the time, so the code waiting for the time can be tested without sleeping.
+package clock
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Real is the [Clock] using the system time.
+type Real struct{}
+
+var _ Clock = Real{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,106 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is the [Clock] controlled manually by [Fake.Advance].
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []waiter
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+var _ Clock = (*Fake)(nil)
+
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 {
+		ch <- f.now
+
+		return ch
+	}
+
+	f.waiters = append(f.waiters, waiter{at: f.now.Add(d), ch: ch})
+	f.cond.Broadcast()
+
+	return ch
+}
+
+// Advance moves the time forward and fires all waiters whose time has come.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+
+	pending := f.waiters[:0]
+
+	for _, w := range f.waiters {
+		if w.at.After(f.now) {
+			pending = append(pending, w)
+
+			continue
+		}
+
+		w.ch <- f.now
+	}
+
+	f.waiters = pending
+	f.cond.Broadcast()
+}
+
+// BlockUntil waits until there are at least n pending waiters.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// Next returns the time of the earliest pending waiter.
+func (f *Fake) Next() (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.waiters) == 0 {
+		return time.Time{}, false
+	}
+
+	next := f.waiters[0].at
+
+	for _, w := range f.waiters[1:] {
+		if w.at.Before(next) {
+			next = w.at
+		}
+	}
+
+	return next, true
+}
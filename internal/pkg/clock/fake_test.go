@@ -0,0 +1,51 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+)
+
+func TestFake(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	ch := fake.After(time.Hour)
+
+	next, ok := fake.Next()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(time.Hour), next)
+
+	fake.BlockUntil(1)
+
+	fake.Advance(30 * time.Minute)
+
+	select {
+	case <-ch:
+		t.Fatal("fired too early")
+	default:
+	}
+
+	fake.Advance(30 * time.Minute)
+
+	assert.Equal(t, now.Add(time.Hour), <-ch)
+	assert.Equal(t, now.Add(time.Hour), fake.Now())
+
+	_, ok = fake.Next()
+	assert.False(t, ok)
+}
+
+func TestFake_After_NonPositive(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	assert.Equal(t, now, <-fake.After(0))
+}
@@ -18,8 +18,9 @@ type command interface {
 type name = string
 
 type cmnd struct {
-	n name
-	c command
+	n      name
+	c      command
+	hidden bool
 }
 
 type Command struct {
@@ -52,6 +53,15 @@ func (c *Command) AddCommand(name string, cmd command) {
 	})
 }
 
+// AddHiddenCommand adds the command which is not listed by the help command.
+func (c *Command) AddHiddenCommand(name string, cmd command) {
+	c.cmds = append(c.cmds, cmnd{
+		n:      name,
+		c:      cmd,
+		hidden: true,
+	})
+}
+
 var errUnknownCommand = errors.New("unknown command")
 
 func (c *Command) Run(args []string) error {
@@ -132,6 +142,10 @@ func (h helpCommand) Help() string {
 	cmds := *h.cmds
 
 	for i := 0; i < len(cmds); i++ {
+		if cmds[i].hidden {
+			continue
+		}
+
 		help += "\n\t" + cmds[i].n + " - " + cmds[i].c.Description()
 	}
 
@@ -96,6 +96,35 @@ func TestCommand_Run_Help(t *testing.T) {
 	}
 }
 
+func TestCommand_Run_Help_Hidden(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	commandMock := mocks.NewCommand(mockCtrl)
+	hiddenMock := mocks.NewCommand(mockCtrl)
+	buf := &bytes.Buffer{}
+
+	cmd := command.New(command.WithHelpOutput(buf))
+	cmd.AddCommand("test", commandMock)
+	cmd.AddHiddenCommand("hidden", hiddenMock)
+
+	commandMock.EXPECT().
+		Description().
+		Return("Test command mock.")
+
+	hiddenMock.EXPECT().
+		Run([]string{"some"}).
+		Return(nil)
+
+	err := cmd.Run([]string{"help"})
+	require.NoError(t, err)
+
+	assert.NotContains(t, buf.String(), "hidden")
+
+	err = cmd.Run([]string{"hidden", "some"})
+	require.NoError(t, err)
+}
+
 func TestCommand_Run_Help_Subcommand(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,195 @@
+package fakecloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/fakecloud"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+var errInvalidFailure = errors.New("invalid failure")
+
+type FakeCloud struct {
+	flags     *flag.FlagSet
+	addr      string
+	userName  string
+	password  string
+	providers int
+	books     int
+	pageLimit int
+	fail      string
+	delay     time.Duration
+	slowBody  time.Duration
+}
+
+func New() *FakeCloud {
+	fc := &FakeCloud{
+		flags: flag.NewFlagSet("fake-cloud", flag.ContinueOnError),
+	}
+
+	fc.flags.StringVar(&fc.addr, "addr", "127.0.0.1:8080", "Address to listen.")
+	fc.flags.StringVar(&fc.userName, "username", "demo@example.com", "Username of the account.")
+	fc.flags.StringVar(&fc.password, "password", "demo", "Password of the account.")
+	fc.flags.IntVar(&fc.providers, "providers", 2, "Number of providers.")
+	fc.flags.IntVar(&fc.books, "books", 5, "Number of books of each provider.")
+	fc.flags.IntVar(&fc.pageLimit, "page-limit", 0, "Maximum number of books in a single response, 0 is unlimited.")
+	fc.flags.StringVar(&fc.fail, "fail", "", "Comma-separated list of injected failures as endpoint:status[:times],\n"+
+		"e.g. \"books:503:2,files:429\". Endpoints: "+endpoints()+".")
+	fc.flags.DurationVar(&fc.delay, "delay", 0, "Delay of every response.")
+	fc.flags.DurationVar(&fc.slowBody, "slow-body", 0, "Pause between chunks of downloaded files.")
+
+	return fc
+}
+
+func (f *FakeCloud) Description() string {
+	return "Runs fake PocketBook Cloud server for testing and demos."
+}
+
+func (f *FakeCloud) Help() string {
+	buf := &bytes.Buffer{}
+
+	f.flags.SetOutput(buf)
+	f.flags.Usage()
+
+	return buf.String()
+}
+
+func (f *FakeCloud) Run(args []string) error {
+	if err := f.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return fmt.Errorf("flag parse: %v", err)
+	}
+
+	srv, err := f.server()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	ln, err := net.Listen("tcp", f.addr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	hs := &http.Server{
+		Handler:           srv,
+		ReadHeaderTimeout: time.Minute,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		sctx, scancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer scancel()
+
+		_ = hs.Shutdown(sctx)
+	}()
+
+	slog.Info("fake cloud is listening",
+		"api_url", "http://"+ln.Addr().String()+fakecloud.Path,
+		"username", f.userName,
+		"password", f.password,
+	)
+
+	if err = hs.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+
+	return nil
+}
+
+func (f *FakeCloud) server() (*fakecloud.Server, error) {
+	opts := []fakecloud.Option{
+		fakecloud.WithUser(f.userName, f.password),
+		fakecloud.WithPageLimit(f.pageLimit),
+	}
+
+	for _, p := range fakecloud.Demo(f.providers, f.books) {
+		opts = append(opts, fakecloud.WithProvider(p))
+	}
+
+	srv := fakecloud.New(opts...)
+
+	if f.fail != "" {
+		for _, spec := range strings.Split(f.fail, ",") {
+			endpoint, failure, err := parseFailure(spec)
+			if err != nil {
+				return nil, fmt.Errorf("parse failure: %w", err)
+			}
+
+			srv.Fail(endpoint, failure)
+		}
+	}
+
+	if f.delay > 0 || f.slowBody > 0 {
+		for _, e := range fakecloud.Endpoints {
+			failure := fakecloud.Failure{Delay: f.delay}
+
+			if e == fakecloud.EndpointFiles {
+				failure.SlowBody = f.slowBody
+			}
+
+			srv.Fail(e, failure)
+		}
+	}
+
+	return srv, nil
+}
+
+func parseFailure(spec string) (fakecloud.Endpoint, fakecloud.Failure, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fakecloud.Failure{}, fmt.Errorf("%w: %q", errInvalidFailure, spec)
+	}
+
+	endpoint := fakecloud.Endpoint(parts[0])
+	if !slices.Contains(fakecloud.Endpoints, endpoint) {
+		return "", fakecloud.Failure{}, fmt.Errorf("%w: unknown endpoint %q", errInvalidFailure, parts[0])
+	}
+
+	var (
+		failure fakecloud.Failure
+		err     error
+	)
+
+	if failure.Status, err = strconv.Atoi(parts[1]); err != nil || http.StatusText(failure.Status) == "" {
+		return "", fakecloud.Failure{}, fmt.Errorf("%w: invalid status %q", errInvalidFailure, parts[1])
+	}
+
+	if len(parts) == 3 {
+		if failure.Times, err = strconv.Atoi(parts[2]); err != nil || failure.Times < 1 {
+			return "", fakecloud.Failure{}, fmt.Errorf("%w: invalid times %q", errInvalidFailure, parts[2])
+		}
+	}
+
+	return endpoint, failure, nil
+}
+
+func endpoints() string {
+	s := make([]string, len(fakecloud.Endpoints))
+
+	for i, e := range fakecloud.Endpoints {
+		s[i] = string(e)
+	}
+
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,56 @@
+package fakecloud_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/fakecloud"
+)
+
+func TestFakeCloud_Description(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Runs fake PocketBook Cloud server for testing and demos.", fakecloud.New().Description())
+}
+
+func TestFakeCloud_Run_Error_Fail(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		fail   string
+		expect string
+	}{
+		{
+			name:   "format",
+			fail:   "books",
+			expect: `parse failure: invalid failure: "books"`,
+		},
+		{
+			name:   "endpoint",
+			fail:   "unknown:500",
+			expect: `parse failure: invalid failure: unknown endpoint "unknown"`,
+		},
+		{
+			name:   "status",
+			fail:   "books:999",
+			expect: `parse failure: invalid failure: invalid status "999"`,
+		},
+		{
+			name:   "times",
+			fail:   "files:429:0",
+			expect: `parse failure: invalid failure: invalid times "0"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := fakecloud.New().Run([]string{"-addr", "127.0.0.1:0", "-fail", "books:503:1," + tt.fail})
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/health"
+)
+
+const defaultAddr = "127.0.0.1:8080"
+
+var errUnhealthy = errors.New("unhealthy")
+
+type HealthCheck struct {
+	flags   *flag.FlagSet
+	stdout  io.Writer
+	env     bool
+	addr    string
+	path    string
+	timeout time.Duration
+}
+
+func New(opts ...Option) *HealthCheck {
+	hc := &HealthCheck{
+		flags:  flag.NewFlagSet("healthcheck", flag.ContinueOnError),
+		stdout: os.Stdout,
+	}
+
+	for _, o := range opts {
+		o(hc)
+	}
+
+	hc.flags.BoolVar(&hc.env, "env", false, "Take the address from the environment variables and the config file like the sync command with the env flag.\n"+
+		"The check passes without the request if the address is empty, because the health server is disabled.")
+	hc.flags.StringVar(&hc.addr, "addr", defaultAddr, "Address of the health server of the daemon.")
+	hc.flags.StringVar(&hc.path, "path", health.PathHealth, "Path to check, e.g. \""+health.PathReady+"\" to check the last sync.")
+	hc.flags.DurationVar(&hc.timeout, "timeout", 5*time.Second, "Timeout of the check.")
+
+	return hc
+}
+
+func (h *HealthCheck) Description() string {
+	return "Checks the health server of the daemon, exits with non-zero status if it is unhealthy."
+}
+
+func (h *HealthCheck) Help() string {
+	buf := &bytes.Buffer{}
+
+	h.flags.SetOutput(buf)
+	h.flags.Usage()
+
+	return buf.String()
+}
+
+func (h *HealthCheck) Run(args []string) error {
+	if err := h.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return fmt.Errorf("flag parse: %v", err)
+	}
+
+	addr := h.addr
+
+	if h.env {
+		var err error
+
+		if addr, err = sync.EnvHTTPAddr(); err != nil {
+			return err
+		}
+
+		if addr == "" {
+			fmt.Fprintln(h.stdout, "skipped: the health server is disabled")
+
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+dialAddr(addr)+h.path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnhealthy, err)
+	}
+
+	_ = rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: status %s", errUnhealthy, rsp.Status)
+	}
+
+	fmt.Fprintln(h.stdout, "ok")
+
+	return nil
+}
+
+// dialAddr turns the listen address into the address to connect, the unspecified host is the loopback.
+func dialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, port)
+}
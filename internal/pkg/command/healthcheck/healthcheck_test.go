@@ -0,0 +1,104 @@
+package healthcheck_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/healthcheck"
+)
+
+func server(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestHealthCheck_Run(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := healthcheck.New(healthcheck.WithStdout(&buf)).Run([]string{"-addr", server(t)})
+	require.NoError(t, err)
+	assert.Equal(t, "ok\n", buf.String())
+}
+
+func TestHealthCheck_Run_Env(t *testing.T) {
+	tests := [...]struct {
+		name string
+		env  func(t *testing.T, addr string)
+	}{
+		{
+			name: "http addr",
+			env: func(t *testing.T, addr string) {
+				t.Setenv("HTTP_ADDR", addr)
+				t.Setenv("CONFIG_FILE", "")
+			},
+		},
+		{
+			name: "config file",
+			env: func(t *testing.T, addr string) {
+				path := filepath.Join(t.TempDir(), "pbcsync.conf")
+				require.NoError(t, os.WriteFile(path, []byte("http-addr="+addr+"\n"), 0o600))
+
+				t.Setenv("HTTP_ADDR", "")
+				t.Setenv("CONFIG_FILE", path)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, port, _ := strings.Cut(server(t), ":")
+
+			tt.env(t, ":"+port)
+
+			var buf bytes.Buffer
+
+			err := healthcheck.New(healthcheck.WithStdout(&buf)).Run([]string{"-env"})
+			require.NoError(t, err)
+			assert.Equal(t, "ok\n", buf.String())
+		})
+	}
+}
+
+func TestHealthCheck_Run_Env_Disabled(t *testing.T) {
+	t.Setenv("HTTP_ADDR", "")
+	t.Setenv("CONFIG_FILE", "")
+
+	var buf bytes.Buffer
+
+	err := healthcheck.New(healthcheck.WithStdout(&buf)).Run([]string{"-env"})
+	require.NoError(t, err)
+	assert.Equal(t, "skipped: the health server is disabled\n", buf.String())
+}
+
+func TestHealthCheck_Run_Error(t *testing.T) {
+	t.Parallel()
+
+	err := healthcheck.New().Run([]string{"-addr", server(t), "-path", "/readyz"})
+	require.EqualError(t, err, "unhealthy: status 503 Service Unavailable")
+}
+
+func TestHealthCheck_Description(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Checks the health server of the daemon, exits with non-zero status if it is unhealthy.",
+		healthcheck.New().Description())
+}
@@ -0,0 +1,12 @@
+package healthcheck
+
+import "io"
+
+type Option func(*HealthCheck)
+
+// WithStdout sets the output of the check, [os.Stdout] by default.
+func WithStdout(w io.Writer) Option {
+	return func(h *HealthCheck) {
+		h.stdout = w
+	}
+}
@@ -0,0 +1,232 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	dateLayout  = "2006-01-02"
+	timeLayout  = "2006-01-02 15:04:05"
+)
+
+var errInvalidValue = errors.New("invalid value")
+
+type History struct {
+	flags    *flag.FlagSet
+	stdout   io.Writer
+	env      bool
+	dir      string
+	stateDir string
+	status   string
+	since    string
+	until    string
+	book     string
+	files    bool
+	limit    int
+	output   string
+}
+
+func New(opts ...Option) *History {
+	h := &History{
+		flags:  flag.NewFlagSet("history", flag.ContinueOnError),
+		stdout: os.Stdout,
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	h.flags.BoolVar(&h.env, "env", false, "Take the directories from the environment variables and the config file like the sync command with the env flag.")
+	h.flags.StringVar(&h.dir, "dir", "books", "Directory of the sync.")
+	h.flags.StringVar(&h.stateDir, "state-dir", "", "State directory of the sync, by default \""+statefile.DirName+"\" inside the sync directory.")
+	h.flags.StringVar(&h.status, "status", "", "Show only the runs with the status: "+report.StatusSuccess+" or "+report.StatusError+".")
+	h.flags.StringVar(&h.since, "since", "", "Show the runs started since the date or the time, e.g. \"2025-03-10\" or \"2025-03-10T12:00:00Z\".")
+	h.flags.StringVar(&h.until, "until", "", "Show the runs started before the date or the time, the date includes its day.")
+	h.flags.StringVar(&h.book, "book", "", "Show the operations of the books whose names contain the text regardless of the case.")
+	h.flags.BoolVar(&h.files, "files", false, "Show the file operations of the runs instead of the runs, implied by the book flag.")
+	h.flags.IntVar(&h.limit, "limit", 20, "How many latest runs to show, 0 shows all.")
+	h.flags.StringVar(&h.output, "output", outputTable, "Output format: "+outputTable+" or "+outputJSON+".")
+
+	return h
+}
+
+func (h *History) Description() string {
+	return "Shows the journal of the sync runs and the file operations."
+}
+
+func (h *History) Help() string {
+	buf := &bytes.Buffer{}
+
+	h.flags.SetOutput(buf)
+	h.flags.Usage()
+
+	return buf.String()
+}
+
+func (h *History) Run(args []string) error {
+	if err := h.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return fmt.Errorf("flag parse: %v", err)
+	}
+
+	if h.env {
+		var err error
+
+		if h.stateDir, err = sync.EnvStateDirectory(); err != nil {
+			return err
+		}
+	}
+
+	filter, err := h.filter()
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	runs, err := history.Read(filepath.Join(h.stateDirectory(), history.FileName))
+	if err != nil {
+		return err
+	}
+
+	runs = filter.Apply(runs)
+
+	if h.limit > 0 && len(runs) > h.limit {
+		runs = runs[len(runs)-h.limit:]
+	}
+
+	// The latest runs go first.
+	slices.Reverse(runs)
+
+	switch {
+	case h.output == outputJSON:
+		return h.printJSON(runs)
+	case h.files || h.book != "":
+		return h.printFiles(runs)
+	default:
+		return h.printRuns(runs)
+	}
+}
+
+func (h *History) stateDirectory() string {
+	if h.stateDir != "" {
+		return h.stateDir
+	}
+
+	return filepath.Join(h.dir, statefile.DirName)
+}
+
+func (h *History) filter() (history.Filter, error) {
+	f := history.Filter{Status: h.status, Book: h.book}
+
+	switch {
+	case h.status != "" && h.status != report.StatusSuccess && h.status != report.StatusError:
+		return f, fmt.Errorf("%w: status must be %s or %s", errInvalidValue, report.StatusSuccess, report.StatusError)
+	case h.output != outputTable && h.output != outputJSON:
+		return f, fmt.Errorf("%w: output must be %s or %s", errInvalidValue, outputTable, outputJSON)
+	case h.limit < 0:
+		return f, fmt.Errorf("%w: limit must not be negative", errInvalidValue)
+	}
+
+	var err error
+
+	if f.Since, err = parseTime(h.since, false); err != nil {
+		return f, fmt.Errorf("since: %w", err)
+	}
+
+	if f.Until, err = parseTime(h.until, true); err != nil {
+		return f, fmt.Errorf("until: %w", err)
+	}
+
+	return f, nil
+}
+
+// parseTime parses the date in the local time zone or the RFC 3339 time.
+// The end of the period includes the whole day of the date.
+func parseTime(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseInLocation(dateLayout, s, time.Local); err == nil {
+		if end {
+			d = d.AddDate(0, 0, 1)
+		}
+
+		return d, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is neither a date nor a time", errInvalidValue, s)
+	}
+
+	return t, nil
+}
+
+func (h *History) printJSON(runs []report.Summary) error {
+	if runs == nil {
+		runs = []report.Summary{}
+	}
+
+	data, err := json.MarshalIndent(runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	_, err = fmt.Fprintf(h.stdout, "%s\n", data)
+
+	return err
+}
+
+func (h *History) printRuns(runs []report.Summary) error {
+	w := tabwriter.NewWriter(h.stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "START\tRUN ID\tDURATION\tSTATUS\tTOTAL\tDOWNLOADED\tFILTERED\tFAILED\tERROR")
+
+	for _, r := range runs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			r.Start.Local().Format(timeLayout), r.RunID, duration(r.DurationMS), r.Status,
+			r.Total, r.Downloaded, r.Filtered, r.Failed, r.Error)
+	}
+
+	return w.Flush()
+}
+
+func (h *History) printFiles(runs []report.Summary) error {
+	w := tabwriter.NewWriter(h.stdout, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "START\tRUN ID\tBOOK\tOUTCOME\tBYTES\tDURATION\tREASON")
+
+	for _, r := range runs {
+		for _, b := range r.Books {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+				r.Start.Local().Format(timeLayout), r.RunID, b.Name, b.Outcome,
+				b.Bytes, duration(b.DurationMS), b.Reason)
+		}
+	}
+
+	return w.Flush()
+}
+
+func duration(ms int64) time.Duration {
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second / 10)
+}
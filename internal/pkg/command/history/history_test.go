@@ -0,0 +1,225 @@
+package history_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/history"
+	journal "github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func stateDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	j := journal.New(filepath.Join(dir, journal.FileName), 0)
+
+	start := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, j.Append(report.Summary{
+		RunID:      "first",
+		Status:     report.StatusSuccess,
+		Start:      start,
+		End:        start.Add(time.Minute),
+		DurationMS: time.Minute.Milliseconds(),
+		Total:      2,
+		Downloaded: 1,
+		Books:      []report.BookSummary{{Name: "Dune.epub", Outcome: report.OutcomeDownloaded, Bytes: 1024}},
+	}))
+
+	require.NoError(t, j.Append(report.Summary{
+		RunID:  "second",
+		Status: report.StatusError,
+		Error:  "refused",
+		Start:  start.Add(24 * time.Hour),
+		End:    start.Add(24 * time.Hour),
+		Failed: 1,
+		Books:  []report.BookSummary{{Name: "Emma.pdf", Outcome: report.OutcomeFailed, Reason: "refused"}},
+	}))
+
+	return dir
+}
+
+func TestHistory_Run(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := history.New(history.WithStdout(&buf)).Run([]string{"-state-dir", stateDir(t)})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "RUN ID")
+	assert.Contains(t, lines[1], "second")
+	assert.Contains(t, lines[1], "refused")
+	assert.Contains(t, lines[2], "first")
+	assert.Contains(t, lines[2], "1m0s")
+}
+
+func TestHistory_Run_Env(t *testing.T) {
+	tests := [...]struct {
+		name string
+		env  func(t *testing.T, state string)
+	}{
+		{
+			name: "state dir",
+			env: func(t *testing.T, state string) {
+				t.Setenv("STATE_DIR", state)
+			},
+		},
+		{
+			name: "config file",
+			env: func(t *testing.T, state string) {
+				path := filepath.Join(t.TempDir(), "pbcsync.conf")
+				require.NoError(t, os.WriteFile(path, []byte("state-dir="+state+"\n"), 0o600))
+
+				t.Setenv("STATE_DIR", "")
+				t.Setenv("CONFIG_FILE", path)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env(t, stateDir(t))
+
+			var buf bytes.Buffer
+
+			err := history.New(history.WithStdout(&buf)).Run([]string{"-env"})
+			require.NoError(t, err)
+
+			assert.Contains(t, buf.String(), "second")
+		})
+	}
+}
+
+func TestHistory_Run_Files(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+
+	err := history.New(history.WithStdout(&buf)).Run([]string{"-state-dir", stateDir(t), "-book", "dune"})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "OUTCOME")
+	assert.Contains(t, lines[1], "Dune.epub")
+	assert.Contains(t, lines[1], "1024")
+}
+
+func TestHistory_Run_JSON(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		args   []string
+		expect []string
+	}{
+		{
+			name:   "all",
+			expect: []string{"second", "first"},
+		},
+		{
+			name:   "status",
+			args:   []string{"-status", "error"},
+			expect: []string{"second"},
+		},
+		{
+			name:   "since",
+			args:   []string{"-since", "2025-03-11T00:00:00Z"},
+			expect: []string{"second"},
+		},
+		{
+			name:   "until",
+			args:   []string{"-until", "2025-03-11T00:00:00Z"},
+			expect: []string{"first"},
+		},
+		{
+			name:   "limit",
+			args:   []string{"-limit", "1"},
+			expect: []string{"second"},
+		},
+		{
+			name:   "nothing",
+			args:   []string{"-book", "unknown"},
+			expect: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			args := append([]string{"-state-dir", stateDir(t), "-output", "json"}, tt.args...)
+
+			err := history.New(history.WithStdout(&buf)).Run(args)
+			require.NoError(t, err)
+
+			var runs []report.Summary
+
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &runs))
+
+			ids := []string{}
+
+			for _, r := range runs {
+				ids = append(ids, r.RunID)
+			}
+
+			assert.Equal(t, tt.expect, ids)
+		})
+	}
+}
+
+func TestHistory_Run_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name:   "status",
+			args:   []string{"-status", "unknown"},
+			expect: "validate: invalid value: status must be success or error",
+		},
+		{
+			name:   "output",
+			args:   []string{"-output", "yaml"},
+			expect: "validate: invalid value: output must be table or json",
+		},
+		{
+			name:   "since",
+			args:   []string{"-since", "yesterday"},
+			expect: `validate: since: invalid value: "yesterday" is neither a date nor a time`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := history.New().Run(append([]string{"-state-dir", t.TempDir()}, tt.args...))
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestHistory_Description(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "Shows the journal of the sync runs and the file operations.", history.New().Description())
+}
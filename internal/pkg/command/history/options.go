@@ -0,0 +1,12 @@
+package history
+
+import "io"
+
+type Option func(*History)
+
+// WithStdout sets the output of the history, [os.Stdout] by default.
+func WithStdout(w io.Writer) Option {
+	return func(h *History) {
+		h.stdout = w
+	}
+}
@@ -1,17 +1,91 @@
 package sync
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	mqttclient "github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/email"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/webhook"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
 
 type config struct {
-	clientID      string
-	clientSecret  string
-	userName      string
-	password      string
-	dir           string
-	debug         bool
-	env           bool
-	daemon        bool
-	daemonTimeout time.Duration
+	clientID       string
+	clientSecret   string
+	userName       string
+	password       string
+	dir            string
+	debug          bool
+	env            bool
+	daemon         bool
+	daemonTimeout  time.Duration
+	providers      string
+	providersConc  int
+	token          string
+	stateDir       string
+	apiURL         string
+	schedule       string
+	timeZone       string
+	errorPolicy    string
+	maxFailures    int
+	retryBackoff   time.Duration
+	retryMax       time.Duration
+	syncOnStart    bool
+	triggerFile    string
+	configFile     string
+	shutdownGrace  time.Duration
+	httpAddr       string
+	readyIntervals int
+	metricsFile    string
+	lockWait       time.Duration
+	webhooks       string
+	webhookTmpl    string
+	webhookOn      string
+	smtpAddr       string
+	smtpSecurity   string
+	smtpUsername   string
+	smtpPassword   string
+	emailFrom      string
+	emailTo        string
+	emailTmpl      string
+	emailDigest    time.Duration
+	emailOn        string
+	mqttBroker     string
+	mqttUsername   string
+	mqttPassword   string
+	mqttTopic      string
+	mqttQoS        int
+	mqttRetain     bool
+	mqttDiscovery  string
+	logFormat      string
+	logLevel       string
+	logFile        string
+	logMaxSize     int
+	logMaxAge      time.Duration
+	logMaxBackups  int
+	traceHTTP      string
+	reportFile     string
+	output         string
+	historyAge     time.Duration
+	// transport records the traffic to the traceHTTP file, it is kept by the reload.
+	transport http.RoundTripper
 }
 
 func (c *config) ClientID() string {
@@ -33,3 +107,407 @@ func (c *config) Password() string {
 func (c *config) Directory() string {
 	return c.dir
 }
+
+func (c *config) ProvidersConcurrency() int {
+	return c.providersConc
+}
+
+func (c *config) APIURL() string {
+	return c.apiURL
+}
+
+func (c *config) LockWait() time.Duration {
+	return c.lockWait
+}
+
+// Transport returns the transport of the API and the downloads, nil is the default one.
+func (c *config) Transport() http.RoundTripper {
+	return c.transport
+}
+
+func (c *config) Token() string {
+	return c.token
+}
+
+// StateDirectory returns the directory for the internal files.
+// By default, it is the hidden directory inside the sync directory.
+func (c *config) StateDirectory() string {
+	if c.stateDir != "" {
+		return c.stateDir
+	}
+
+	return filepath.Join(c.dir, statefile.DirName)
+}
+
+// TriggerFile returns the file requesting the sync in daemon mode.
+// By default, it is inside the state directory.
+func (c *config) TriggerFile() string {
+	if c.triggerFile != "" {
+		return c.triggerFile
+	}
+
+	return filepath.Join(c.StateDirectory(), defaultTriggerFile)
+}
+
+func (c *config) Providers() []string {
+	return splitList(c.providers)
+}
+
+// notifiers returns the dispatchers of the configured notification channels.
+func (c *config) notifiers() ([]*notify.Dispatcher, error) {
+	var dispatchers []*notify.Dispatcher
+
+	wh, err := c.webhookNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if wh != nil {
+		dispatchers = append(dispatchers, wh)
+	}
+
+	em, err := c.emailNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if em != nil {
+		dispatchers = append(dispatchers, em)
+	}
+
+	mq, err := c.mqttNotifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if mq != nil {
+		dispatchers = append(dispatchers, mq)
+	}
+
+	return dispatchers, nil
+}
+
+// webhookNotifier returns the dispatcher of webhooks, it is nil if no URL is set.
+func (c *config) webhookNotifier() (*notify.Dispatcher, error) {
+	mode, err := notify.ParseMode(c.webhookOn)
+	if err != nil {
+		return nil, fmt.Errorf("parse webhook-on: %w", err)
+	}
+
+	urls := splitList(c.webhooks)
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	var opts []webhook.Option
+
+	if c.webhookTmpl != "" {
+		tmpl, err := webhook.ParseTemplate(c.webhookTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("webhook template: %w", err)
+		}
+
+		opts = append(opts, webhook.WithTemplate(tmpl))
+	}
+
+	notifiers := make([]notify.Notifier, 0, len(urls))
+
+	for _, u := range urls {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return nil, fmt.Errorf("%w: webhook %q", errInvalidValue, redact.URL(u))
+		}
+
+		notifiers = append(notifiers, webhook.New(u, opts...))
+	}
+
+	return notify.New(mode, notifiers, notify.WithStateFile(filepath.Join(c.StateDirectory(), webhookStateFile))), nil
+}
+
+// emailNotifier returns the dispatcher of emails, it is nil if neither the server nor the recipients are set.
+func (c *config) emailNotifier() (*notify.Dispatcher, error) {
+	mode, err := notify.ParseMode(c.emailOn)
+	if err != nil {
+		return nil, fmt.Errorf("parse email-on: %w", err)
+	}
+
+	security, err := email.ParseSecurity(c.smtpSecurity)
+	if err != nil {
+		return nil, fmt.Errorf("parse smtp-security: %w", err)
+	}
+
+	to := splitList(c.emailTo)
+
+	switch {
+	case c.smtpAddr == "" && len(to) == 0:
+		return nil, nil
+	case c.smtpAddr == "":
+		return nil, requiredError{param: "smtp-addr"}
+	case len(to) == 0:
+		return nil, requiredError{param: "email-to"}
+	case c.emailFrom == "":
+		return nil, requiredError{param: "email-from"}
+	case c.emailDigest < 0:
+		return nil, fmt.Errorf("%w: email-digest must not be negative", errInvalidValue)
+	}
+
+	if _, _, err = net.SplitHostPort(c.smtpAddr); err != nil {
+		return nil, fmt.Errorf("%w: smtp-addr %q", errInvalidValue, c.smtpAddr)
+	}
+
+	opts := []email.Option{
+		email.WithDigest(c.emailDigest, filepath.Join(c.StateDirectory(), emailDigestFile)),
+	}
+
+	if c.emailTmpl != "" {
+		tmpl, err := email.ParseTemplate(c.emailTmpl)
+		if err != nil {
+			return nil, fmt.Errorf("email template: %w", err)
+		}
+
+		opts = append(opts, email.WithTemplate(tmpl))
+	}
+
+	em := email.New(email.Config{
+		Addr:     c.smtpAddr,
+		Security: security,
+		Username: c.smtpUsername,
+		Password: c.smtpPassword,
+		From:     c.emailFrom,
+		To:       to,
+	}, opts...)
+
+	return notify.New(mode, []notify.Notifier{em}, notify.WithStateFile(filepath.Join(c.StateDirectory(), emailStateFile))), nil
+}
+
+// mqttNotifier returns the dispatcher publishing every run to the MQTT broker, it is nil if the broker is not set.
+func (c *config) mqttNotifier() (*notify.Dispatcher, error) {
+	if c.mqttBroker == "" {
+		return nil, nil
+	}
+
+	if _, _, err := mqttclient.ParseBroker(c.mqttBroker); err != nil {
+		return nil, fmt.Errorf("%w: mqtt-broker: %w", errInvalidValue, err)
+	}
+
+	switch {
+	case c.mqttQoS < 0 || c.mqttQoS > 2:
+		return nil, fmt.Errorf("%w: mqtt-qos must be 0, 1 or 2", errInvalidValue)
+	case c.mqttPassword != "" && c.mqttUsername == "":
+		// MQTT 3.1.1 doesn't allow the password flag without the username flag, the broker would drop the connection.
+		return nil, fmt.Errorf("%w: mqtt-password requires mqtt-username", errInvalidValue)
+	case strings.Trim(c.mqttTopic, "/") == "":
+		return nil, requiredError{param: "mqtt-topic"}
+	}
+
+	pub := mqtt.New(mqtt.Config{
+		Config: mqttclient.Config{
+			Broker:   c.mqttBroker,
+			Username: c.mqttUsername,
+			Password: c.mqttPassword,
+		},
+		Topic:     strings.Trim(c.mqttTopic, "/"),
+		QoS:       byte(c.mqttQoS),
+		Retain:    c.mqttRetain,
+		Discovery: strings.Trim(c.mqttDiscovery, "/"),
+	}, mqtt.WithStateFile(filepath.Join(c.StateDirectory(), mqttStateFile)))
+
+	return notify.New(notify.ModeAlways, []notify.Notifier{pub}), nil
+}
+
+// secrets returns the values masked in the log and the errors.
+func (c *config) secrets() []string {
+	return []string{c.password, c.clientSecret, c.token, c.smtpPassword, c.mqttPassword}
+}
+
+// fingerprint identifies the settings of the configuration without the secrets,
+// so the reports of runs with different settings are told apart.
+func (c *config) fingerprint() string {
+	cp := *c
+	cp.password, cp.clientSecret, cp.token, cp.smtpPassword, cp.mqttPassword = "", "", "", "", ""
+	cp.transport = nil
+
+	sum := sha256.Sum256(fmt.Appendf(nil, "%+v", cp))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// logConfig returns the configuration of the logger, the debug flag overrides the level.
+func (c *config) logConfig() (logging.Config, error) {
+	format, err := logging.ParseFormat(c.logFormat)
+	if err != nil {
+		return logging.Config{}, err
+	}
+
+	level := slog.LevelDebug
+
+	if !c.debug {
+		if level, err = logging.ParseLevel(c.logLevel); err != nil {
+			return logging.Config{}, err
+		}
+	}
+
+	switch {
+	case c.logMaxSize < 0:
+		return logging.Config{}, fmt.Errorf("%w: log-max-size must not be negative", errInvalidValue)
+	case c.logMaxAge < 0:
+		return logging.Config{}, fmt.Errorf("%w: log-max-age must not be negative", errInvalidValue)
+	case c.logMaxBackups < 0:
+		return logging.Config{}, fmt.Errorf("%w: log-max-backups must not be negative", errInvalidValue)
+	}
+
+	var output io.Writer
+
+	// The standard output is left to the reports.
+	if c.output == outputJSON {
+		output = os.Stderr
+	}
+
+	return logging.Config{
+		Format: format,
+		Level:  level,
+		Output: output,
+		File:   c.logFile,
+		Rotation: logging.Rotation{
+			MaxSize:    int64(c.logMaxSize) << 20,
+			MaxAge:     c.logMaxAge,
+			MaxBackups: c.logMaxBackups,
+		},
+	}, nil
+}
+
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+
+	items := strings.Split(s, ",")
+
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+
+	return items
+}
+
+// daemonSchedule returns the cron schedule if it is set, otherwise the fixed timeout.
+func (c *config) daemonSchedule() (schedule.Schedule, error) {
+	if c.schedule == "" {
+		every, err := schedule.Every(c.daemonTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("daemon-timeout: %w", err)
+		}
+
+		return every, nil
+	}
+
+	loc := time.Local
+
+	if c.timeZone != "" {
+		var err error
+
+		if loc, err = time.LoadLocation(c.timeZone); err != nil {
+			return nil, fmt.Errorf("load time zone: %w", err)
+		}
+	}
+
+	cron, err := schedule.ParseCron(c.schedule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("parse schedule: %w", err)
+	}
+
+	return cron, nil
+}
+
+func (c *config) daemonPolicy() (daemon.Policy, error) {
+	actions, err := daemon.ParseActions(c.errorPolicy)
+	if err != nil {
+		return daemon.Policy{}, err
+	}
+
+	return daemon.Policy{
+		Actions:     actions,
+		MaxFailures: c.maxFailures,
+		Backoff:     c.retryBackoff,
+		MaxBackoff:  c.retryMax,
+	}, nil
+}
+
+// loadConfig completes the config parsed from the command-line by the environment variables and the config file.
+func loadConfig(cfg *config) (*config, error) {
+	if cfg.env {
+		var err error
+
+		if cfg, err = loadConfigFromEnv(); err != nil {
+			return nil, fmt.Errorf("load config from env: %v", err)
+		}
+	}
+
+	if cfg.configFile != "" {
+		if err := applyConfigFile(cfg, cfg.configFile); err != nil {
+			return nil, fmt.Errorf("load config file: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// EnvStateDirectory returns the state directory of the sync command in the environment variables mode,
+// the config file set by CONFIG_FILE is applied too.
+func EnvStateDirectory() (string, error) {
+	cfg, err := loadConfig(&config{env: true})
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.StateDirectory(), nil
+}
+
+// EnvHTTPAddr returns the address of the health server of the sync command in the environment variables mode,
+// the config file set by CONFIG_FILE is applied too. It is empty when the server is disabled.
+func EnvHTTPAddr() (string, error) {
+	cfg, err := loadConfig(&config{env: true})
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.httpAddr, nil
+}
+
+// applyConfigFile sets the flags listed in the file on top of the config.
+// Each non-empty line is a name=value pair, boolean flags may omit the value, lines starting with "#" are comments.
+func applyConfigFile(cfg *config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	var args []string
+
+	for line := range strings.Lines(string(data)) {
+		line = strings.TrimSpace(line)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args = append(args, "-"+strings.TrimLeft(line, "-"))
+	}
+
+	// Defining the flags resets the config to the defaults, so the current values are restored before parsing.
+	values := *cfg
+
+	flags := newFlagSet(cfg)
+	flags.SetOutput(io.Discard)
+
+	*cfg = values
+
+	if err = flags.Parse(args); err != nil {
+		return fmt.Errorf("parse: %w", err)
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected line %q", errInvalidValue, flags.Arg(0))
+	}
+
+	return nil
+}
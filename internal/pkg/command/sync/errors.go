@@ -13,7 +13,11 @@ func (e requiredError) Error() string {
 	return fmt.Sprintf("%s is required", e.param)
 }
 
-var errIsNotDirectory = errors.New("is not a directory")
+var (
+	errIsNotDirectory  = errors.New("is not a directory")
+	errInvalidProvider = errors.New("invalid provider")
+	errInvalidValue    = errors.New("invalid value")
+)
 
 type directoryError struct {
 	dir string
@@ -1,37 +1,114 @@
-//go:generate mockgen -source $GOFILE -destination mocks/$GOFILE -package mocks -typed -exclude_interfaces Synchronizer
+//go:generate mockgen -source $GOFILE -destination mocks/$GOFILE -package mocks -typed -exclude_interfaces Synchronizer,Checker,Lister
 package factory
 
 import (
 	"context"
+	"net/http"
+	"path/filepath"
+	"time"
 
 	pc "github.com/micronull/pocketbook-cloud-client"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/apiurl"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/download"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/books"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/tokens"
 )
 
 type Synchronizer interface {
 	Sync(ctx context.Context) error
 }
 
+// Checker compares the cloud with the directory without downloading.
+type Checker interface {
+	Status(ctx context.Context) (sync.Status, error)
+}
+
+// Lister lists the books of the cloud.
+type Lister interface {
+	Books(ctx context.Context) ([]domain.Book, error)
+}
+
 type Configurator interface {
 	ClientID() string
 	ClientSecret() string
+	APIURL() string
 	UserName() string
 	Password() string
 	Directory() string
+	Providers() []string
+	ProvidersConcurrency() int
+	Token() string
+	StateDirectory() string
+	LockWait() time.Duration
+	// Transport of the API and the downloads, nil is [http.DefaultTransport].
+	Transport() http.RoundTripper
 }
 
 func Factory(config Configurator) Synchronizer {
+	return newApp(config)
+}
+
+// StatusFactory builds the checker from the same configuration as [Factory].
+func StatusFactory(config Configurator) Checker {
+	return newApp(config)
+}
+
+// ListFactory builds the lister of the books from the same configuration as [Factory].
+func ListFactory(config Configurator) Lister {
+	return newRepository(config, httpTransport(config))
+}
+
+func newApp(config Configurator) *sync.App {
+	transport := httpTransport(config)
+
 	return sync.New(
-		books.New(
-			pc.New(
-				pc.WithClientID(config.ClientID()),
-				pc.WithClientSecret(config.ClientSecret()),
-			),
-			config.UserName(),
-			config.Password(),
-		),
+		newRepository(config, transport),
 		config.Directory(),
+		sync.WithDownloader(download.New(
+			download.WithHTTPClient(&http.Client{Transport: transport}),
+			download.WithMetrics(metrics.Default),
+		).Download),
+		sync.WithMetrics(metrics.Default),
+		sync.WithLock(filepath.Join(config.Directory(), lock.FileName), config.LockWait()),
+	)
+}
+
+func newRepository(config Configurator, transport http.RoundTripper) *books.Repository {
+	return books.New(
+		pc.New(
+			pc.WithClientID(config.ClientID()),
+			pc.WithClientSecret(config.ClientSecret()),
+			pc.WithHTTPClient(apiClient(config.APIURL(), transport)),
+		),
+		config.UserName(),
+		config.Password(),
+		books.WithProviders(config.Providers()),
+		books.WithConcurrency(config.ProvidersConcurrency()),
+		books.WithToken(config.Token()),
+		books.WithTokens(tokens.New(filepath.Join(config.StateDirectory(), "tokens.json"))),
+		books.WithMetrics(metrics.Default),
 	)
 }
+
+func httpTransport(config Configurator) http.RoundTripper {
+	if t := config.Transport(); t != nil {
+		return t
+	}
+
+	return http.DefaultTransport
+}
+
+func apiClient(apiURL string, transport http.RoundTripper) *http.Client {
+	base, err := apiurl.Parse(apiURL)
+	if err != nil {
+		// The configuration is validated before, so it is unreachable.
+		return &http.Client{Transport: transport}
+	}
+
+	return &http.Client{Transport: apiurl.NewTransport(base, transport)}
+}
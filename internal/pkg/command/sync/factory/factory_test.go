@@ -1,14 +1,24 @@
 package factory_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/apiurl"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory/mocks"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/fakecloud"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/har"
 )
 
 func TestFactory(t *testing.T) {
@@ -19,11 +29,196 @@ func TestFactory(t *testing.T) {
 
 	cfgMock.EXPECT().ClientID().Return("some client id")
 	cfgMock.EXPECT().ClientSecret().Return("some client secret")
+	cfgMock.EXPECT().APIURL().Return(apiurl.Default)
 	cfgMock.EXPECT().UserName().Return("some user name")
 	cfgMock.EXPECT().Password().Return("some password")
-	cfgMock.EXPECT().Directory().Return("some directory")
+	cfgMock.EXPECT().Directory().Return("some directory").Times(2)
+	cfgMock.EXPECT().Providers().Return([]string{"1", "-some-alias"})
+	cfgMock.EXPECT().ProvidersConcurrency().Return(2)
+	cfgMock.EXPECT().Token().Return("some token")
+	cfgMock.EXPECT().StateDirectory().Return("some state directory")
+	cfgMock.EXPECT().LockWait().Return(time.Second)
+	cfgMock.EXPECT().Transport().Return(nil)
 
 	got := factory.Factory(cfgMock)
 
 	assert.IsType(t, (*sync.App)(nil), got)
 }
+
+func newConfigurator(t *testing.T, apiURL, dir string, transport http.RoundTripper) *mocks.MockConfigurator {
+	t.Helper()
+
+	ctrl := gomock.NewController(t)
+	cfgMock := mocks.NewMockConfigurator(ctrl)
+
+	cfgMock.EXPECT().ClientID().Return("some client id")
+	cfgMock.EXPECT().ClientSecret().Return("some client secret")
+	cfgMock.EXPECT().APIURL().Return(apiURL)
+	cfgMock.EXPECT().UserName().Return("some user name")
+	cfgMock.EXPECT().Password().Return("some password")
+	cfgMock.EXPECT().Directory().Return(dir).Times(2)
+	cfgMock.EXPECT().Providers().Return(nil)
+	cfgMock.EXPECT().ProvidersConcurrency().Return(2)
+	cfgMock.EXPECT().Token().Return("")
+	cfgMock.EXPECT().StateDirectory().Return(filepath.Join(dir, ".pbcsync"))
+	cfgMock.EXPECT().LockWait().Return(time.Duration(0))
+	cfgMock.EXPECT().Transport().Return(transport)
+
+	return cfgMock
+}
+
+func startFakeCloud(t *testing.T, opts ...fakecloud.Option) (*fakecloud.Server, string) {
+	t.Helper()
+
+	fake := fakecloud.New(append([]fakecloud.Option{fakecloud.WithUser("some user name", "some password")}, opts...)...)
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return fake, srv.URL + fakecloud.Path
+}
+
+func TestFactory_APIURL(t *testing.T) {
+	t.Parallel()
+
+	providers := fakecloud.Demo(2, 2)
+	_, apiURL := startFakeCloud(t, fakecloud.WithProvider(providers[0]), fakecloud.WithProvider(providers[1]))
+
+	dir := t.TempDir()
+
+	err := factory.Factory(newConfigurator(t, apiURL, dir, nil)).Sync(t.Context())
+	require.NoError(t, err)
+
+	for _, p := range providers {
+		for _, bk := range p.Books {
+			got, err := os.ReadFile(filepath.Join(dir, bk.Name))
+			require.NoError(t, err)
+
+			assert.Equal(t, bk.Content, got)
+		}
+	}
+}
+
+func TestFactory_PageLimit(t *testing.T) {
+	t.Parallel()
+
+	provider := fakecloud.Demo(1, 5)[0]
+	_, apiURL := startFakeCloud(t, fakecloud.WithProvider(provider), fakecloud.WithPageLimit(2))
+
+	dir := t.TempDir()
+
+	err := factory.Factory(newConfigurator(t, apiURL, dir, nil)).Sync(t.Context())
+	require.NoError(t, err)
+
+	for _, bk := range provider.Books {
+		got, err := os.ReadFile(filepath.Join(dir, bk.Name))
+		require.NoError(t, err)
+
+		assert.Equal(t, bk.Content, got)
+	}
+}
+
+func TestFactory_Status(t *testing.T) {
+	t.Parallel()
+
+	providers := fakecloud.Demo(1, 2)
+	_, apiURL := startFakeCloud(t, fakecloud.WithProvider(providers[0]))
+
+	dir := t.TempDir()
+
+	st, err := factory.StatusFactory(newConfigurator(t, apiURL, dir, nil)).Status(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, st.Missing, 2)
+
+	require.NoError(t, factory.Factory(newConfigurator(t, apiURL, dir, nil)).Sync(t.Context()))
+
+	st, err = factory.StatusFactory(newConfigurator(t, apiURL, dir, nil)).Status(t.Context())
+	require.NoError(t, err)
+	assert.True(t, st.InSync(), "status after the sync: %+v", st)
+}
+
+func TestFactory_List(t *testing.T) {
+	t.Parallel()
+
+	_, apiURL := startFakeCloud(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	ctrl := gomock.NewController(t)
+	cfgMock := mocks.NewMockConfigurator(ctrl)
+
+	cfgMock.EXPECT().ClientID().Return("some client id")
+	cfgMock.EXPECT().ClientSecret().Return("some client secret")
+	cfgMock.EXPECT().APIURL().Return(apiURL)
+	cfgMock.EXPECT().UserName().Return("some user name")
+	cfgMock.EXPECT().Password().Return("some password")
+	cfgMock.EXPECT().Providers().Return(nil)
+	cfgMock.EXPECT().ProvidersConcurrency().Return(1)
+	cfgMock.EXPECT().Token().Return("")
+	cfgMock.EXPECT().StateDirectory().Return(t.TempDir())
+	cfgMock.EXPECT().Transport().Return(nil)
+
+	got, err := factory.ListFactory(cfgMock).Books(t.Context())
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	assert.Equal(t, "11", got[0].ID)
+	assert.Equal(t, "book-1-1.txt", got[0].FileName)
+	assert.Equal(t, "Provider 1", got[0].Provider)
+	assert.Equal(t, "txt", got[0].Format)
+	assert.Equal(t, int64(len("Book 1 of provider 1\n")), got[0].Size)
+}
+
+func TestFactory_TraceHTTP(t *testing.T) {
+	t.Parallel()
+
+	_, apiURL := startFakeCloud(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	rec := har.New(filepath.Join(t.TempDir(), "trace.har"))
+
+	err := factory.Factory(newConfigurator(t, apiURL, t.TempDir(), rec.Transport(nil))).Sync(t.Context())
+	require.NoError(t, err)
+
+	var urls []string
+
+	for _, e := range rec.Entries() {
+		assert.Equal(t, http.StatusOK, e.Response.Status)
+		assert.NotContains(t, e.Request.URL, "some password")
+
+		urls = append(urls, e.Request.URL)
+	}
+
+	// Providers, login, books count, books and the download of the book.
+	require.Len(t, urls, 5)
+	assert.True(t, strings.HasPrefix(urls[0], apiURL), "the API requests are traced after the base URL is applied")
+}
+
+func TestFactory_ExpiredTokens(t *testing.T) {
+	t.Parallel()
+
+	fake, apiURL := startFakeCloud(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	dir := t.TempDir()
+
+	err := factory.Factory(newConfigurator(t, apiURL, dir, nil)).Sync(t.Context())
+	require.NoError(t, err)
+
+	fake.ExpireTokens()
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "book-1-1.txt")))
+
+	err = factory.Factory(newConfigurator(t, apiURL, dir, nil)).Sync(t.Context())
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, fake.Requests(fakecloud.EndpointLogin))
+	assert.FileExists(t, filepath.Join(dir, "book-1-1.txt"))
+}
+
+func TestFactory_Error_Download(t *testing.T) {
+	t.Parallel()
+
+	fake, apiURL := startFakeCloud(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	fake.Fail(fakecloud.EndpointFiles, fakecloud.Failure{Status: http.StatusServiceUnavailable})
+
+	err := factory.Factory(newConfigurator(t, apiURL, t.TempDir(), nil)).Sync(t.Context())
+	require.ErrorContains(t, err, "503 Service Unavailable")
+}
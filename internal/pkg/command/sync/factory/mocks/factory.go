@@ -10,7 +10,9 @@
 package mocks
 
 import (
+	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -39,6 +41,44 @@ func (m *MockConfigurator) EXPECT() *MockConfiguratorMockRecorder {
 	return m.recorder
 }
 
+// APIURL mocks base method.
+func (m *MockConfigurator) APIURL() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "APIURL")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// APIURL indicates an expected call of APIURL.
+func (mr *MockConfiguratorMockRecorder) APIURL() *MockConfiguratorAPIURLCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "APIURL", reflect.TypeOf((*MockConfigurator)(nil).APIURL))
+	return &MockConfiguratorAPIURLCall{Call: call}
+}
+
+// MockConfiguratorAPIURLCall wrap *gomock.Call
+type MockConfiguratorAPIURLCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorAPIURLCall) Return(arg0 string) *MockConfiguratorAPIURLCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorAPIURLCall) Do(f func() string) *MockConfiguratorAPIURLCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorAPIURLCall) DoAndReturn(f func() string) *MockConfiguratorAPIURLCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ClientID mocks base method.
 func (m *MockConfigurator) ClientID() string {
 	m.ctrl.T.Helper()
@@ -153,6 +193,44 @@ func (c *MockConfiguratorDirectoryCall) DoAndReturn(f func() string) *MockConfig
 	return c
 }
 
+// LockWait mocks base method.
+func (m *MockConfigurator) LockWait() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockWait")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// LockWait indicates an expected call of LockWait.
+func (mr *MockConfiguratorMockRecorder) LockWait() *MockConfiguratorLockWaitCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockWait", reflect.TypeOf((*MockConfigurator)(nil).LockWait))
+	return &MockConfiguratorLockWaitCall{Call: call}
+}
+
+// MockConfiguratorLockWaitCall wrap *gomock.Call
+type MockConfiguratorLockWaitCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorLockWaitCall) Return(arg0 time.Duration) *MockConfiguratorLockWaitCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorLockWaitCall) Do(f func() time.Duration) *MockConfiguratorLockWaitCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorLockWaitCall) DoAndReturn(f func() time.Duration) *MockConfiguratorLockWaitCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Password mocks base method.
 func (m *MockConfigurator) Password() string {
 	m.ctrl.T.Helper()
@@ -191,6 +269,196 @@ func (c *MockConfiguratorPasswordCall) DoAndReturn(f func() string) *MockConfigu
 	return c
 }
 
+// Providers mocks base method.
+func (m *MockConfigurator) Providers() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Providers")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// Providers indicates an expected call of Providers.
+func (mr *MockConfiguratorMockRecorder) Providers() *MockConfiguratorProvidersCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Providers", reflect.TypeOf((*MockConfigurator)(nil).Providers))
+	return &MockConfiguratorProvidersCall{Call: call}
+}
+
+// MockConfiguratorProvidersCall wrap *gomock.Call
+type MockConfiguratorProvidersCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorProvidersCall) Return(arg0 []string) *MockConfiguratorProvidersCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorProvidersCall) Do(f func() []string) *MockConfiguratorProvidersCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorProvidersCall) DoAndReturn(f func() []string) *MockConfiguratorProvidersCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ProvidersConcurrency mocks base method.
+func (m *MockConfigurator) ProvidersConcurrency() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProvidersConcurrency")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// ProvidersConcurrency indicates an expected call of ProvidersConcurrency.
+func (mr *MockConfiguratorMockRecorder) ProvidersConcurrency() *MockConfiguratorProvidersConcurrencyCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProvidersConcurrency", reflect.TypeOf((*MockConfigurator)(nil).ProvidersConcurrency))
+	return &MockConfiguratorProvidersConcurrencyCall{Call: call}
+}
+
+// MockConfiguratorProvidersConcurrencyCall wrap *gomock.Call
+type MockConfiguratorProvidersConcurrencyCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorProvidersConcurrencyCall) Return(arg0 int) *MockConfiguratorProvidersConcurrencyCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorProvidersConcurrencyCall) Do(f func() int) *MockConfiguratorProvidersConcurrencyCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorProvidersConcurrencyCall) DoAndReturn(f func() int) *MockConfiguratorProvidersConcurrencyCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// StateDirectory mocks base method.
+func (m *MockConfigurator) StateDirectory() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StateDirectory")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// StateDirectory indicates an expected call of StateDirectory.
+func (mr *MockConfiguratorMockRecorder) StateDirectory() *MockConfiguratorStateDirectoryCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StateDirectory", reflect.TypeOf((*MockConfigurator)(nil).StateDirectory))
+	return &MockConfiguratorStateDirectoryCall{Call: call}
+}
+
+// MockConfiguratorStateDirectoryCall wrap *gomock.Call
+type MockConfiguratorStateDirectoryCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorStateDirectoryCall) Return(arg0 string) *MockConfiguratorStateDirectoryCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorStateDirectoryCall) Do(f func() string) *MockConfiguratorStateDirectoryCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorStateDirectoryCall) DoAndReturn(f func() string) *MockConfiguratorStateDirectoryCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Token mocks base method.
+func (m *MockConfigurator) Token() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Token indicates an expected call of Token.
+func (mr *MockConfiguratorMockRecorder) Token() *MockConfiguratorTokenCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*MockConfigurator)(nil).Token))
+	return &MockConfiguratorTokenCall{Call: call}
+}
+
+// MockConfiguratorTokenCall wrap *gomock.Call
+type MockConfiguratorTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorTokenCall) Return(arg0 string) *MockConfiguratorTokenCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorTokenCall) Do(f func() string) *MockConfiguratorTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorTokenCall) DoAndReturn(f func() string) *MockConfiguratorTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Transport mocks base method.
+func (m *MockConfigurator) Transport() http.RoundTripper {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transport")
+	ret0, _ := ret[0].(http.RoundTripper)
+	return ret0
+}
+
+// Transport indicates an expected call of Transport.
+func (mr *MockConfiguratorMockRecorder) Transport() *MockConfiguratorTransportCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transport", reflect.TypeOf((*MockConfigurator)(nil).Transport))
+	return &MockConfiguratorTransportCall{Call: call}
+}
+
+// MockConfiguratorTransportCall wrap *gomock.Call
+type MockConfiguratorTransportCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConfiguratorTransportCall) Return(arg0 http.RoundTripper) *MockConfiguratorTransportCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConfiguratorTransportCall) Do(f func() http.RoundTripper) *MockConfiguratorTransportCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConfiguratorTransportCall) DoAndReturn(f func() http.RoundTripper) *MockConfiguratorTransportCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // UserName mocks base method.
 func (m *MockConfigurator) UserName() string {
 	m.ctrl.T.Helper()
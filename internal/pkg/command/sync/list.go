@@ -0,0 +1,294 @@
+package sync
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/har"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+)
+
+const (
+	outputCSV      = "csv"
+	outputMarkdown = "markdown"
+)
+
+// listColumns are the columns of the list in all outputs.
+var listColumns = [...]string{"provider", "name", "format", "size", "id"}
+
+type factoryLister func(config factory.Configurator) factory.Lister
+
+// List prints the books of the cloud.
+// It takes the environment variables and the config file of the sync command.
+type List struct {
+	sync    *Sync
+	factory factoryLister
+	sort    string
+	formats string
+	name    string
+}
+
+func NewList(factory factoryLister, opts ...Option) *List {
+	s := New(nil, opts...)
+	s.flags = newQueryFlagSet("list", s.cfg)
+
+	l := &List{
+		sync:    s,
+		factory: factory,
+	}
+
+	s.flags.StringVar(&s.cfg.output, "output", outputText, "Output of the books: text - the table, json - the array of objects,\n"+
+		"csv - the table with the header for spreadsheets, markdown - the table for wikis.")
+
+	s.flags.StringVar(&l.sort, "sort", "name", "Column to sort the books by: "+strings.Join(listColumns[:], ", ")+".\n"+
+		"Prefix the column with \"-\" for the descending order, e.g. \"-size\".")
+
+	s.flags.StringVar(&l.formats, "formats", "", "Comma-separated list of formats of the listed books, e.g. \"epub,pdf\". By default all books are listed.")
+
+	s.flags.StringVar(&l.name, "name", "", "List only the books whose names contain the text regardless of the case.")
+
+	return l
+}
+
+func (l *List) Description() string {
+	return "Prints the books of the cloud as a table, JSON, CSV or Markdown."
+}
+
+func (l *List) Help() string {
+	buf := &bytes.Buffer{}
+
+	l.sync.flags.SetOutput(buf)
+	l.sync.flags.Usage()
+
+	return buf.String()
+}
+
+func (l *List) Run(args []string) error {
+	var err error
+
+	if err = l.sync.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return fmt.Errorf("flag parse: %v", err)
+	}
+
+	cfg, err := loadConfig(l.sync.cfg)
+	if err != nil {
+		return err
+	}
+
+	redact.Register(cfg.secrets()...)
+
+	if err = l.validation(*cfg); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	logCfg, err := cfg.logConfig()
+	if err != nil {
+		return fmt.Errorf("log config: %w", err)
+	}
+
+	// The standard output is left to the list.
+	logCfg.Output = os.Stderr
+
+	if err = logging.Setup(logCfg); err != nil {
+		return fmt.Errorf("setup logging: %w", err)
+	}
+
+	if cfg.traceHTTP != "" {
+		cfg.transport = har.New(cfg.traceHTTP).Transport(nil)
+	}
+
+	ctx, cancel := shutdown.Notify(context.Background(), 0, shutdownSignals(false)...)
+	defer cancel()
+
+	bks, err := l.factory(cfg).Books(ctx)
+	if err != nil {
+		return fmt.Errorf("get books: %w", err)
+	}
+
+	bks = l.filter(bks)
+	l.sortBooks(bks)
+
+	if err = printBooks(l.sync.stdout, cfg.output, bks); err != nil {
+		return fmt.Errorf("print books: %w", err)
+	}
+
+	return nil
+}
+
+// validation checks the configuration like the sync command, but the list has more outputs and doesn't use the directory.
+func (l *List) validation(cfg config) error {
+	switch cfg.output {
+	case outputText, outputJSON, outputCSV, outputMarkdown:
+	default:
+		return fmt.Errorf("%w: output must be text, json, csv or markdown", errInvalidValue)
+	}
+
+	if !slices.Contains(listColumns[:], strings.TrimPrefix(l.sort, "-")) {
+		return fmt.Errorf("%w: sort must be one of %s", errInvalidValue, strings.Join(listColumns[:], ", "))
+	}
+
+	cfg.output = outputText
+
+	return settingsCheck(cfg)
+}
+
+func (l *List) filter(bks []domain.Book) []domain.Book {
+	formats := slices.DeleteFunc(splitList(strings.ToLower(l.formats)), func(f string) bool { return f == "" })
+	name := strings.ToLower(l.name)
+
+	return slices.DeleteFunc(bks, func(bk domain.Book) bool {
+		if len(formats) > 0 && !slices.Contains(formats, bookFormat(bk)) {
+			return true
+		}
+
+		return !strings.Contains(strings.ToLower(bk.FileName), name)
+	})
+}
+
+func (l *List) sortBooks(bks []domain.Book) {
+	column, desc := strings.CutPrefix(l.sort, "-")
+
+	slices.SortStableFunc(bks, func(a, b domain.Book) int {
+		var c int
+
+		switch column {
+		case "provider":
+			c = strings.Compare(a.Provider, b.Provider)
+		case "format":
+			c = strings.Compare(bookFormat(a), bookFormat(b))
+		case "size":
+			c = cmp.Compare(a.Size, b.Size)
+		case "id":
+			c = strings.Compare(a.ID, b.ID)
+		}
+
+		// The books are ordered by the name within the column.
+		c = cmp.Or(c, strings.Compare(a.FileName, b.FileName))
+
+		if desc {
+			return -c
+		}
+
+		return c
+	})
+}
+
+// bookFormat returns the format of the book, the extension of the file if the cloud doesn't tell it.
+func bookFormat(bk domain.Book) string {
+	if bk.Format != "" {
+		return strings.ToLower(bk.Format)
+	}
+
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(bk.FileName), "."))
+}
+
+func bookRow(bk domain.Book) []string {
+	return []string{bk.Provider, bk.FileName, bookFormat(bk), strconv.FormatInt(bk.Size, 10), bk.ID}
+}
+
+func printBooks(w io.Writer, output string, bks []domain.Book) error {
+	switch output {
+	case outputJSON:
+		return printBooksJSON(w, bks)
+	case outputCSV:
+		return printBooksCSV(w, bks)
+	case outputMarkdown:
+		return printBooksMarkdown(w, bks)
+	default:
+		return printBooksTable(w, bks)
+	}
+}
+
+func printBooksTable(w io.Writer, bks []domain.Book) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, strings.ToUpper(strings.Join(listColumns[:], "\t")))
+
+	for _, bk := range bks {
+		_, _ = fmt.Fprintln(tw, strings.Join(bookRow(bk), "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func printBooksJSON(w io.Writer, bks []domain.Book) error {
+	type book struct {
+		Provider string `json:"provider"`
+		Name     string `json:"name"`
+		Format   string `json:"format"`
+		Size     int64  `json:"size"`
+		ID       string `json:"id"`
+	}
+
+	list := make([]book, 0, len(bks))
+
+	for _, bk := range bks {
+		list = append(list, book{Provider: bk.Provider, Name: bk.FileName, Format: bookFormat(bk), Size: bk.Size, ID: bk.ID})
+	}
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+
+	return err
+}
+
+func printBooksCSV(w io.Writer, bks []domain.Book) error {
+	cw := csv.NewWriter(w)
+
+	_ = cw.Write(listColumns[:])
+
+	for _, bk := range bks {
+		_ = cw.Write(bookRow(bk))
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func printBooksMarkdown(w io.Writer, bks []domain.Book) error {
+	buf := &bytes.Buffer{}
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	fmt.Fprintf(buf, "| %s |\n", strings.Join(listColumns[:], " | "))
+	fmt.Fprintf(buf, "|%s\n", strings.Repeat(" --- |", len(listColumns)))
+
+	for _, bk := range bks {
+		row := bookRow(bk)
+
+		for i := range row {
+			row[i] = escape.Replace(row[i])
+		}
+
+		fmt.Fprintf(buf, "| %s |\n", strings.Join(row, " | "))
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
@@ -0,0 +1,163 @@
+package sync_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+)
+
+type mockLister struct {
+	mock.Mock
+}
+
+func (m *mockLister) Books(ctx context.Context) ([]domain.Book, error) {
+	args := m.Called(ctx)
+
+	return args.Get(0).([]domain.Book), args.Error(1)
+}
+
+func listBooks() []domain.Book {
+	return []domain.Book{
+		{ID: "12", FileName: "Emma.pdf", Provider: "Provider 1", Format: "PDF", Size: 2048},
+		{ID: "11", FileName: "Dune.epub", Provider: "Provider 1", Format: "epub", Size: 1024},
+		{ID: "21", FileName: "Dune | Messiah.fb2", Provider: "Provider 2", Size: 4096},
+	}
+}
+
+func TestList_Run(t *testing.T) {
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	tests := [...]struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name: "table",
+			expect: "PROVIDER    NAME                FORMAT  SIZE  ID\n" +
+				"Provider 2  Dune | Messiah.fb2  fb2     4096  21\n" +
+				"Provider 1  Dune.epub           epub    1024  11\n" +
+				"Provider 1  Emma.pdf            pdf     2048  12\n",
+		},
+		{
+			name: "json",
+			args: []string{"-output", "json", "-sort", "-size", "-formats", "epub,fb2"},
+			expect: `[
+  {
+    "provider": "Provider 2",
+    "name": "Dune | Messiah.fb2",
+    "format": "fb2",
+    "size": 4096,
+    "id": "21"
+  },
+  {
+    "provider": "Provider 1",
+    "name": "Dune.epub",
+    "format": "epub",
+    "size": 1024,
+    "id": "11"
+  }
+]
+`,
+		},
+		{
+			name: "formats with spaces",
+			args: []string{"-formats", " EPUB, ,pdf "},
+			expect: "PROVIDER    NAME       FORMAT  SIZE  ID\n" +
+				"Provider 1  Dune.epub  epub    1024  11\n" +
+				"Provider 1  Emma.pdf   pdf     2048  12\n",
+		},
+		{
+			name: "csv",
+			args: []string{"-output", "csv", "-sort", "id"},
+			expect: "provider,name,format,size,id\n" +
+				"Provider 1,Dune.epub,epub,1024,11\n" +
+				"Provider 1,Emma.pdf,pdf,2048,12\n" +
+				"Provider 2,Dune | Messiah.fb2,fb2,4096,21\n",
+		},
+		{
+			name: "markdown",
+			args: []string{"-output", "markdown", "-name", "MESSIAH"},
+			expect: "| provider | name | format | size | id |\n" +
+				"| --- | --- | --- | --- | --- |\n" +
+				"| Provider 2 | Dune \\| Messiah.fb2 | fb2 | 4096 | 21 |\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listerMock := &mockLister{}
+			listerMock.On("Books", mock.Anything).Return(listBooks(), nil)
+
+			var buf bytes.Buffer
+
+			cmd := sync.NewList(func(factory.Configurator) factory.Lister { return listerMock }, sync.WithStdout(&buf))
+
+			args := append([]string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-dir", "not-existing",
+			}, tt.args...)
+
+			require.NoError(t, cmd.Run(args))
+			assert.Equal(t, tt.expect, buf.String())
+
+			listerMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestList_Run_Error_Validation(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		args   []string
+		expect string
+	}{
+		{
+			name:   "output",
+			args:   []string{"-output", "yaml"},
+			expect: "validate: invalid value: output must be text, json, csv or markdown",
+		},
+		{
+			name:   "sort",
+			args:   []string{"-sort", "title"},
+			expect: "validate: invalid value: sort must be one of provider, name, format, size, id",
+		},
+		{
+			name:   "credentials",
+			args:   []string{"-client-id", ""},
+			expect: "validate: client-id is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := sync.NewList(nil)
+
+			err := cmd.Run(append(queryArgs(), tt.args...))
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
+
+func TestList_Help(t *testing.T) {
+	t.Parallel()
+
+	assert.Contains(t, sync.NewList(nil).Help(), "Usage of list:")
+}
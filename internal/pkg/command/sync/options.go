@@ -0,0 +1,12 @@
+package sync
+
+import "io"
+
+type Option func(*Sync)
+
+// WithStdout sets the standard output of the reports, [os.Stdout] by default.
+func WithStdout(w io.Writer) Option {
+	return func(s *Sync) {
+		s.stdout = w
+	}
+}
@@ -0,0 +1,169 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/har"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+)
+
+// ExitCodeOutOfSync is the exit status of the status command when books are missing locally.
+const ExitCodeOutOfSync = 2
+
+// ErrOutOfSync is returned by the status command when books are missing locally.
+var ErrOutOfSync = errors.New("out of sync")
+
+type factoryChecker func(config factory.Configurator) factory.Checker
+
+// Status compares the cloud with the directory without downloading.
+// It takes the environment variables and the config file of the sync command.
+type Status struct {
+	sync    *Sync
+	factory factoryChecker
+}
+
+func NewStatus(factory factoryChecker, opts ...Option) *Status {
+	s := New(nil, opts...)
+	s.flags = newQueryFlagSet("status", s.cfg)
+
+	s.flags.StringVar(&s.cfg.output, "output", outputText, "Output of the status: text - the counts and the names of the books,\n"+
+		"json - the JSON object with the lists of the books and the in_sync field.")
+
+	return &Status{
+		sync:    s,
+		factory: factory,
+	}
+}
+
+func (s Status) Description() string {
+	return "Compares the cloud with the directory without downloading, exits with status " +
+		strconv.Itoa(ExitCodeOutOfSync) + " if they differ."
+}
+
+func (s Status) Help() string {
+	buf := &bytes.Buffer{}
+
+	s.sync.flags.SetOutput(buf)
+	s.sync.flags.Usage()
+
+	return buf.String()
+}
+
+func (s Status) Run(args []string) error {
+	var err error
+
+	if err = s.sync.flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return fmt.Errorf("flag parse: %v", err)
+	}
+
+	cfg, err := loadConfig(s.sync.cfg)
+	if err != nil {
+		return err
+	}
+
+	redact.Register(cfg.secrets()...)
+
+	if err = validation(*cfg); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	logCfg, err := cfg.logConfig()
+	if err != nil {
+		return fmt.Errorf("log config: %w", err)
+	}
+
+	// The standard output is left to the status.
+	logCfg.Output = os.Stderr
+
+	if err = logging.Setup(logCfg); err != nil {
+		return fmt.Errorf("setup logging: %w", err)
+	}
+
+	if cfg.traceHTTP != "" {
+		cfg.transport = har.New(cfg.traceHTTP).Transport(nil)
+	}
+
+	ctx, cancel := shutdown.Notify(context.Background(), 0, shutdownSignals(false)...)
+	defer cancel()
+
+	st, err := s.factory(cfg).Status(ctx)
+	if err != nil {
+		return fmt.Errorf("status: %w", err)
+	}
+
+	if cfg.output == outputJSON {
+		err = printStatusJSON(s.sync.stdout, st)
+	} else {
+		err = printStatus(s.sync.stdout, st)
+	}
+
+	if err != nil {
+		return fmt.Errorf("print status: %w", err)
+	}
+
+	if !st.InSync() {
+		return fmt.Errorf("%w: %d missing locally", ErrOutOfSync, len(st.Missing))
+	}
+
+	return nil
+}
+
+func printStatusJSON(w io.Writer, st sync.Status) error {
+	data, err := json.MarshalIndent(struct {
+		sync.Status
+		InSync bool `json:"in_sync"`
+	}{st, st.InSync()}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+
+	return err
+}
+
+func printStatus(w io.Writer, st sync.Status) error {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "cloud: %d, local: %d, missing locally: %d, local only: %d, possibly updated: %d\n",
+		st.Total, st.Local, len(st.Missing), len(st.LocalOnly), len(st.Updated))
+
+	for _, l := range [...]struct {
+		title string
+		names []string
+	}{
+		{"missing locally", st.Missing},
+		{"local only", st.LocalOnly},
+		{"possibly updated", st.Updated},
+	} {
+		if len(l.names) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "\n%s:\n", l.title)
+
+		for _, n := range l.names {
+			fmt.Fprintf(buf, "  %s\n", n)
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
@@ -0,0 +1,218 @@
+package sync_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/micronull/pocketbook-cloud-sync/internal/app/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+)
+
+type mockChecker struct {
+	mock.Mock
+}
+
+func (m *mockChecker) Status(ctx context.Context) (app.Status, error) {
+	args := m.Called(ctx)
+
+	return args.Get(0).(app.Status), args.Error(1)
+}
+
+// queryArgs are the flags of the status and list commands.
+func queryArgs() []string {
+	return []string{
+		"-client-id", "some-id",
+		"-client-secret", "some-secret",
+		"-dir", "testdata",
+		"-password", "some-password",
+		"-username", "some-username",
+	}
+}
+
+func TestStatus_Run(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	tests := [...]struct {
+		name   string
+		status app.Status
+		expect string
+		err    string
+	}{
+		{
+			name:   "in sync",
+			status: app.Status{Total: 1, Local: 1, Missing: []string{}, LocalOnly: []string{}, Updated: []string{}},
+			expect: "cloud: 1, local: 1, missing locally: 0, local only: 0, possibly updated: 0\n",
+		},
+		{
+			name: "out of sync",
+			status: app.Status{
+				Total:     3,
+				Local:     2,
+				Missing:   []string{"first.epub", "second.epub"},
+				LocalOnly: []string{"local.pdf"},
+				Updated:   []string{},
+			},
+			expect: "cloud: 3, local: 2, missing locally: 2, local only: 1, possibly updated: 0\n" +
+				"\n" +
+				"missing locally:\n" +
+				"  first.epub\n" +
+				"  second.epub\n" +
+				"\n" +
+				"local only:\n" +
+				"  local.pdf\n",
+			err: "out of sync: 2 missing locally",
+		},
+		{
+			name: "possibly updated",
+			status: app.Status{
+				Total:     1,
+				Local:     1,
+				Missing:   []string{},
+				LocalOnly: []string{},
+				Updated:   []string{"first.epub"},
+			},
+			expect: "cloud: 1, local: 1, missing locally: 0, local only: 0, possibly updated: 1\n" +
+				"\n" +
+				"possibly updated:\n" +
+				"  first.epub\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkerMock := &mockChecker{}
+			checkerMock.On("Status", mock.Anything).Return(tt.status, nil)
+
+			var buf bytes.Buffer
+
+			cmd := sync.NewStatus(func(config factory.Configurator) factory.Checker {
+				assert.Equal(t, "testdata", config.Directory())
+
+				return checkerMock
+			}, sync.WithStdout(&buf))
+
+			err := cmd.Run(queryArgs())
+
+			if tt.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, sync.ErrOutOfSync)
+				require.EqualError(t, err, tt.err)
+			}
+
+			assert.Equal(t, tt.expect, buf.String())
+
+			checkerMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStatus_Run_JSON(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	checkerMock := &mockChecker{}
+	checkerMock.On("Status", mock.Anything).Return(app.Status{
+		Total:     1,
+		Missing:   []string{"first.epub"},
+		LocalOnly: []string{},
+		Updated:   []string{},
+	}, nil)
+
+	var buf bytes.Buffer
+
+	cmd := sync.NewStatus(func(factory.Configurator) factory.Checker { return checkerMock }, sync.WithStdout(&buf))
+
+	err := cmd.Run(append(queryArgs(), "-output", "json"))
+	require.ErrorIs(t, err, sync.ErrOutOfSync)
+
+	assert.JSONEq(t, `{
+		"total": 1,
+		"local": 0,
+		"missing": ["first.epub"],
+		"local_only": [],
+		"updated": [],
+		"in_sync": false
+	}`, buf.String())
+}
+
+func TestStatus_Run_Error(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	errExpected := errors.New("some error")
+
+	checkerMock := &mockChecker{}
+	checkerMock.On("Status", mock.Anything).Return(app.Status{}, errExpected)
+
+	cmd := sync.NewStatus(func(factory.Configurator) factory.Checker { return checkerMock })
+
+	err := cmd.Run(queryArgs())
+	require.ErrorIs(t, err, errExpected)
+	assert.NotErrorIs(t, err, sync.ErrOutOfSync)
+}
+
+func TestStatus_Help(t *testing.T) {
+	t.Parallel()
+
+	assert.Contains(t, sync.NewStatus(nil).Help(), "Usage of status:")
+}
+
+func TestStatus_Run_Error_SyncFlag(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]string{"-daemon", "-webhook=http://localhost", "-history-retention=0", "-log-file=sync.log"}
+
+	for _, arg := range tests {
+		t.Run(arg, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := sync.NewStatus(nil, sync.WithStdout(io.Discard))
+
+			err := cmd.Run(append(queryArgs(), arg))
+			require.ErrorContains(t, err, "flag provided but not defined")
+		})
+	}
+}
+
+func TestStatus_Run_ConfigFile(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	// The config file shared with the sync command may set its other flags.
+	path := filepath.Join(t.TempDir(), "pbcsync.conf")
+	require.NoError(t, os.WriteFile(path, []byte("daemon\nschedule=0 3 * * *\nproviders=1234\n"), 0o600))
+
+	checkerMock := &mockChecker{}
+	checkerMock.On("Status", mock.Anything).Return(app.Status{}, nil)
+
+	cmd := sync.NewStatus(func(config factory.Configurator) factory.Checker {
+		assert.Equal(t, []string{"1234"}, config.Providers())
+
+		return checkerMock
+	}, sync.WithStdout(io.Discard))
+
+	require.NoError(t, cmd.Run(append(queryArgs(), "-config", path)))
+
+	checkerMock.AssertExpectations(t)
+}
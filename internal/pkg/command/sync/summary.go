@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+const reportFilePerm = 0o644
+
+// summary writes the JSON report of each run to the report file, the output and the history journal.
+type summary struct {
+	path    string
+	out     io.Writer
+	journal *history.Journal
+	config  atomic.Pointer[string]
+}
+
+// newSummary returns nil if the configuration has neither the report file, the JSON output nor the history.
+func newSummary(cfg *config, stdout io.Writer) *summary {
+	if cfg.reportFile == "" && cfg.output != outputJSON && cfg.historyAge == 0 {
+		return nil
+	}
+
+	s := &summary{path: cfg.reportFile}
+
+	if cfg.historyAge > 0 {
+		s.journal = history.New(filepath.Join(cfg.StateDirectory(), history.FileName), cfg.historyAge)
+	}
+
+	if cfg.output == outputJSON {
+		s.out = stdout
+	}
+
+	s.reconfigure(cfg)
+
+	return s
+}
+
+// reconfigure changes the fingerprint of the configuration after the reload.
+func (s *summary) reconfigure(cfg *config) {
+	if s == nil {
+		return
+	}
+
+	fp := cfg.fingerprint()
+	s.config.Store(&fp)
+}
+
+func (s *summary) observe(_ context.Context, run report.Run) {
+	if s == nil {
+		return
+	}
+
+	sum := report.NewSummary(run, *s.config.Load())
+
+	if s.path != "" {
+		data, err := json.MarshalIndent(sum, "", "  ")
+		if err == nil {
+			err = statefile.WriteFile(s.path, append(data, '\n'), reportFilePerm)
+		}
+
+		if err != nil {
+			slog.Error("failed to write report", "error", err)
+		}
+	}
+
+	if s.out != nil {
+		if err := json.NewEncoder(s.out).Encode(sum); err != nil {
+			slog.Error("failed to print report", "error", err)
+		}
+	}
+
+	if s.journal != nil {
+		if err := s.journal.Append(sum); err != nil {
+			slog.Error("failed to write history", "error", err)
+		}
+	}
+}
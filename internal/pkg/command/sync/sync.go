@@ -7,17 +7,55 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/apiurl"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/har"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/health"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/email"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/books"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/systemd"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/trigger"
 )
 
-const daemonTimeoutDefault = time.Hour * 24
+const (
+	daemonTimeoutDefault = time.Hour * 24
+	// shutdownGraceDefault is less than 10 seconds Docker waits before killing the container.
+	shutdownGraceDefault = 8 * time.Second
+	daemonStateFile      = "daemon.json"
+	webhookStateFile     = "webhook.json"
+	emailStateFile       = "email.json"
+	emailDigestFile      = "digest.json"
+	mqttStateFile        = "mqtt.json"
+	pathMetrics          = "/metrics"
+	defaultTriggerFile   = "trigger"
+	outputText           = "text"
+	outputJSON           = "json"
+	logMaxSizeDefault    = 10
+	logMaxBackupsDefault = 5
+	// historyAgeDefault keeps the runs of the last month.
+	historyAgeDefault = 30 * 24 * time.Hour
+)
 
 type factorySynchronizer func(config factory.Configurator) factory.Synchronizer
 
@@ -25,13 +63,31 @@ type Sync struct {
 	flags   *flag.FlagSet
 	cfg     *config
 	factory factorySynchronizer
+	stdout  io.Writer
+	summary *summary
 }
 
-func New(factory factorySynchronizer) *Sync {
-	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
-
+func New(factory factorySynchronizer, opts ...Option) *Sync {
 	cfg := &config{}
 
+	s := &Sync{
+		flags:   newFlagSet(cfg),
+		cfg:     cfg,
+		factory: factory,
+		stdout:  os.Stdout,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// newFlagSet defines the flags bound to the config, the config gets the default values.
+func newFlagSet(cfg *config) *flag.FlagSet {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+
 	flags.BoolVar(&cfg.env, "env", false, "Enable environment variables mode.\n"+
 		"Ignores all command-line flags and loads values from environment variables:\n"+
 		"PBC_CLIENT_ID as -client-id\n"+
@@ -41,7 +97,59 @@ func New(factory factorySynchronizer) *Sync {
 		"DEBUG as -debug\n"+
 		"DIR as -dir\n"+
 		"DAEMON as -daemon\n"+
-		"DAEMON_TIMEOUT as -daemon-timeout")
+		"DAEMON_TIMEOUT as -daemon-timeout\n"+
+		"PBC_PROVIDERS as -providers\n"+
+		"PBC_PROVIDERS_CONCURRENCY as -providers-concurrency\n"+
+		"PBC_TOKEN as -token\n"+
+		"STATE_DIR as -state-dir\n"+
+		"PBC_API_URL as -api-url\n"+
+		"SCHEDULE as -schedule\n"+
+		"TIME_ZONE as -time-zone\n"+
+		"ERROR_POLICY as -error-policy\n"+
+		"MAX_FAILURES as -max-failures\n"+
+		"RETRY_BACKOFF as -retry-backoff\n"+
+		"RETRY_MAX_BACKOFF as -retry-max-backoff\n"+
+		"SYNC_ON_START as -sync-on-start\n"+
+		"TRIGGER_FILE as -trigger-file\n"+
+		"CONFIG_FILE as -config\n"+
+		"SHUTDOWN_GRACE as -shutdown-grace\n"+
+		"HTTP_ADDR as -http-addr\n"+
+		"READY_INTERVALS as -ready-intervals\n"+
+		"METRICS_FILE as -metrics-file\n"+
+		"LOCK_WAIT as -lock-wait\n"+
+		"WEBHOOK as -webhook\n"+
+		"WEBHOOK_TEMPLATE as -webhook-template\n"+
+		"WEBHOOK_ON as -webhook-on\n"+
+		"SMTP_ADDR as -smtp-addr\n"+
+		"SMTP_SECURITY as -smtp-security\n"+
+		"SMTP_USERNAME as -smtp-username\n"+
+		"SMTP_PASSWORD as -smtp-password\n"+
+		"EMAIL_FROM as -email-from\n"+
+		"EMAIL_TO as -email-to\n"+
+		"EMAIL_TEMPLATE as -email-template\n"+
+		"EMAIL_DIGEST as -email-digest\n"+
+		"EMAIL_ON as -email-on\n"+
+		"MQTT_BROKER as -mqtt-broker\n"+
+		"MQTT_USERNAME as -mqtt-username\n"+
+		"MQTT_PASSWORD as -mqtt-password\n"+
+		"MQTT_TOPIC as -mqtt-topic\n"+
+		"MQTT_QOS as -mqtt-qos\n"+
+		"MQTT_RETAIN as -mqtt-retain\n"+
+		"MQTT_DISCOVERY as -mqtt-discovery\n"+
+		"LOG_FORMAT as -log-format\n"+
+		"LOG_LEVEL as -log-level\n"+
+		"LOG_FILE as -log-file\n"+
+		"LOG_MAX_SIZE as -log-max-size\n"+
+		"LOG_MAX_AGE as -log-max-age\n"+
+		"LOG_MAX_BACKUPS as -log-max-backups\n"+
+		"TRACE_HTTP as -trace-http\n"+
+		"REPORT_FILE as -report-file\n"+
+		"OUTPUT as -output\n"+
+		"HISTORY_RETENTION as -history-retention")
+
+	flags.StringVar(&cfg.configFile, "config", "", "File with flags, one name=value pair per line, e.g. \"providers=1234\".\n"+
+		"Its values override the command-line flags and the environment variables.\n"+
+		"In daemon mode the configuration is reloaded on the SIGHUP signal.")
 
 	flags.StringVar(&cfg.clientID, "client-id", "", "Client ID of PocketBook Cloud API.\n"+
 		"Read the readme to find out how to get it.")
@@ -49,24 +157,176 @@ func New(factory factorySynchronizer) *Sync {
 	flags.StringVar(&cfg.clientSecret, "client-secret", "", "Client Secret of PocketBook Cloud API.\n"+
 		"Read the readme to find out how to get it.")
 
+	flags.StringVar(&cfg.apiURL, "api-url", apiurl.Default, "Base URL of PocketBook Cloud API.\n"+
+		"Allows to use a regional endpoint, a caching proxy or a local stand-in server.")
+
 	flags.StringVar(&cfg.userName, "username", "", "Username of PocketBook Cloud. Usually it's your email.")
 
 	flags.StringVar(&cfg.password, "password", "", "Password from your PocketBook Cloud account.")
 
+	flags.StringVar(&cfg.token, "token", "", "Pre-obtained access token of PocketBook Cloud, it is used while there is no valid cached token.\n"+
+		"Allows to run without the password, the password is used only if the token is rejected.\n"+
+		"The tokens aren't refreshed, the expired token falls back to the login with the password.")
+
 	flags.StringVar(&cfg.dir, "dir", "books", "Directory to sync files.")
 
-	flags.BoolVar(&cfg.debug, "debug", false, "Enable debug output.")
+	flags.DurationVar(&cfg.lockWait, "lock-wait", 0, "How long to wait for the directory locked by another process.\n"+
+		"By default the sync fails immediately naming PID of the holder.")
+
+	flags.StringVar(&cfg.stateDir, "state-dir", "", "Directory for internal files like cached access tokens.\n"+
+		"By default \""+statefile.DirName+"\" inside the sync directory.")
+
+	flags.DurationVar(&cfg.shutdownGrace, "shutdown-grace", shutdownGraceDefault, "How long to wait for running downloads on shutdown.\n"+
+		"No new downloads are started after the first signal. The second signal or the end of the period\n"+
+		"aborts the downloads, removes partial files and exits with status "+strconv.Itoa(shutdown.ExitCodeAborted)+".")
+
+	flags.BoolVar(&cfg.debug, "debug", false, "Enable debug output, the same as -log-level=debug.")
+
+	flags.StringVar(&cfg.logFormat, "log-format", string(logging.FormatText), "Format of the log:\n"+
+		"text - time, level, message and key=value attributes, json - JSON object per line,\n"+
+		"logfmt - key=value pairs including time, level and message.")
+
+	flags.StringVar(&cfg.logLevel, "log-level", slog.LevelInfo.String(), "Minimal level of the log: debug, info, warn or error.\n"+
+		"In daemon mode the level is changed by the config reload.")
+
+	flags.StringVar(&cfg.logFile, "log-file", "", "File to write the log instead of the standard output, it is rotated by the size and the age.")
+
+	flags.IntVar(&cfg.logMaxSize, "log-max-size", logMaxSizeDefault, "Size of the log file in megabytes after which it is rotated, 0 is unlimited.")
+
+	flags.DurationVar(&cfg.logMaxAge, "log-max-age", 0, "Age of the log file after which it is rotated, e.g. \"24h\". Unlimited by default.")
+
+	flags.IntVar(&cfg.logMaxBackups, "log-max-backups", logMaxBackupsDefault, "How many rotated log files are kept, 0 keeps all.")
+
+	flags.StringVar(&cfg.traceHTTP, "trace-http", "", "File to record HTTP traffic of the API and the downloads in HAR format for bug reports.\n"+
+		"The credentials and the tokens are masked, the bodies are truncated and the books are omitted.")
 
 	flags.BoolVar(&cfg.daemon, "daemon", false, "Enable daemon mode. Use the daemon-timeout flag for setting sync interval.")
 
 	flags.DurationVar(&cfg.daemonTimeout, "daemon-timeout", daemonTimeoutDefault, "Timeout for sync operation. \n"+
 		"Used only daemon mode.")
 
-	return &Sync{
-		flags:   flags,
-		cfg:     cfg,
-		factory: factory,
+	flags.BoolVar(&cfg.syncOnStart, "sync-on-start", false, "Sync right after the start in daemon mode.\n"+
+		"By default the daemon continues the schedule of the previous run saved in the state directory.")
+
+	flags.StringVar(&cfg.triggerFile, "trigger-file", "", "File requesting an immediate sync in daemon mode, it is removed when noticed.\n"+
+		"The sync is also requested by the SIGUSR1 signal. By default \""+defaultTriggerFile+"\" inside the state directory.")
+
+	flags.StringVar(&cfg.schedule, "schedule", "", "Cron expression of sync times in daemon mode, e.g. \"0 3 * * *\" or \"@daily\".\n"+
+		"Overrides the daemon-timeout flag.")
+
+	flags.StringVar(&cfg.httpAddr, "http-addr", "", "Address of the health server in daemon mode, e.g. \":8080\". Disabled by default.\n"+
+		"Endpoints: "+health.PathHealth+" - the process is alive, "+health.PathReady+" - the last sync has succeeded recently,\n"+
+		health.PathStatus+" - JSON status of the last and the next runs, "+pathMetrics+" - Prometheus metrics.")
+
+	flags.StringVar(&cfg.metricsFile, "metrics-file", "", "File to write Prometheus metrics after the sync without daemon mode,\n"+
+		"e.g. for the textfile collector of node_exporter.")
+
+	flags.StringVar(&cfg.reportFile, "report-file", "", "File to write the JSON report after each run: the run ID, the times, the fingerprint of the configuration,\n"+
+		"the outcome of each book - downloaded, skipped, filtered or failed with the reason, the bytes and the durations.")
+
+	flags.StringVar(&cfg.output, "output", outputText, "Output of the command: text - the log only,\n"+
+		"json - the JSON report of each run on a line of the standard output, the log is moved to the standard error.")
+
+	flags.DurationVar(&cfg.historyAge, "history-retention", historyAgeDefault, "How long the runs are kept in the history journal\n"+
+		"in the state directory, see the history command. 0 disables the journal.")
+
+	flags.StringVar(&cfg.webhooks, "webhook", "", "Comma-separated list of URLs receiving the JSON report after each run:\n"+
+		"status, error, counts of books and names of the downloaded books.")
+
+	flags.StringVar(&cfg.webhookTmpl, "webhook-template", "", "File with Go template of the webhook body, e.g. for Slack, Discord, Gotify or ntfy.\n"+
+		"The fields of the JSON report are available as .Status, .Error, .Start, .End, .Total, .Skipped,\n"+
+		".Downloaded and .NewBooks, the functions json and join help to build the body.")
+
+	flags.StringVar(&cfg.webhookOn, "webhook-on", string(notify.ModeAlways), "Runs to notify about:\n"+
+		"always - every run, change - new books or the result differs from the previous run,\n"+
+		"failure - failed runs and the first successful run after them.")
+
+	flags.StringVar(&cfg.smtpAddr, "smtp-addr", "", "Address of the SMTP server sending emails about runs, e.g. \"smtp.example.com:587\".")
+
+	flags.StringVar(&cfg.smtpSecurity, "smtp-security", string(email.SecuritySTARTTLS), "Security of the SMTP connection:\n"+
+		"starttls - upgrade to TLS, usually port 587, tls - TLS from the start, usually port 465, none - plain text.")
+
+	flags.StringVar(&cfg.smtpUsername, "smtp-username", "", "Username of the SMTP server, enables the authentication.")
+
+	flags.StringVar(&cfg.smtpPassword, "smtp-password", "", "Password of the SMTP server.")
+
+	flags.StringVar(&cfg.emailFrom, "email-from", "", "Sender address of emails.")
+
+	flags.StringVar(&cfg.emailTo, "email-to", "", "Comma-separated list of recipient addresses of emails.")
+
+	flags.StringVar(&cfg.emailTmpl, "email-template", "", "File with Go templates \"subject\", \"text\" and optionally \"html\" of emails.\n"+
+		"The data has the fields .Since, .Until, .Runs, .Total, .NewBooks and .Failures with .Time and .Error.")
+
+	flags.DurationVar(&cfg.emailDigest, "email-digest", 0, "Period of the email digest, e.g. \"24h\". The runs are collected in the state directory\n"+
+		"and sent by the first run after the end of the period. By default an email is sent per run.")
+
+	flags.StringVar(&cfg.emailOn, "email-on", string(notify.ModeAlways), "Runs to send emails about, the values are the same as of the webhook-on flag.")
+
+	flags.StringVar(&cfg.mqttBroker, "mqtt-broker", "", "URL of the MQTT broker receiving the state of each run and an event per downloaded book,\n"+
+		"e.g. \"tcp://broker.lan:1883\" or \"ssl://broker.lan:8883\" for TLS.")
+
+	flags.StringVar(&cfg.mqttUsername, "mqtt-username", "", "Username of the MQTT broker.")
+
+	flags.StringVar(&cfg.mqttPassword, "mqtt-password", "", "Password of the MQTT broker, requires the username.")
+
+	flags.StringVar(&cfg.mqttTopic, "mqtt-topic", mqtt.DefaultTopic, "Prefix of the MQTT topics \""+mqtt.TopicState+"\" and \""+mqtt.TopicBook+"\".")
+
+	flags.IntVar(&cfg.mqttQoS, "mqtt-qos", 0, "QoS of the MQTT messages: 0, 1 or 2.")
+
+	flags.BoolVar(&cfg.mqttRetain, "mqtt-retain", true, "Retain the state of the last run on the MQTT broker.")
+
+	flags.StringVar(&cfg.mqttDiscovery, "mqtt-discovery", "", "Discovery prefix of Home Assistant, e.g. \""+mqtt.DefaultDiscoveryPrefix+"\".\n"+
+		"Enables discovery messages of the sensors and the book event. Disabled by default.")
+
+	flags.IntVar(&cfg.readyIntervals, "ready-intervals", health.DefaultReadyIntervals,
+		"How many schedule intervals the daemon stays ready after the last successful sync.")
+
+	flags.StringVar(&cfg.errorPolicy, "error-policy", "", "Actions of daemon mode by error classes as class=action pairs, e.g. \"auth=wait,other=retry\".\n"+
+		"Classes: network, server, ratelimit, auth, filesystem, other.\n"+
+		"Actions: retry - retry soon with exponential backoff, wait - wait for the next run, exit - stop.\n"+
+		"By default network errors and rate limits are retried, server errors wait and others exit.")
+
+	flags.IntVar(&cfg.maxFailures, "max-failures", 0, "Maximum of consecutive failures in daemon mode, 0 is unlimited.")
+
+	flags.DurationVar(&cfg.retryBackoff, "retry-backoff", daemon.DefaultBackoff, "First delay before retry in daemon mode, it doubles after each failure.")
+
+	flags.DurationVar(&cfg.retryMax, "retry-max-backoff", daemon.DefaultMaxBackoff, "Maximum delay before retry in daemon mode.")
+
+	flags.StringVar(&cfg.timeZone, "time-zone", "", "Time zone of the schedule, e.g. \"Europe/Berlin\". By default the local time zone.")
+
+	flags.StringVar(&cfg.providers, "providers", "", "Comma-separated list of shop IDs or aliases of providers to sync.\n"+
+		"Prefix an item with \"-\" to exclude the provider, e.g. \"-providers=-1234\".\n"+
+		"If any provider is included, all others are skipped. By default all providers are synced.")
+
+	flags.IntVar(&cfg.providersConc, "providers-concurrency", books.DefaultConcurrency,
+		"How many providers are listed at the same time.")
+
+	return flags
+}
+
+// queryFlags are the flags of the sync command taken by the commands which only read the cloud and the directory.
+var queryFlags = [...]string{
+	"config", "client-id", "client-secret", "api-url", "username", "password", "token",
+	"dir", "state-dir", "providers", "providers-concurrency",
+}
+
+// newQueryFlagSet defines the connection, directory and filter flags bound to the config.
+// The other settings get the default values and are set only by the environment variables and the config file.
+func newQueryFlagSet(name string, cfg *config) *flag.FlagSet {
+	all := newFlagSet(cfg)
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flags.BoolVar(&cfg.env, "env", false, "Enable environment variables mode.\n"+
+		"Ignores all command-line flags and loads values from the environment variables of the sync command.")
+
+	flags.BoolVar(&cfg.debug, "debug", false, "Enable debug output to the standard error.")
+
+	for _, n := range queryFlags {
+		f := all.Lookup(n)
+		flags.Var(f.Value, f.Name, f.Usage)
 	}
+
+	return flags
 }
 
 func (s Sync) Description() string {
@@ -93,51 +353,279 @@ func (s Sync) Run(args []string) error {
 		return fmt.Errorf("flag parse: %v", err)
 	}
 
-	if s.cfg.env {
-		if s.cfg, err = loadConfigFromEnv(); err != nil {
-			return fmt.Errorf("load config from env: %v", err)
-		}
+	if s.cfg, err = loadConfig(s.cfg); err != nil {
+		return err
 	}
 
+	redact.Register(s.cfg.secrets()...)
+
 	if err := validation(*s.cfg); err != nil {
 		return fmt.Errorf("validate: %w", err)
 	}
 
-	if s.cfg.debug {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-		slog.Debug("debug enabled")
+	logCfg, err := s.cfg.logConfig()
+	if err != nil {
+		return fmt.Errorf("log config: %w", err)
 	}
 
-	ctx, cancel := createContext()
+	if err = logging.Setup(logCfg); err != nil {
+		return fmt.Errorf("setup logging: %w", err)
+	}
+
+	slog.Debug("debug enabled")
+
+	sd, err := systemd.FromEnv()
+	if err != nil {
+		return fmt.Errorf("systemd: %w", err)
+	}
+
+	ctx, cancel := shutdown.Notify(context.Background(), s.cfg.shutdownGrace, shutdownSignals(s.cfg.daemon)...)
 	defer cancel()
 
+	if sd != nil {
+		ctx = watchSystemd(ctx, sd)
+		defer sd.Stopping()
+	}
+
+	notifiers, err := s.cfg.notifiers()
+	if err != nil {
+		return fmt.Errorf("notifiers: %w", err)
+	}
+
+	if s.cfg.traceHTTP != "" {
+		s.cfg.transport = har.New(s.cfg.traceHTTP).Transport(nil)
+
+		slog.Info("http traffic is traced", "path", s.cfg.traceHTTP)
+	}
+
 	app := s.factory(s.cfg)
+	s.summary = newSummary(s.cfg, s.stdout)
 
 	slog.Info("Welcome! I will be glad to receive your star: https://github.com/micronull/pocketbook-cloud-client")
 
 	if s.cfg.daemon {
-		slog.Debug("starting daemon mode", "timeout", s.cfg.daemonTimeout)
-		app = factory.Synchronizer(daemon.New(s.cfg.daemonTimeout, app))
+		sch, err := s.cfg.daemonSchedule()
+		if err != nil {
+			return fmt.Errorf("daemon schedule: %w", err)
+		}
+
+		policy, err := s.cfg.daemonPolicy()
+		if err != nil {
+			return fmt.Errorf("daemon error policy: %w", err)
+		}
+
+		trg := trigger.New()
+
+		go trg.WatchSignal(ctx)
+		go trg.WatchFile(ctx, s.cfg.TriggerFile(), trigger.DefaultInterval, clock.Real{})
+
+		// Registered before the start, so an early SIGHUP doesn't terminate the process.
+		reload := make(chan os.Signal, 1)
+
+		signal.Notify(reload, syscall.SIGHUP)
+		defer signal.Stop(reload)
+
+		opts := []daemon.Option{
+			daemon.WithSchedule(sch),
+			daemon.WithPolicy(policy),
+			daemon.WithStateFile(filepath.Join(s.cfg.StateDirectory(), daemonStateFile)),
+			daemon.WithSyncOnStart(s.cfg.syncOnStart),
+			daemon.WithTrigger(trg.C()),
+			daemon.WithStatusHook(func(st daemon.Status) {
+				if !st.Running {
+					sd.Status(idleStatus(st.NextRun, time.Now()))
+				}
+			}),
+		}
+
+		for _, n := range notifiers {
+			opts = append(opts, daemon.WithObserver(n.Observe))
+		}
+
+		if s.summary != nil {
+			opts = append(opts, daemon.WithObserver(s.summary.observe))
+		}
+
+		slog.Debug("starting daemon mode", "timeout", s.cfg.daemonTimeout, "schedule", s.cfg.schedule)
+		dn := daemon.New(s.cfg.daemonTimeout, app, opts...)
+
+		go s.watchReload(ctx, reload, args, dn)
+
+		if s.cfg.httpAddr != "" {
+			mux := http.NewServeMux()
+			mux.Handle("/", health.Handler(dn, s.cfg.readyIntervals))
+			mux.Handle("GET "+pathMetrics, metrics.Default.Registry().Handler())
+
+			srv, err := health.Listen(s.cfg.httpAddr, mux)
+			if err != nil {
+				return fmt.Errorf("health server: %w", err)
+			}
+
+			defer srv.Shutdown()
+		}
+
+		// The service stays healthy until the failures outlast the readiness.
+		go sd.Watchdog(ctx, func() bool { return dn.Status().Failures == 0 || dn.Ready(s.cfg.readyIntervals) })
+
+		sd.Ready()
+
+		err = dn.Sync(ctx)
+	} else {
+		go sd.Watchdog(ctx, func() bool { return true })
+
+		sd.Ready()
+
+		run := report.Collect(ctx, clock.Real{}, app.Sync)
+		err = run.Err
+
+		if s.cfg.metricsFile != "" {
+			if werr := metrics.Default.Registry().WriteFile(s.cfg.metricsFile); werr != nil {
+				slog.Error("failed to write metrics", "error", werr)
+			}
+		}
+
+		s.summary.observe(ctx, run)
+
+		for _, n := range notifiers {
+			n.Observe(ctx, run)
+		}
+	}
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(context.Cause(ctx), shutdown.ErrAborted):
+		return fmt.Errorf("run: %w: %w", shutdown.ErrAborted, err)
+	case errors.Is(err, shutdown.ErrDrained):
+		slog.Info("stopped gracefully")
+
+		return nil
+	}
+
+	return fmt.Errorf("run: %w", err)
+}
+
+// watchSystemd shows the progress of runs in the status of the service and tells systemd about the shutdown.
+func watchSystemd(ctx context.Context, sd *systemd.Notifier) context.Context {
+	go func() {
+		select {
+		case <-shutdown.Draining(ctx):
+			sd.Stopping()
+		case <-ctx.Done():
+		}
+	}()
+
+	return report.WithWatcher(ctx, func(c report.Counts) { sd.Status(progressStatus(c)) })
+}
+
+func progressStatus(c report.Counts) string {
+	if c.Total == 0 {
+		return "listing"
 	}
 
-	if err := app.Sync(ctx); err != nil {
-		return fmt.Errorf("run: %w", err)
+	return fmt.Sprintf("downloading %d/%d", c.Skipped+c.Downloaded, c.Total)
+}
+
+func idleStatus(next, now time.Time) string {
+	if next.Sub(now) < 24*time.Hour {
+		return "idle until " + next.Format("15:04")
 	}
 
+	return "idle until " + next.Format("2006-01-02 15:04")
+}
+
+// watchReload reloads the configuration on each signal until the context is done.
+// An invalid configuration is logged and the daemon keeps the previous one.
+func (s Sync) watchReload(ctx context.Context, reload <-chan os.Signal, args []string, dn *daemon.Daemon) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+		}
+
+		if err := s.reload(args, dn); err != nil {
+			slog.Error("config reload failed, the previous config is kept", "error", err)
+
+			continue
+		}
+
+		slog.Info("config reloaded")
+	}
+}
+
+func (s Sync) reload(args []string, dn *daemon.Daemon) error {
+	cfg := &config{}
+
+	flags := newFlagSet(cfg)
+	flags.SetOutput(io.Discard)
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("flag parse: %w", err)
+	}
+
+	cfg, err := loadConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	redact.Register(cfg.secrets()...)
+
+	cfg.transport = s.cfg.transport
+
+	if err = validation(*cfg); err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	sch, err := cfg.daemonSchedule()
+	if err != nil {
+		return fmt.Errorf("daemon schedule: %w", err)
+	}
+
+	policy, err := cfg.daemonPolicy()
+	if err != nil {
+		return fmt.Errorf("daemon error policy: %w", err)
+	}
+
+	logCfg, err := cfg.logConfig()
+	if err != nil {
+		return fmt.Errorf("log config: %w", err)
+	}
+
+	// The format and the file are kept, they are opened once per process.
+	logging.SetLevel(logCfg.Level)
+
+	s.summary.reconfigure(cfg)
+	dn.Reconfigure(s.factory(cfg), sch, policy)
+
 	return nil
 }
 
-func createContext() (context.Context, context.CancelFunc) {
-	return signal.NotifyContext(
-		context.Background(),
-		syscall.SIGTERM,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGQUIT,
-	)
+func shutdownSignals(daemon bool) []os.Signal {
+	signals := []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT}
+
+	// In daemon mode SIGHUP reloads the configuration.
+	if !daemon {
+		signals = append(signals, syscall.SIGHUP)
+	}
+
+	return signals
 }
 
 func validation(cfg config) error {
+	if err := settingsCheck(cfg); err != nil {
+		return err
+	}
+
+	if err := dirCheck(cfg.dir); err != nil {
+		return fmt.Errorf("check directory: %w", err)
+	}
+
+	return nil
+}
+
+// settingsCheck checks the configuration without the directory.
+func settingsCheck(cfg config) error {
 	switch {
 	case cfg.clientID == "":
 		return requiredError{param: "client-id"}
@@ -145,14 +633,86 @@ func validation(cfg config) error {
 		return requiredError{param: "client-secret"}
 	case cfg.userName == "":
 		return requiredError{param: "username"}
-	case cfg.password == "":
+	case cfg.password == "" && cfg.token == "":
 		return requiredError{param: "password"}
 	case cfg.dir == "":
 		return requiredError{param: "dir"}
+	case cfg.providersConc < 1:
+		return fmt.Errorf("%w: providers-concurrency must be positive", errInvalidValue)
+	case cfg.readyIntervals < 1:
+		return fmt.Errorf("%w: ready-intervals must be positive", errInvalidValue)
+	case cfg.lockWait < 0:
+		return fmt.Errorf("%w: lock-wait must not be negative", errInvalidValue)
+	case cfg.shutdownGrace < 0:
+		return fmt.Errorf("%w: shutdown-grace must not be negative", errInvalidValue)
+	case cfg.output != outputText && cfg.output != outputJSON:
+		return fmt.Errorf("%w: output must be text or json", errInvalidValue)
+	case cfg.historyAge < 0:
+		return fmt.Errorf("%w: history-retention must not be negative", errInvalidValue)
 	}
 
-	if err := dirCheck(cfg.dir); err != nil {
-		return fmt.Errorf("check directory: %w", err)
+	if _, err := apiurl.Parse(cfg.apiURL); err != nil {
+		return fmt.Errorf("check api url: %w", err)
+	}
+
+	if err := scheduleCheck(cfg); err != nil {
+		return fmt.Errorf("check schedule: %w", err)
+	}
+
+	if err := policyCheck(cfg); err != nil {
+		return fmt.Errorf("check error policy: %w", err)
+	}
+
+	if _, err := cfg.notifiers(); err != nil {
+		return fmt.Errorf("check notifications: %w", err)
+	}
+
+	if _, err := cfg.logConfig(); err != nil {
+		return fmt.Errorf("check logging: %w", err)
+	}
+
+	if err := providersCheck(cfg.Providers()); err != nil {
+		return fmt.Errorf("check providers: %w", err)
+	}
+
+	return nil
+}
+
+func scheduleCheck(cfg config) error {
+	s, err := cfg.daemonSchedule()
+	if err != nil {
+		return err
+	}
+
+	if s.Next(time.Now()).IsZero() {
+		return fmt.Errorf("%w: schedule never runs", errInvalidValue)
+	}
+
+	return nil
+}
+
+func policyCheck(cfg config) error {
+	if _, err := cfg.daemonPolicy(); err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.maxFailures < 0:
+		return fmt.Errorf("%w: max-failures must not be negative", errInvalidValue)
+	case cfg.retryBackoff <= 0:
+		return fmt.Errorf("%w: retry-backoff must be positive", errInvalidValue)
+	case cfg.retryMax < cfg.retryBackoff:
+		return fmt.Errorf("%w: retry-max-backoff must not be less than retry-backoff", errInvalidValue)
+	}
+
+	return nil
+}
+
+func providersCheck(providers []string) error {
+	for _, p := range providers {
+		if strings.TrimPrefix(p, "-") == "" {
+			return fmt.Errorf("%w: %q", errInvalidProvider, p)
+		}
 	}
 
 	return nil
@@ -182,7 +742,20 @@ func dirCheck(dir string) error {
 
 func loadConfigFromEnv() (*config, error) {
 	cfg := &config{
-		daemonTimeout: daemonTimeoutDefault,
+		apiURL:         apiurl.Default,
+		daemonTimeout:  daemonTimeoutDefault,
+		retryBackoff:   daemon.DefaultBackoff,
+		retryMax:       daemon.DefaultMaxBackoff,
+		providersConc:  books.DefaultConcurrency,
+		shutdownGrace:  shutdownGraceDefault,
+		readyIntervals: health.DefaultReadyIntervals,
+		mqttTopic:      mqtt.DefaultTopic,
+		logFormat:      string(logging.FormatText),
+		logLevel:       slog.LevelInfo.String(),
+		logMaxSize:     logMaxSizeDefault,
+		logMaxBackups:  logMaxBackupsDefault,
+		output:         outputText,
+		historyAge:     historyAgeDefault,
 	}
 
 	var err error
@@ -193,6 +766,7 @@ func loadConfigFromEnv() (*config, error) {
 	cfg.password = os.Getenv("PBC_PASSWORD")
 	cfg.debug = os.Getenv("DEBUG") == "true"
 	cfg.daemon = os.Getenv("DAEMON") == "true"
+	cfg.syncOnStart = os.Getenv("SYNC_ON_START") == "true"
 
 	if dt := os.Getenv("DAEMON_TIMEOUT"); dt != "" {
 		if cfg.daemonTimeout, err = time.ParseDuration(dt); err != nil {
@@ -200,7 +774,137 @@ func loadConfigFromEnv() (*config, error) {
 		}
 	}
 
+	if pc := os.Getenv("PBC_PROVIDERS_CONCURRENCY"); pc != "" {
+		if cfg.providersConc, err = strconv.Atoi(pc); err != nil {
+			return nil, fmt.Errorf("set providers concurrency: %w", err)
+		}
+	}
+
+	if sg := os.Getenv("SHUTDOWN_GRACE"); sg != "" {
+		if cfg.shutdownGrace, err = time.ParseDuration(sg); err != nil {
+			return nil, fmt.Errorf("set shutdown grace: %w", err)
+		}
+	}
+
+	if hr := os.Getenv("HISTORY_RETENTION"); hr != "" {
+		if cfg.historyAge, err = time.ParseDuration(hr); err != nil {
+			return nil, fmt.Errorf("set history retention: %w", err)
+		}
+	}
+
+	if mq := os.Getenv("MQTT_QOS"); mq != "" {
+		if cfg.mqttQoS, err = strconv.Atoi(mq); err != nil {
+			return nil, fmt.Errorf("set mqtt qos: %w", err)
+		}
+	}
+
+	if ed := os.Getenv("EMAIL_DIGEST"); ed != "" {
+		if cfg.emailDigest, err = time.ParseDuration(ed); err != nil {
+			return nil, fmt.Errorf("set email digest: %w", err)
+		}
+	}
+
+	if ms := os.Getenv("LOG_MAX_SIZE"); ms != "" {
+		if cfg.logMaxSize, err = strconv.Atoi(ms); err != nil {
+			return nil, fmt.Errorf("set log max size: %w", err)
+		}
+	}
+
+	if ma := os.Getenv("LOG_MAX_AGE"); ma != "" {
+		if cfg.logMaxAge, err = time.ParseDuration(ma); err != nil {
+			return nil, fmt.Errorf("set log max age: %w", err)
+		}
+	}
+
+	if mb := os.Getenv("LOG_MAX_BACKUPS"); mb != "" {
+		if cfg.logMaxBackups, err = strconv.Atoi(mb); err != nil {
+			return nil, fmt.Errorf("set log max backups: %w", err)
+		}
+	}
+
+	if lw := os.Getenv("LOCK_WAIT"); lw != "" {
+		if cfg.lockWait, err = time.ParseDuration(lw); err != nil {
+			return nil, fmt.Errorf("set lock wait: %w", err)
+		}
+	}
+
+	if ri := os.Getenv("READY_INTERVALS"); ri != "" {
+		if cfg.readyIntervals, err = strconv.Atoi(ri); err != nil {
+			return nil, fmt.Errorf("set ready intervals: %w", err)
+		}
+	}
+
+	if mf := os.Getenv("MAX_FAILURES"); mf != "" {
+		if cfg.maxFailures, err = strconv.Atoi(mf); err != nil {
+			return nil, fmt.Errorf("set max failures: %w", err)
+		}
+	}
+
+	if rb := os.Getenv("RETRY_BACKOFF"); rb != "" {
+		if cfg.retryBackoff, err = time.ParseDuration(rb); err != nil {
+			return nil, fmt.Errorf("set retry backoff: %w", err)
+		}
+	}
+
+	if rm := os.Getenv("RETRY_MAX_BACKOFF"); rm != "" {
+		if cfg.retryMax, err = time.ParseDuration(rm); err != nil {
+			return nil, fmt.Errorf("set retry max backoff: %w", err)
+		}
+	}
+
 	cfg.dir = os.Getenv("DIR")
+	cfg.errorPolicy = os.Getenv("ERROR_POLICY")
+	cfg.schedule = os.Getenv("SCHEDULE")
+	cfg.timeZone = os.Getenv("TIME_ZONE")
+	cfg.providers = os.Getenv("PBC_PROVIDERS")
+
+	if u := os.Getenv("PBC_API_URL"); u != "" {
+		cfg.apiURL = u
+	}
+
+	cfg.token = os.Getenv("PBC_TOKEN")
+	cfg.stateDir = os.Getenv("STATE_DIR")
+	cfg.triggerFile = os.Getenv("TRIGGER_FILE")
+	cfg.configFile = os.Getenv("CONFIG_FILE")
+	cfg.httpAddr = os.Getenv("HTTP_ADDR")
+	cfg.metricsFile = os.Getenv("METRICS_FILE")
+	cfg.webhooks = os.Getenv("WEBHOOK")
+	cfg.webhookTmpl = os.Getenv("WEBHOOK_TEMPLATE")
+	cfg.webhookOn = os.Getenv("WEBHOOK_ON")
+	cfg.smtpAddr = os.Getenv("SMTP_ADDR")
+	cfg.smtpSecurity = os.Getenv("SMTP_SECURITY")
+	cfg.smtpUsername = os.Getenv("SMTP_USERNAME")
+	cfg.smtpPassword = os.Getenv("SMTP_PASSWORD")
+	cfg.emailFrom = os.Getenv("EMAIL_FROM")
+	cfg.emailTo = os.Getenv("EMAIL_TO")
+	cfg.emailTmpl = os.Getenv("EMAIL_TEMPLATE")
+	cfg.emailOn = os.Getenv("EMAIL_ON")
+	cfg.mqttBroker = os.Getenv("MQTT_BROKER")
+	cfg.mqttUsername = os.Getenv("MQTT_USERNAME")
+	cfg.mqttPassword = os.Getenv("MQTT_PASSWORD")
+
+	if t := os.Getenv("MQTT_TOPIC"); t != "" {
+		cfg.mqttTopic = t
+	}
+
+	cfg.mqttRetain = os.Getenv("MQTT_RETAIN") != "false"
+	cfg.mqttDiscovery = os.Getenv("MQTT_DISCOVERY")
+
+	if f := os.Getenv("LOG_FORMAT"); f != "" {
+		cfg.logFormat = f
+	}
+
+	if l := os.Getenv("LOG_LEVEL"); l != "" {
+		cfg.logLevel = l
+	}
+
+	cfg.logFile = os.Getenv("LOG_FILE")
+	cfg.traceHTTP = os.Getenv("TRACE_HTTP")
+	cfg.reportFile = os.Getenv("REPORT_FILE")
+
+	if o := os.Getenv("OUTPUT"); o != "" {
+		cfg.output = o
+	}
 
 	return cfg, err
 }
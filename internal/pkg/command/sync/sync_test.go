@@ -1,10 +1,18 @@
 package sync_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -12,6 +20,10 @@ import (
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt/mqtttest"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
 )
 
 func TestSync_Description(t *testing.T) {
@@ -28,21 +40,40 @@ func TestSync_Help(t *testing.T) {
 	cmd := sync.New(nil)
 
 	const expected = `Usage of sync:
+  -api-url string
+    	Base URL of PocketBook Cloud API.
+    	Allows to use a regional endpoint, a caching proxy or a local stand-in server. (default "https://cloud.pocketbook.digital/api/v1.0/")
   -client-id string
     	Client ID of PocketBook Cloud API.
     	Read the readme to find out how to get it.
   -client-secret string
     	Client Secret of PocketBook Cloud API.
     	Read the readme to find out how to get it.
+  -config string
+    	File with flags, one name=value pair per line, e.g. "providers=1234".
+    	Its values override the command-line flags and the environment variables.
+    	In daemon mode the configuration is reloaded on the SIGHUP signal.
   -daemon
     	Enable daemon mode. Use the daemon-timeout flag for setting sync interval.
   -daemon-timeout duration
     	Timeout for sync operation. 
     	Used only daemon mode. (default 24h0m0s)
   -debug
-    	Enable debug output.
+    	Enable debug output, the same as -log-level=debug.
   -dir string
     	Directory to sync files. (default "books")
+  -email-digest duration
+    	Period of the email digest, e.g. "24h". The runs are collected in the state directory
+    	and sent by the first run after the end of the period. By default an email is sent per run.
+  -email-from string
+    	Sender address of emails.
+  -email-on string
+    	Runs to send emails about, the values are the same as of the webhook-on flag. (default "always")
+  -email-template string
+    	File with Go templates "subject", "text" and optionally "html" of emails.
+    	The data has the fields .Since, .Until, .Runs, .Total, .NewBooks and .Failures with .Time and .Error.
+  -email-to string
+    	Comma-separated list of recipient addresses of emails.
   -env
     	Enable environment variables mode.
     	Ignores all command-line flags and loads values from environment variables:
@@ -54,10 +85,172 @@ func TestSync_Help(t *testing.T) {
     	DIR as -dir
     	DAEMON as -daemon
     	DAEMON_TIMEOUT as -daemon-timeout
+    	PBC_PROVIDERS as -providers
+    	PBC_PROVIDERS_CONCURRENCY as -providers-concurrency
+    	PBC_TOKEN as -token
+    	STATE_DIR as -state-dir
+    	PBC_API_URL as -api-url
+    	SCHEDULE as -schedule
+    	TIME_ZONE as -time-zone
+    	ERROR_POLICY as -error-policy
+    	MAX_FAILURES as -max-failures
+    	RETRY_BACKOFF as -retry-backoff
+    	RETRY_MAX_BACKOFF as -retry-max-backoff
+    	SYNC_ON_START as -sync-on-start
+    	TRIGGER_FILE as -trigger-file
+    	CONFIG_FILE as -config
+    	SHUTDOWN_GRACE as -shutdown-grace
+    	HTTP_ADDR as -http-addr
+    	READY_INTERVALS as -ready-intervals
+    	METRICS_FILE as -metrics-file
+    	LOCK_WAIT as -lock-wait
+    	WEBHOOK as -webhook
+    	WEBHOOK_TEMPLATE as -webhook-template
+    	WEBHOOK_ON as -webhook-on
+    	SMTP_ADDR as -smtp-addr
+    	SMTP_SECURITY as -smtp-security
+    	SMTP_USERNAME as -smtp-username
+    	SMTP_PASSWORD as -smtp-password
+    	EMAIL_FROM as -email-from
+    	EMAIL_TO as -email-to
+    	EMAIL_TEMPLATE as -email-template
+    	EMAIL_DIGEST as -email-digest
+    	EMAIL_ON as -email-on
+    	MQTT_BROKER as -mqtt-broker
+    	MQTT_USERNAME as -mqtt-username
+    	MQTT_PASSWORD as -mqtt-password
+    	MQTT_TOPIC as -mqtt-topic
+    	MQTT_QOS as -mqtt-qos
+    	MQTT_RETAIN as -mqtt-retain
+    	MQTT_DISCOVERY as -mqtt-discovery
+    	LOG_FORMAT as -log-format
+    	LOG_LEVEL as -log-level
+    	LOG_FILE as -log-file
+    	LOG_MAX_SIZE as -log-max-size
+    	LOG_MAX_AGE as -log-max-age
+    	LOG_MAX_BACKUPS as -log-max-backups
+    	TRACE_HTTP as -trace-http
+    	REPORT_FILE as -report-file
+    	OUTPUT as -output
+    	HISTORY_RETENTION as -history-retention
+  -error-policy string
+    	Actions of daemon mode by error classes as class=action pairs, e.g. "auth=wait,other=retry".
+    	Classes: network, server, ratelimit, auth, filesystem, other.
+    	Actions: retry - retry soon with exponential backoff, wait - wait for the next run, exit - stop.
+    	By default network errors and rate limits are retried, server errors wait and others exit.
+  -history-retention duration
+    	How long the runs are kept in the history journal
+    	in the state directory, see the history command. 0 disables the journal. (default 720h0m0s)
+  -http-addr string
+    	Address of the health server in daemon mode, e.g. ":8080". Disabled by default.
+    	Endpoints: /healthz - the process is alive, /readyz - the last sync has succeeded recently,
+    	/status - JSON status of the last and the next runs, /metrics - Prometheus metrics.
+  -lock-wait duration
+    	How long to wait for the directory locked by another process.
+    	By default the sync fails immediately naming PID of the holder.
+  -log-file string
+    	File to write the log instead of the standard output, it is rotated by the size and the age.
+  -log-format string
+    	Format of the log:
+    	text - time, level, message and key=value attributes, json - JSON object per line,
+    	logfmt - key=value pairs including time, level and message. (default "text")
+  -log-level string
+    	Minimal level of the log: debug, info, warn or error.
+    	In daemon mode the level is changed by the config reload. (default "INFO")
+  -log-max-age duration
+    	Age of the log file after which it is rotated, e.g. "24h". Unlimited by default.
+  -log-max-backups int
+    	How many rotated log files are kept, 0 keeps all. (default 5)
+  -log-max-size int
+    	Size of the log file in megabytes after which it is rotated, 0 is unlimited. (default 10)
+  -max-failures int
+    	Maximum of consecutive failures in daemon mode, 0 is unlimited.
+  -metrics-file string
+    	File to write Prometheus metrics after the sync without daemon mode,
+    	e.g. for the textfile collector of node_exporter.
+  -mqtt-broker string
+    	URL of the MQTT broker receiving the state of each run and an event per downloaded book,
+    	e.g. "tcp://broker.lan:1883" or "ssl://broker.lan:8883" for TLS.
+  -mqtt-discovery string
+    	Discovery prefix of Home Assistant, e.g. "homeassistant".
+    	Enables discovery messages of the sensors and the book event. Disabled by default.
+  -mqtt-password string
+    	Password of the MQTT broker, requires the username.
+  -mqtt-qos int
+    	QoS of the MQTT messages: 0, 1 or 2.
+  -mqtt-retain
+    	Retain the state of the last run on the MQTT broker. (default true)
+  -mqtt-topic string
+    	Prefix of the MQTT topics "state" and "book". (default "pbcsync")
+  -mqtt-username string
+    	Username of the MQTT broker.
+  -output string
+    	Output of the command: text - the log only,
+    	json - the JSON report of each run on a line of the standard output, the log is moved to the standard error. (default "text")
   -password string
     	Password from your PocketBook Cloud account.
+  -providers string
+    	Comma-separated list of shop IDs or aliases of providers to sync.
+    	Prefix an item with "-" to exclude the provider, e.g. "-providers=-1234".
+    	If any provider is included, all others are skipped. By default all providers are synced.
+  -providers-concurrency int
+    	How many providers are listed at the same time. (default 4)
+  -ready-intervals int
+    	How many schedule intervals the daemon stays ready after the last successful sync. (default 2)
+  -report-file string
+    	File to write the JSON report after each run: the run ID, the times, the fingerprint of the configuration,
+    	the outcome of each book - downloaded, skipped, filtered or failed with the reason, the bytes and the durations.
+  -retry-backoff duration
+    	First delay before retry in daemon mode, it doubles after each failure. (default 30s)
+  -retry-max-backoff duration
+    	Maximum delay before retry in daemon mode. (default 30m0s)
+  -schedule string
+    	Cron expression of sync times in daemon mode, e.g. "0 3 * * *" or "@daily".
+    	Overrides the daemon-timeout flag.
+  -shutdown-grace duration
+    	How long to wait for running downloads on shutdown.
+    	No new downloads are started after the first signal. The second signal or the end of the period
+    	aborts the downloads, removes partial files and exits with status 3. (default 8s)
+  -smtp-addr string
+    	Address of the SMTP server sending emails about runs, e.g. "smtp.example.com:587".
+  -smtp-password string
+    	Password of the SMTP server.
+  -smtp-security string
+    	Security of the SMTP connection:
+    	starttls - upgrade to TLS, usually port 587, tls - TLS from the start, usually port 465, none - plain text. (default "starttls")
+  -smtp-username string
+    	Username of the SMTP server, enables the authentication.
+  -state-dir string
+    	Directory for internal files like cached access tokens.
+    	By default ".pbcsync" inside the sync directory.
+  -sync-on-start
+    	Sync right after the start in daemon mode.
+    	By default the daemon continues the schedule of the previous run saved in the state directory.
+  -time-zone string
+    	Time zone of the schedule, e.g. "Europe/Berlin". By default the local time zone.
+  -token string
+    	Pre-obtained access token of PocketBook Cloud, it is used while there is no valid cached token.
+    	Allows to run without the password, the password is used only if the token is rejected.
+    	The tokens aren't refreshed, the expired token falls back to the login with the password.
+  -trace-http string
+    	File to record HTTP traffic of the API and the downloads in HAR format for bug reports.
+    	The credentials and the tokens are masked, the bodies are truncated and the books are omitted.
+  -trigger-file string
+    	File requesting an immediate sync in daemon mode, it is removed when noticed.
+    	The sync is also requested by the SIGUSR1 signal. By default "trigger" inside the state directory.
   -username string
     	Username of PocketBook Cloud. Usually it's your email.
+  -webhook string
+    	Comma-separated list of URLs receiving the JSON report after each run:
+    	status, error, counts of books and names of the downloaded books.
+  -webhook-on string
+    	Runs to notify about:
+    	always - every run, change - new books or the result differs from the previous run,
+    	failure - failed runs and the first successful run after them. (default "always")
+  -webhook-template string
+    	File with Go template of the webhook body, e.g. for Slack, Discord, Gotify or ntfy.
+    	The fields of the JSON report are available as .Status, .Error, .Start, .End, .Total, .Skipped,
+    	.Downloaded and .NewBooks, the functions json and join help to build the body.
 `
 
 	assert.Equal(t, expected, cmd.Help())
@@ -70,6 +263,7 @@ func defaultArgs() []string {
 		"-dir", "testdata",
 		"-password", "some-password",
 		"-username", "some-username",
+		"-history-retention", "0",
 	}
 }
 
@@ -116,6 +310,11 @@ func TestSync_Run_Env(t *testing.T) {
 		assert.Equal(t, "testdata", config.Directory())
 		assert.Equal(t, "some-password from env", config.Password())
 		assert.Equal(t, "some-username from env", config.UserName())
+		assert.Equal(t, []string{"1", "-some-alias"}, config.Providers())
+		assert.Equal(t, "some-token from env", config.Token())
+		assert.Equal(t, 3, config.ProvidersConcurrency())
+		assert.Equal(t, "http://localhost:8080/api/", config.APIURL())
+		assert.Equal(t, "testdata/.pbcsync", config.StateDirectory())
 
 		return appMock
 	})
@@ -134,6 +333,11 @@ func TestSync_Run_Env(t *testing.T) {
 	t.Setenv("PBC_USERNAME", "some-username from env")
 	t.Setenv("PBC_PASSWORD", "some-password from env")
 	t.Setenv("DIR", "testdata")
+	t.Setenv("PBC_PROVIDERS", "1, -some-alias")
+	t.Setenv("PBC_TOKEN", "some-token from env")
+	t.Setenv("PBC_PROVIDERS_CONCURRENCY", "3")
+	t.Setenv("PBC_API_URL", "http://localhost:8080/api/")
+	t.Setenv("HISTORY_RETENTION", "0")
 
 	appMock.On("Sync", mock.Anything).Return(nil)
 
@@ -143,6 +347,298 @@ func TestSync_Run_Env(t *testing.T) {
 	appMock.AssertExpectations(t)
 }
 
+func TestSync_Run_Token(t *testing.T) {
+	t.Parallel()
+	_ = os.Mkdir("testdata", 0777)
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(config factory.Configurator) factory.Synchronizer {
+		assert.Empty(t, config.Password())
+		assert.Equal(t, "some-token", config.Token())
+		assert.Equal(t, "some-state-dir", config.StateDirectory())
+
+		return appMock
+	})
+
+	args := []string{
+		"-client-id", "some-id",
+		"-client-secret", "some-secret",
+		"-dir", "testdata",
+		"-token", "some-token",
+		"-username", "some-username",
+		"-state-dir", "some-state-dir",
+		"-history-retention", "0",
+	}
+
+	appMock.On("Sync", mock.Anything).Return(nil)
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_ConfigFile(t *testing.T) {
+	t.Parallel()
+	_ = os.Mkdir("testdata", 0777)
+
+	path := filepath.Join(t.TempDir(), "pbcsync.conf")
+
+	const content = `# credentials
+username=user from file
+-password=password from file
+
+providers=1, -some-alias
+`
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(config factory.Configurator) factory.Synchronizer {
+		assert.Equal(t, "some-id", config.ClientID())
+		assert.Equal(t, "user from file", config.UserName())
+		assert.Equal(t, "password from file", config.Password())
+		assert.Equal(t, []string{"1", "-some-alias"}, config.Providers())
+
+		return appMock
+	})
+
+	args := append(defaultArgs(), "-config", path)
+
+	appMock.On("Sync", mock.Anything).Return(nil)
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_Webhook(t *testing.T) {
+	t.Parallel()
+	_ = os.Mkdir("testdata", 0777)
+
+	bodies := make(chan string, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"text": {{ printf "%s, %d new" .Status .Downloaded | json }}}`), 0o600))
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock })
+
+	args := append(defaultArgs(),
+		"-state-dir", t.TempDir(),
+		"-webhook", srv.URL,
+		"-webhook-template", path,
+	)
+
+	appMock.On("Sync", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		report.FromContext(args.Get(0).(context.Context)).Download("first.txt", 0, 0)
+	})
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"text": "success, 1 new"}`, <-bodies)
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_MQTT(t *testing.T) {
+	t.Parallel()
+	_ = os.Mkdir("testdata", 0777)
+
+	broker := mqtttest.NewBroker()
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock })
+
+	args := append(defaultArgs(),
+		"-state-dir", t.TempDir(),
+		"-mqtt-broker", broker.URL,
+		"-mqtt-topic", "home/pbcsync/",
+		"-mqtt-discovery", "homeassistant",
+	)
+
+	appMock.On("Sync", mock.Anything).Return(nil)
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	broker.Close()
+
+	state, ok := broker.Retained("home/pbcsync/state")
+	require.True(t, ok)
+	assert.Equal(t, "home_pbcsync", state.ClientID)
+	assert.Contains(t, state.Payload, `"status":"success"`)
+
+	_, ok = broker.Retained("homeassistant/sensor/home_pbcsync/home_pbcsync_status/config")
+	assert.True(t, ok)
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_LogFile(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	path := filepath.Join(t.TempDir(), "sync.log")
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock })
+
+	args := append(defaultArgs(),
+		"-log-format", "json",
+		"-log-level", "warn",
+		"-log-file", path,
+	)
+
+	appMock.On("Sync", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+
+		slog.InfoContext(ctx, "skipped by level")
+		slog.WarnContext(ctx, "book link is empty", logging.Book("first.txt"))
+	})
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var record map[string]any
+
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "book link is empty", record["msg"])
+	assert.Equal(t, "first.txt", record["book"])
+	assert.NotEmpty(t, record["run_id"])
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_Report(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	path := filepath.Join(t.TempDir(), "report.json")
+
+	var stdout bytes.Buffer
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock }, sync.WithStdout(&stdout))
+
+	args := append(defaultArgs(),
+		"-output", "json",
+		"-report-file", path,
+	)
+
+	appMock.On("Sync", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		rep := report.FromContext(args.Get(0).(context.Context))
+		rep.SetTotal(1)
+		rep.Download("first.txt", 1024, time.Second)
+	})
+
+	err := cmd.Run(args)
+	require.NoError(t, err)
+
+	var printed report.Summary
+
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &printed))
+	assert.Equal(t, report.StatusSuccess, printed.Status)
+	assert.NotEmpty(t, printed.RunID)
+	assert.Len(t, printed.Config, 16)
+	assert.Equal(t, []report.BookSummary{
+		{Name: "first.txt", Outcome: report.OutcomeDownloaded, Bytes: 1024, DurationMS: 1000},
+	}, printed.Books)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var written report.Summary
+
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, printed, written)
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_History(t *testing.T) {
+	t.Parallel()
+	_ = os.Mkdir("testdata", 0777)
+
+	stateDir := t.TempDir()
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock })
+
+	args := append(defaultArgs(),
+		"-state-dir", stateDir,
+		"-history-retention", "24h",
+	)
+
+	appMock.On("Sync", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		rep := report.FromContext(args.Get(0).(context.Context))
+		rep.SetTotal(2)
+		rep.Skip("exist.txt")
+		rep.Download("first.txt", 1024, time.Second)
+	})
+
+	require.NoError(t, cmd.Run(args))
+	require.NoError(t, cmd.Run(args))
+
+	runs, err := history.Read(filepath.Join(stateDir, history.FileName))
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.NotEqual(t, runs[0].RunID, runs[1].RunID)
+	assert.Equal(t, []report.BookSummary{
+		{Name: "first.txt", Outcome: report.OutcomeDownloaded, Bytes: 1024, DurationMS: 1000},
+	}, runs[1].Books)
+
+	appMock.AssertExpectations(t)
+}
+
+func TestSync_Run_Error_ConfigFile(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "unknown flag",
+			content: "unknown=value",
+		},
+		{
+			name:    "invalid value",
+			content: "providers-concurrency=many",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "pbcsync.conf")
+
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			cmd := sync.New(nil)
+
+			err := cmd.Run(append(defaultArgs(), "-config", path))
+			require.ErrorContains(t, err, "load config file")
+		})
+	}
+}
+
 func TestSync_Run_Error(t *testing.T) {
 	t.Parallel()
 	_ = os.Mkdir("testdata", 0777)
@@ -207,6 +703,219 @@ func TestSync_Run_Error_Validation(t *testing.T) {
 			},
 			expect: "validate: dir is required",
 		},
+		{
+			name: "invalid provider",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-providers", "1,-",
+			},
+			expect: `validate: check providers: invalid provider: "-"`,
+		},
+		{
+			name: "invalid api url",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-api-url", "localhost:8080",
+			},
+			expect: "validate: check api url: invalid api url: scheme must be http or https",
+		},
+		{
+			name: "invalid schedule",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-schedule", "0 3 * *",
+			},
+			expect: "validate: check schedule: parse schedule: invalid cron expression: expected 5 fields, got 4",
+		},
+		{
+			name: "schedule never runs",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-schedule", "0 0 31 2 *",
+			},
+			expect: "validate: check schedule: invalid value: schedule never runs",
+		},
+		{
+			name: "invalid daemon timeout",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-daemon-timeout", "0s",
+			},
+			expect: "validate: check schedule: daemon-timeout: invalid interval 0s: must be positive",
+		},
+		{
+			name: "invalid time zone",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-schedule", "@daily",
+				"-time-zone", "Mars/Olympus",
+			},
+			expect: "validate: check schedule: load time zone: unknown time zone Mars/Olympus",
+		},
+		{
+			name: "invalid error policy",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-error-policy", "auth=ignore",
+			},
+			expect: `validate: check error policy: invalid error policy: unknown action "ignore"`,
+		},
+		{
+			name: "invalid retry max backoff",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-retry-backoff", "1h",
+				"-retry-max-backoff", "1m",
+			},
+			expect: "validate: check error policy: invalid value: retry-max-backoff must not be less than retry-backoff",
+		},
+		{
+			name: "invalid providers concurrency",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-providers-concurrency", "0",
+			},
+			expect: "validate: invalid value: providers-concurrency must be positive",
+		},
+		{
+			name: "invalid webhook mode",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-webhook-on", "never",
+			},
+			expect: `validate: check notifications: parse webhook-on: unknown mode "never"`,
+		},
+		{
+			name: "invalid webhook url",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-webhook", "hooks.example.com",
+			},
+			expect: `validate: check notifications: invalid value: webhook "hooks.example.com"`,
+		},
+		{
+			name: "email without smtp server",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-email-to", "me@example.com",
+			},
+			expect: "validate: check notifications: smtp-addr is required",
+		},
+		{
+			name: "invalid smtp security",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-smtp-security", "ssl",
+			},
+			expect: `validate: check notifications: parse smtp-security: unknown security "ssl"`,
+		},
+		{
+			name: "invalid mqtt qos",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-mqtt-broker", "tcp://broker.lan",
+				"-mqtt-qos", "3",
+			},
+			expect: "validate: check notifications: invalid value: mqtt-qos must be 0, 1 or 2",
+		},
+		{
+			name: "mqtt password without username",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-mqtt-broker", "tcp://broker.lan",
+				"-mqtt-password", "some-mqtt-password",
+			},
+			expect: "validate: check notifications: invalid value: mqtt-password requires mqtt-username",
+		},
+		{
+			name: "invalid log format",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-log-format", "xml",
+			},
+			expect: `validate: check logging: unknown format "xml"`,
+		},
+		{
+			name: "negative log max size",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-log-max-size", "-1",
+			},
+			expect: "validate: check logging: invalid value: log-max-size must not be negative",
+		},
+		{
+			name: "invalid output",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-output", "yaml",
+			},
+			expect: "validate: invalid value: output must be text or json",
+		},
+		{
+			name: "negative history retention",
+			args: []string{
+				"-client-id", "some-id",
+				"-client-secret", "some-secret",
+				"-username", "some-username",
+				"-password", "some-password",
+				"-history-retention", "-1h",
+			},
+			expect: "validate: invalid value: history-retention must not be negative",
+		},
 	}
 
 	for _, tt := range tests {
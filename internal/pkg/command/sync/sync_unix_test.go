@@ -0,0 +1,236 @@
+//go:build unix
+
+package sync_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/health"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/systemd"
+)
+
+func TestSync_Run_Systemd(t *testing.T) {
+	_ = os.Mkdir("testdata", 0777)
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	t.Setenv(systemd.EnvNotifySocket, path)
+
+	appMock := &mockSync{}
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer { return appMock })
+
+	appMock.On("Sync", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		rep := report.FromContext(args.Get(0).(context.Context))
+		rep.SetTotal(2)
+		rep.Skip("exist.txt")
+		rep.Download("first.txt", 0, 0)
+	})
+
+	require.NoError(t, cmd.Run(defaultArgs()))
+
+	var states []string
+
+	buf := make([]byte, 1024)
+
+	for range 6 {
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+
+		states = append(states, string(buf[:n]))
+	}
+
+	assert.Equal(t, []string{
+		"READY=1",
+		"STATUS=listing",
+		"STATUS=downloading 0/2",
+		"STATUS=downloading 1/2",
+		"STATUS=downloading 2/2",
+		"STOPPING=1",
+	}, states)
+}
+
+// lockedBuffer is the output written by the daemon and read by the test.
+type lockedBuffer struct {
+	mu  gosync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// reports returns the JSON reports of the finished runs.
+func (b *lockedBuffer) reports(t *testing.T) []report.Summary {
+	t.Helper()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var sums []report.Summary
+
+	dec := json.NewDecoder(bytes.NewReader(b.buf.Bytes()))
+
+	for dec.More() {
+		var s report.Summary
+
+		require.NoError(t, dec.Decode(&s))
+
+		sums = append(sums, s)
+	}
+
+	return sums
+}
+
+type reloadDaemon struct {
+	config string
+	addr   string
+	out    *lockedBuffer
+	log    string
+	// factories counts the builds of the synchronizer, the accepted reload builds it again.
+	factories atomic.Int32
+	done      chan error
+}
+
+// startReloadDaemon runs the daemon with the config file and waits for its first run.
+func startReloadDaemon(t *testing.T, config string) *reloadDaemon {
+	t.Helper()
+
+	_ = os.Mkdir("testdata", 0777)
+
+	orig := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	d := &reloadDaemon{
+		config: filepath.Join(dir, "pbcsync.conf"),
+		addr:   l.Addr().String(),
+		out:    &lockedBuffer{},
+		log:    filepath.Join(dir, "sync.log"),
+		done:   make(chan error, 1),
+	}
+
+	require.NoError(t, l.Close())
+	require.NoError(t, os.WriteFile(d.config, []byte(config), 0o600))
+
+	appMock := &mockSync{}
+	appMock.On("Sync", mock.Anything).Return(nil)
+
+	cmd := sync.New(func(factory.Configurator) factory.Synchronizer {
+		d.factories.Add(1)
+
+		return appMock
+	}, sync.WithStdout(d.out))
+
+	args := append(defaultArgs(),
+		"-daemon",
+		"-config", d.config,
+		"-state-dir", filepath.Join(dir, "state"),
+		"-http-addr", d.addr,
+		"-output", "json",
+		"-log-file", d.log,
+	)
+
+	go func() { d.done <- cmd.Run(args) }()
+
+	t.Cleanup(func() {
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+		require.NoError(t, <-d.done)
+	})
+
+	require.Eventually(t, func() bool { return len(d.out.reports(t)) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	return d
+}
+
+func (d *reloadDaemon) nextRun(t *testing.T) time.Time {
+	t.Helper()
+
+	rsp, err := http.Get("http://" + d.addr + health.PathStatus)
+	require.NoError(t, err)
+
+	defer func() { _ = rsp.Body.Close() }()
+
+	var st struct {
+		NextRun time.Time `json:"next_run"`
+	}
+
+	require.NoError(t, json.NewDecoder(rsp.Body).Decode(&st))
+
+	return st.NextRun
+}
+
+func (d *reloadDaemon) reload(t *testing.T, config string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(d.config, []byte(config), 0o600))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+}
+
+func TestSync_Run_Reload(t *testing.T) {
+	d := startReloadDaemon(t, "schedule=0 3 * * *\ntime-zone=UTC\nlog-level=info\n")
+
+	assert.Equal(t, 3, d.nextRun(t).UTC().Hour())
+	assert.False(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+
+	d.reload(t, "schedule=0 5 * * *\ntime-zone=UTC\nlog-level=debug\n")
+
+	require.Eventually(t, func() bool { return d.nextRun(t).UTC().Hour() == 5 }, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+	assert.Equal(t, int32(2), d.factories.Load())
+}
+
+func TestSync_Run_Reload_Invalid(t *testing.T) {
+	d := startReloadDaemon(t, "schedule=0 3 * * *\ntime-zone=UTC\nlog-level=info\n")
+
+	next := d.nextRun(t)
+	before := d.out.reports(t)[0]
+
+	d.reload(t, "schedule=0 5 * * *\ntime-zone=UTC\nlog-level=debug\nproviders-concurrency=0\n")
+
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(d.log)
+
+		return err == nil && strings.Contains(string(data), "config reload failed")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, next, d.nextRun(t))
+	assert.False(t, slog.Default().Enabled(t.Context(), slog.LevelDebug))
+	assert.Equal(t, int32(1), d.factories.Load())
+
+	// The run after the rejected reload is reported with the running configuration.
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool { return len(d.out.reports(t)) == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, before.Config, d.out.reports(t)[1].Config)
+}
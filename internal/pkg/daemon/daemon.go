@@ -1,55 +1,275 @@
 package daemon
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
+	gosync "sync"
 	"time"
 
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/command/sync/factory"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+)
+
+var (
+	errNoNextRun  = errors.New("schedule has no next run")
+	errNoSchedule = errors.New("no schedule")
 )
 
 type Daemon struct {
-	timeout time.Duration
-	sync    factory.Synchronizer
+	// mu guards the settings replaced by [Daemon.Reconfigure].
+	mu           gosync.Mutex
+	policy       Policy
+	schedule     schedule.Schedule
+	sync         factory.Synchronizer
+	reconfigured chan struct{}
+	status       Status
+
+	clock       clock.Clock
+	stateFile   string
+	syncOnStart bool
+	trigger     <-chan struct{}
+	observers   []func(ctx context.Context, run report.Run)
+	statusHook  func(Status)
 }
 
 var _ factory.Synchronizer = (*Daemon)(nil)
 
 // New construct for [Daemon].
-// timeout - how long to wait between synchronizations.
-func New(timeout time.Duration, sync factory.Synchronizer) *Daemon {
-	return &Daemon{
-		timeout: timeout,
-		sync:    sync,
+// timeout - how long to wait between synchronizations, unless the schedule is set by [WithSchedule].
+// Sync fails if the timeout isn't positive and the schedule isn't set.
+func New(timeout time.Duration, sync factory.Synchronizer, opts ...Option) *Daemon {
+	sch, _ := schedule.Every(timeout)
+
+	d := &Daemon{
+		policy:       DefaultPolicy(),
+		schedule:     sch,
+		clock:        clock.Real{},
+		sync:         sync,
+		reconfigured: make(chan struct{}, 1),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// Reconfigure replaces the synchronizer, the schedule and the error policy from the next run on.
+// The running sync is not interrupted, the wait for the next run is recalculated as after a restart.
+func (d *Daemon) Reconfigure(sync factory.Synchronizer, sch schedule.Schedule, policy Policy) {
+	d.mu.Lock()
+	d.sync, d.schedule, d.policy = sync, sch, policy
+	d.mu.Unlock()
+
+	select {
+	case d.reconfigured <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Daemon) settings() (factory.Synchronizer, schedule.Schedule, Policy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.sync, d.schedule, d.policy
+}
+
+// Status returns the current status of the daemon.
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.status
+}
+
+// Ready reports whether the last successful sync is not older than the given number of schedule intervals.
+func (d *Daemon) Ready(intervals int) bool {
+	d.mu.Lock()
+	last, sch := d.status.LastSuccess, d.schedule
+	d.mu.Unlock()
+
+	if last.IsZero() {
+		return false
+	}
+
+	deadline := last
+
+	for range max(intervals, 1) {
+		if deadline = sch.Next(deadline); deadline.IsZero() {
+			return true
+		}
 	}
+
+	return d.clock.Now().Before(deadline)
 }
 
-func (d Daemon) Sync(ctx context.Context) error {
-	ticker := time.NewTicker(d.timeout)
-	defer ticker.Stop()
+func (d *Daemon) updateStatus(fn func(s *Status)) {
+	d.mu.Lock()
+	fn(&d.status)
+	st := d.status
+	d.mu.Unlock()
+
+	if d.statusHook != nil {
+		d.statusHook(st)
+	}
+}
+
+func (d *Daemon) Sync(ctx context.Context) error {
+	var failures int
+
+	sync, sch, policy := d.settings()
+	if sch == nil {
+		return errNoSchedule
+	}
+
+	backoff := cmp.Or(policy.Backoff, DefaultBackoff)
+	st := d.loadState()
+	now := d.clock.Now()
+	next := now
+
+	if !d.syncOnStart {
+		next = resume(st, now, sch, policy)
+	}
+
+	d.updateStatus(func(s *Status) {
+		s.LastSuccess = st.LastSuccess
+		s.NextRun = next
+	})
 
 	for {
-		if err := d.sync.Sync(ctx); err != nil {
-			var httpErr interface {
-				Code() int
+		if next.After(now) {
+			slog.InfoContext(ctx, "next sync", "at", next)
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("context done: %w", ctx.Err())
+			case <-shutdown.Draining(ctx):
+				return fmt.Errorf("wait for next sync: %w", shutdown.ErrDrained)
+			case <-d.clock.After(next.Sub(now)):
+			case <-d.trigger:
+				slog.InfoContext(ctx, "sync triggered")
+			case <-d.reconfigured:
+				sync, sch, policy = d.settings()
+				backoff = cmp.Or(policy.Backoff, DefaultBackoff)
+				now = d.clock.Now()
+				next = resume(st, now, sch, policy)
+
+				d.updateStatus(func(s *Status) { s.NextRun = next })
+
+				continue
 			}
+		}
+
+		sync, sch, policy = d.settings()
+
+		d.updateStatus(func(s *Status) { s.Running = true })
+
+		run := report.Collect(ctx, d.clock, sync.Sync)
+		err := run.Err
+		now = run.End
 
-			if errors.As(err, &httpErr) && httpErr.Code() >= http.StatusInternalServerError {
-				slog.Error("server error", "error", err)
-			} else {
-				return fmt.Errorf("call sync: %w", err)
+		d.updateStatus(func(s *Status) {
+			s.Running = false
+			s.LastRun = run
+
+			if err == nil {
+				s.LastSuccess = now
 			}
+		})
+
+		// The run aborted by the shutdown is reported too, so the observers get the context without the cancellation.
+		for _, observe := range d.observers {
+			observe(context.WithoutCancel(ctx), run)
 		}
 
-		ticker.Reset(d.timeout)
+		st.LastAttempt = now
+		if err == nil {
+			st.LastSuccess = now
+		}
 
-		select {
-		case <-ctx.Done():
+		d.saveState(st)
+
+		if err != nil && ctx.Err() != nil {
 			return fmt.Errorf("context done: %w", ctx.Err())
-		case <-ticker.C:
 		}
+
+		if errors.Is(err, shutdown.ErrDrained) {
+			return fmt.Errorf("call sync: %w", err)
+		}
+
+		next = sch.Next(now)
+		if next.IsZero() {
+			return errNoNextRun
+		}
+
+		if err == nil {
+			failures = 0
+			backoff = cmp.Or(policy.Backoff, DefaultBackoff)
+
+			d.updateStatus(func(s *Status) { s.Failures, s.NextRun = 0, next })
+
+			continue
+		}
+
+		class := Classify(err)
+		action := policy.action(class)
+
+		if action == ActionExit {
+			return fmt.Errorf("call sync: %w", err)
+		}
+
+		failures++
+
+		d.updateStatus(func(s *Status) { s.Failures = failures })
+
+		if policy.MaxFailures > 0 && failures >= policy.MaxFailures {
+			return fmt.Errorf("%w (%d): %w", errTooManyFailures, failures, err)
+		}
+
+		slog.ErrorContext(ctx, string(class)+" error",
+			"error", err,
+			"action", action,
+			"failures", failures,
+			logging.RunID(run.ID),
+			logging.Duration(run.End.Sub(run.Start)),
+		)
+
+		if retry := now.Add(backoff); action == ActionRetry && retry.Before(next) {
+			next = retry
+			backoff = min(backoff*2, cmp.Or(policy.MaxBackoff, DefaultMaxBackoff))
+		}
+
+		d.updateStatus(func(s *Status) { s.NextRun = next })
 	}
 }
+
+// resume returns the time of the next synchronization by the state of the previous runs.
+// It continues the schedule of the previous process, so restarts neither resync immediately nor delay the sync.
+func resume(st state, now time.Time, sch schedule.Schedule, policy Policy) time.Time {
+	if st.LastAttempt.IsZero() || st.LastAttempt.After(now) {
+		return now
+	}
+
+	next := sch.Next(st.LastAttempt)
+
+	// The last attempt has failed, retry it soon.
+	if st.LastSuccess.Before(st.LastAttempt) {
+		if retry := st.LastAttempt.Add(cmp.Or(policy.Backoff, DefaultBackoff)); retry.Before(next) {
+			next = retry
+		}
+	}
+
+	if next.IsZero() || next.Before(now) {
+		return now
+	}
+
+	return next
+}
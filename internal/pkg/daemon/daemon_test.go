@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log/slog"
 	"math/rand/v2"
+	"net"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"syscall"
 	"testing"
 	"time"
 
@@ -15,7 +18,13 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/thejerf/slogassert"
 
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/trigger"
 )
 
 type syncFunc func(ctx context.Context) error
@@ -86,6 +95,10 @@ func TestDaemon_Sync_HTTPError_4xx(t *testing.T) {
 	t.Parallel()
 
 	code := rand.N(100) + http.StatusBadRequest
+	if code == http.StatusTooManyRequests {
+		// Rate limits are retried.
+		code = http.StatusBadRequest
+	}
 
 	timeout := 100 * time.Millisecond
 	syncMock := syncFunc(func(context.Context) error {
@@ -114,3 +127,363 @@ func TestDaemon_Sync_Error(t *testing.T) {
 	err := dn.Sync(t.Context())
 	require.ErrorIs(t, err, errExpected)
 }
+
+func TestDaemon_Sync_NoSchedule(t *testing.T) {
+	t.Parallel()
+
+	dn := daemon.New(0, syncFunc(func(context.Context) error {
+		t.Error("unexpected sync")
+
+		return nil
+	}))
+
+	require.EqualError(t, dn.Sync(t.Context()), "no schedule")
+}
+
+func TestDaemon_Sync_Schedule(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	cron, err := schedule.ParseCron("0 3 * * *", time.UTC)
+	require.NoError(t, err)
+
+	runs := make(chan time.Time, 10)
+	syncMock := syncFunc(func(context.Context) error {
+		runs <- fake.Now()
+
+		return nil
+	})
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithSchedule(cron), daemon.WithClock(fake))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	assert.Equal(t, now, <-runs)
+
+	for _, expected := range []time.Time{
+		time.Date(2025, time.February, 25, 3, 0, 0, 0, time.UTC),
+		time.Date(2025, time.February, 26, 3, 0, 0, 0, time.UTC),
+	} {
+		fake.BlockUntil(1)
+
+		next, ok := fake.Next()
+		require.True(t, ok)
+		assert.Equal(t, expected, next)
+
+		fake.Advance(next.Sub(fake.Now()))
+
+		assert.Equal(t, expected, <-runs)
+	}
+
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_Sync_Retry(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	var calls int
+
+	runs := make(chan time.Time, 10)
+	syncMock := syncFunc(func(context.Context) error {
+		runs <- fake.Now()
+
+		calls++
+		if calls <= 2 {
+			return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+		}
+
+		return nil
+	})
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithClock(fake), daemon.WithPolicy(daemon.Policy{
+		Actions:    daemon.DefaultPolicy().Actions,
+		Backoff:    time.Minute,
+		MaxBackoff: time.Hour,
+	}))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	for _, expected := range []time.Time{
+		now,
+		now.Add(time.Minute),
+		now.Add(3 * time.Minute),
+		now.Add(3*time.Minute + time.Hour),
+	} {
+		assert.Equal(t, expected, <-runs)
+
+		fake.BlockUntil(1)
+
+		next, ok := fake.Next()
+		require.True(t, ok)
+
+		fake.Advance(next.Sub(fake.Now()))
+	}
+
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_Sync_MaxFailures(t *testing.T) {
+	t.Parallel()
+
+	fake := clock.NewFake(time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC))
+	errExpected := serverErrorMock{code: http.StatusBadGateway}
+
+	syncMock := syncFunc(func(context.Context) error {
+		return errExpected
+	})
+
+	policy := daemon.DefaultPolicy()
+	policy.MaxFailures = 3
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithClock(fake), daemon.WithPolicy(policy))
+
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(t.Context()) }()
+
+	for range 2 {
+		fake.BlockUntil(1)
+		fake.Advance(time.Hour)
+	}
+
+	err := <-done
+	require.ErrorIs(t, err, errExpected)
+	require.ErrorContains(t, err, "too many consecutive failures (3)")
+}
+
+func TestDaemon_Sync_Trigger(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	trg := trigger.New()
+
+	var c int
+
+	runs := make(chan time.Time, 10)
+	syncMock := syncFunc(func(context.Context) error {
+		c++
+
+		// Requests during the sync are coalesced into one more run.
+		if c == 2 {
+			trg.Fire()
+			trg.Fire()
+		}
+
+		runs <- fake.Now()
+
+		return nil
+	})
+
+	dn := daemon.New(24*time.Hour, syncMock, daemon.WithClock(fake), daemon.WithTrigger(trg.C()))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	assert.Equal(t, now, <-runs)
+
+	fake.BlockUntil(1)
+	trg.Fire()
+
+	assert.Equal(t, now, <-runs)
+	assert.Equal(t, now, <-runs)
+
+	// Abandoned waiters of the triggered runs stay in the fake clock.
+	fake.BlockUntil(3)
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+	assert.Empty(t, runs)
+	assert.Equal(t, 3, c)
+}
+
+func TestDaemon_Sync_Observer(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	errSync := errors.New("sync")
+
+	var c int
+
+	syncMock := syncFunc(func(ctx context.Context) error {
+		c++
+
+		if c > 1 {
+			return errSync
+		}
+
+		report.FromContext(ctx).Download("first.txt", 0, 0)
+
+		return nil
+	})
+
+	runs := make(chan report.Run, 10)
+	observer := func(_ context.Context, run report.Run) { runs <- run }
+
+	dn := daemon.New(time.Hour, syncMock,
+		daemon.WithClock(fake),
+		daemon.WithPolicy(daemon.Policy{Actions: map[daemon.Class]daemon.Action{daemon.ClassOther: daemon.ActionWait}}),
+		daemon.WithObserver(observer),
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	run := <-runs
+	require.NoError(t, run.Err)
+	assert.Equal(t, []string{"first.txt"}, run.NewBooks)
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+
+	run = <-runs
+	require.ErrorIs(t, run.Err, errSync)
+	assert.Empty(t, run.NewBooks)
+
+	fake.BlockUntil(1)
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_Sync_Canceled_Reported(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "daemon.json")
+	ctx, cancel := context.WithCancel(t.Context())
+
+	syncMock := syncFunc(func(ctx context.Context) error {
+		cancel()
+
+		return ctx.Err()
+	})
+
+	var observed []error
+
+	dn := daemon.New(time.Hour, syncMock,
+		daemon.WithClock(clock.NewFake(now)),
+		daemon.WithStateFile(path),
+		daemon.WithObserver(func(ctx context.Context, run report.Run) {
+			observed = append(observed, ctx.Err(), run.Err)
+		}),
+	)
+
+	err := dn.Sync(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	require.Len(t, observed, 2)
+	require.NoError(t, observed[0], "the observer context isn't canceled")
+	require.ErrorIs(t, observed[1], context.Canceled)
+
+	var st struct {
+		LastAttempt time.Time `json:"last_attempt"`
+	}
+
+	require.NoError(t, statefile.Load(path, &st))
+	assert.Equal(t, now, st.LastAttempt)
+}
+
+func TestDaemon_Reconfigure(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	runs := make(chan string, 10)
+	syncMock := func(name string) syncFunc {
+		return func(context.Context) error {
+			runs <- name
+
+			return nil
+		}
+	}
+
+	dn := daemon.New(24*time.Hour, syncMock("old"), daemon.WithClock(fake))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	assert.Equal(t, "old", <-runs)
+
+	sch, err := schedule.Every(time.Hour)
+	require.NoError(t, err)
+
+	fake.BlockUntil(1)
+	dn.Reconfigure(syncMock("new"), sch, daemon.DefaultPolicy())
+	fake.BlockUntil(2)
+
+	next, ok := fake.Next()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(time.Hour), next)
+
+	fake.Advance(time.Hour)
+
+	assert.Equal(t, "new", <-runs)
+
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_Sync_Drained(t *testing.T) {
+	t.Parallel()
+
+	fake := clock.NewFake(time.Now())
+	ctx, drain := shutdown.WithDrain(t.Context())
+
+	var c int
+
+	counter := syncFunc(func(context.Context) error { c++; return nil })
+
+	dn := daemon.New(time.Hour, counter, daemon.WithClock(fake))
+
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	fake.BlockUntil(1)
+	drain()
+
+	require.ErrorIs(t, <-done, shutdown.ErrDrained)
+	assert.Equal(t, 1, c)
+}
+
+func TestDaemon_Sync_Drained_Running(t *testing.T) {
+	t.Parallel()
+
+	ctx, drain := shutdown.WithDrain(t.Context())
+
+	syncMock := syncFunc(func(context.Context) error {
+		drain()
+
+		return shutdown.ErrDrained
+	})
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithClock(clock.NewFake(time.Now())))
+
+	err := dn.Sync(ctx)
+	require.ErrorIs(t, err, shutdown.ErrDrained)
+}
@@ -0,0 +1,69 @@
+package daemon
+
+import (
+	"context"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+)
+
+type Option func(*Daemon)
+
+// WithSchedule sets the schedule of synchronizations instead of the fixed timeout.
+func WithSchedule(s schedule.Schedule) Option {
+	return func(d *Daemon) {
+		d.schedule = s
+	}
+}
+
+// WithClock sets the clock, useful for testing.
+func WithClock(c clock.Clock) Option {
+	return func(d *Daemon) {
+		d.clock = c
+	}
+}
+
+// WithPolicy sets the policy of tolerance to synchronization errors.
+func WithPolicy(p Policy) Option {
+	return func(d *Daemon) {
+		d.policy = p
+	}
+}
+
+// WithStateFile sets the file to persist times of the last runs.
+// The daemon continues the schedule after restart instead of syncing immediately.
+func WithStateFile(path string) Option {
+	return func(d *Daemon) {
+		d.stateFile = path
+	}
+}
+
+// WithSyncOnStart forces the sync right after the start regardless of the persisted state.
+func WithSyncOnStart(enabled bool) Option {
+	return func(d *Daemon) {
+		d.syncOnStart = enabled
+	}
+}
+
+// WithTrigger sets the channel of on-demand synchronizations.
+// A request received while the sync is running causes one more run right after it.
+func WithTrigger(c <-chan struct{}) Option {
+	return func(d *Daemon) {
+		d.trigger = c
+	}
+}
+
+// WithObserver adds the function called with the report after each run, e.g. to send notifications.
+func WithObserver(fn func(ctx context.Context, run report.Run)) Option {
+	return func(d *Daemon) {
+		d.observers = append(d.observers, fn)
+	}
+}
+
+// WithStatusHook sets the function called with the status after each change.
+func WithStatusHook(fn func(Status)) Option {
+	return func(d *Daemon) {
+		d.statusHook = fn
+	}
+}
@@ -0,0 +1,186 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Class of synchronization errors.
+type Class string
+
+const (
+	ClassNetwork    Class = "network"
+	ClassServer     Class = "server"
+	ClassRateLimit  Class = "ratelimit"
+	ClassAuth       Class = "auth"
+	ClassFilesystem Class = "filesystem"
+	ClassOther      Class = "other"
+)
+
+var classes = []Class{ClassNetwork, ClassServer, ClassRateLimit, ClassAuth, ClassFilesystem, ClassOther}
+
+// Action of the daemon after an error.
+type Action string
+
+const (
+	// ActionRetry retries soon with exponential backoff, but not later than the next scheduled run.
+	ActionRetry Action = "retry"
+	// ActionWait waits for the next scheduled run.
+	ActionWait Action = "wait"
+	// ActionExit stops the daemon with the error.
+	ActionExit Action = "exit"
+)
+
+var actions = []Action{ActionRetry, ActionWait, ActionExit}
+
+const (
+	DefaultBackoff    = 30 * time.Second
+	DefaultMaxBackoff = 30 * time.Minute
+)
+
+var (
+	errInvalidPolicy   = errors.New("invalid error policy")
+	errTooManyFailures = errors.New("too many consecutive failures")
+)
+
+// Policy decides how the daemon survives synchronization errors.
+type Policy struct {
+	// Actions by error classes. Classes without an action are handled as [ClassOther].
+	Actions map[Class]Action
+	// MaxFailures is how many consecutive failures are tolerated, zero means unlimited.
+	MaxFailures int
+	// Backoff is the first delay before the retry, it doubles after each failure.
+	Backoff time.Duration
+	// MaxBackoff limits the delay before the retry.
+	MaxBackoff time.Duration
+}
+
+// DefaultPolicy retries network errors and rate limits, waits for the next run after server errors
+// and exits on other errors.
+func DefaultPolicy() Policy {
+	return Policy{
+		Actions: map[Class]Action{
+			ClassNetwork:    ActionRetry,
+			ClassServer:     ActionWait,
+			ClassRateLimit:  ActionRetry,
+			ClassAuth:       ActionExit,
+			ClassFilesystem: ActionExit,
+			ClassOther:      ActionExit,
+		},
+		Backoff:    DefaultBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// ParseActions parses actions by classes in the form "network=retry,auth=exit".
+// Classes which are not listed keep the default actions.
+func ParseActions(s string) (map[Class]Action, error) {
+	acts := DefaultPolicy().Actions
+
+	if strings.TrimSpace(s) == "" {
+		return acts, nil
+	}
+
+	for _, item := range strings.Split(s, ",") {
+		c, a, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not class=action", errInvalidPolicy, item)
+		}
+
+		class, action := Class(strings.TrimSpace(c)), Action(strings.TrimSpace(a))
+
+		if !contains(classes, class) {
+			return nil, fmt.Errorf("%w: unknown class %q", errInvalidPolicy, class)
+		}
+
+		if !contains(actions, action) {
+			return nil, fmt.Errorf("%w: unknown action %q", errInvalidPolicy, action)
+		}
+
+		acts[class] = action
+	}
+
+	return acts, nil
+}
+
+func contains[T comparable](items []T, v T) bool {
+	for _, item := range items {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p Policy) action(class Class) Action {
+	if a, ok := p.Actions[class]; ok {
+		return a
+	}
+
+	if a, ok := p.Actions[ClassOther]; ok {
+		return a
+	}
+
+	return ActionExit
+}
+
+// Classify returns the class of the synchronization error.
+func Classify(err error) Class {
+	var httpErr interface {
+		Code() int
+	}
+
+	if errors.As(err, &httpErr) {
+		switch code := httpErr.Code(); {
+		case code == http.StatusTooManyRequests:
+			return ClassRateLimit
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return ClassAuth
+		case code >= http.StatusInternalServerError:
+			return ClassServer
+		default:
+			return ClassOther
+		}
+	}
+
+	if isNetwork(err) {
+		return ClassNetwork
+	}
+
+	var pathErr *fs.PathError
+
+	if errors.As(err, &pathErr) || errors.Is(err, syscall.ENOSPC) {
+		return ClassFilesystem
+	}
+
+	return ClassOther
+}
+
+func isNetwork(err error) bool {
+	var (
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+		netErr net.Error
+	)
+
+	switch {
+	case errors.As(err, &dnsErr), errors.As(err, &opErr):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+
+	return false
+}
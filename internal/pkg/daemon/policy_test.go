@@ -0,0 +1,94 @@
+package daemon_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		err      error
+		expected daemon.Class
+	}{
+		{"5xx", serverErrorMock{code: http.StatusBadGateway}, daemon.ClassServer},
+		{"429", serverErrorMock{code: http.StatusTooManyRequests}, daemon.ClassRateLimit},
+		{"401", serverErrorMock{code: http.StatusUnauthorized}, daemon.ClassAuth},
+		{"403", serverErrorMock{code: http.StatusForbidden}, daemon.ClassAuth},
+		{"404", serverErrorMock{code: http.StatusNotFound}, daemon.ClassOther},
+		{"dns", &url.Error{Op: "Get", URL: "https://foo", Err: &net.DNSError{Err: "no such host", Name: "foo"}}, daemon.ClassNetwork},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, daemon.ClassNetwork},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), daemon.ClassNetwork},
+		{"timeout", &url.Error{Op: "Get", URL: "https://foo", Err: timeoutError{}}, daemon.ClassNetwork},
+		{"unexpected eof", fmt.Errorf("copy: %w", io.ErrUnexpectedEOF), daemon.ClassNetwork},
+		{"filesystem", &fs.PathError{Op: "open", Path: "foo", Err: os.ErrPermission}, daemon.ClassFilesystem},
+		{"no space", fmt.Errorf("write: %w", syscall.ENOSPC), daemon.ClassFilesystem},
+		{"canceled", context.Canceled, daemon.ClassOther},
+		{"other", errors.New("some error"), daemon.ClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, daemon.Classify(tt.err))
+		})
+	}
+}
+
+func TestParseActions(t *testing.T) {
+	t.Parallel()
+
+	got, err := daemon.ParseActions("auth = retry, other=wait")
+	require.NoError(t, err)
+
+	expected := daemon.DefaultPolicy().Actions
+	expected[daemon.ClassAuth] = daemon.ActionRetry
+	expected[daemon.ClassOther] = daemon.ActionWait
+
+	assert.Equal(t, expected, got)
+}
+
+func TestParseActions_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		value  string
+		expect string
+	}{
+		{"auth", `invalid error policy: "auth" is not class=action`},
+		{"foo=retry", `invalid error policy: unknown class "foo"`},
+		{"auth=ignore", `invalid error policy: unknown action "ignore"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := daemon.ParseActions(tt.value)
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
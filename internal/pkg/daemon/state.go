@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+// state of the daemon persisted between restarts.
+type state struct {
+	LastAttempt time.Time `json:"last_attempt"`
+	LastSuccess time.Time `json:"last_success"`
+}
+
+func (d *Daemon) loadState() state {
+	var st state
+
+	if d.stateFile == "" {
+		return st
+	}
+
+	if err := statefile.Load(d.stateFile, &st); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("daemon state is ignored", "error", err)
+		}
+
+		return state{}
+	}
+
+	slog.Debug("daemon state loaded", "last_attempt", st.LastAttempt, "last_success", st.LastSuccess)
+
+	return st
+}
+
+func (d *Daemon) saveState(st state) {
+	if d.stateFile == "" {
+		return
+	}
+
+	if err := statefile.Save(d.stateFile, st); err != nil {
+		slog.Warn("failed to save daemon state", "error", err)
+	}
+}
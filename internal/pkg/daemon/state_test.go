@@ -0,0 +1,152 @@
+package daemon_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+)
+
+func TestDaemon_Sync_State(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+
+	tests := [...]struct {
+		name        string
+		state       string
+		syncOnStart bool
+		expected    time.Time
+	}{
+		{
+			name:     "no state",
+			expected: now,
+		},
+		{
+			name:     "continue schedule",
+			state:    `{"last_attempt":"2025-02-24T09:30:00Z","last_success":"2025-02-24T09:30:00Z"}`,
+			expected: now.Add(23 * time.Hour),
+		},
+		{
+			name:        "sync on start",
+			state:       `{"last_attempt":"2025-02-24T09:30:00Z","last_success":"2025-02-24T09:30:00Z"}`,
+			syncOnStart: true,
+			expected:    now,
+		},
+		{
+			name:     "overdue",
+			state:    `{"last_attempt":"2025-02-23T09:30:00Z","last_success":"2025-02-23T09:30:00Z"}`,
+			expected: now,
+		},
+		{
+			name:     "failed attempt",
+			state:    `{"last_attempt":"2025-02-24T10:29:00Z","last_success":"2025-02-23T09:30:00Z"}`,
+			expected: now.Add(4 * time.Minute),
+		},
+		{
+			name:     "corrupted",
+			state:    `{corrupted`,
+			expected: now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "daemon.json")
+
+			if tt.state != "" {
+				require.NoError(t, os.WriteFile(path, []byte(tt.state), 0o600))
+			}
+
+			fake := clock.NewFake(now)
+			runs := make(chan time.Time, 1)
+
+			syncMock := syncFunc(func(context.Context) error {
+				runs <- fake.Now()
+
+				return nil
+			})
+
+			policy := daemon.DefaultPolicy()
+			policy.Backoff = 5 * time.Minute
+
+			dn := daemon.New(24*time.Hour, syncMock,
+				daemon.WithClock(fake),
+				daemon.WithPolicy(policy),
+				daemon.WithStateFile(path),
+				daemon.WithSyncOnStart(tt.syncOnStart),
+			)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			done := make(chan error)
+
+			go func() { done <- dn.Sync(ctx) }()
+
+			if tt.expected.After(now) {
+				fake.BlockUntil(1)
+
+				next, ok := fake.Next()
+				require.True(t, ok)
+				assert.Equal(t, tt.expected, next)
+
+				fake.Advance(next.Sub(now))
+			}
+
+			assert.Equal(t, tt.expected, <-runs)
+
+			fake.BlockUntil(1)
+			cancel()
+
+			require.ErrorIs(t, <-done, context.Canceled)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			var st map[string]time.Time
+
+			require.NoError(t, json.Unmarshal(data, &st))
+
+			assert.Equal(t, tt.expected, st["last_attempt"].UTC())
+			assert.Equal(t, tt.expected, st["last_success"].UTC())
+		})
+	}
+}
+
+func TestDaemon_Sync_State_Failure(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "daemon.json")
+	fake := clock.NewFake(now)
+
+	syncMock := syncFunc(func(context.Context) error {
+		return serverErrorMock{code: 502}
+	})
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithClock(fake), daemon.WithStateFile(path))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	fake.BlockUntil(1)
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"last_attempt":"2025-02-24T10:30:00Z","last_success":"0001-01-01T00:00:00Z"}`, string(data))
+}
@@ -0,0 +1,21 @@
+package daemon
+
+import (
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+// Status of the daemon.
+type Status struct {
+	// Running is true while the sync is running.
+	Running bool
+	// LastRun is the result of the last sync of the process, its start is zero before the first sync.
+	LastRun report.Run
+	// LastSuccess is the end of the last successful sync, it is restored from the state file after restart.
+	LastSuccess time.Time
+	// Failures is how many syncs in a row have failed.
+	Failures int
+	// NextRun is the time of the next sync.
+	NextRun time.Time
+}
@@ -0,0 +1,127 @@
+package daemon_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestDaemon_Status(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	var c int
+
+	syncMock := syncFunc(func(ctx context.Context) error {
+		c++
+
+		if c > 1 {
+			return serverErrorMock{code: 502}
+		}
+
+		rep := report.FromContext(ctx)
+		rep.SetTotal(3)
+		rep.Skip("exist.txt")
+		rep.Download("first.txt", 0, 0)
+		rep.Download("second.txt", 0, 0)
+
+		return nil
+	})
+
+	dn := daemon.New(time.Hour, syncMock, daemon.WithClock(fake))
+
+	assert.False(t, dn.Ready(2))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	fake.BlockUntil(1)
+
+	st := dn.Status()
+	assert.NotEmpty(t, st.LastRun.ID)
+
+	assert.Equal(t, daemon.Status{
+		LastRun: report.Run{
+			ID:       st.LastRun.ID,
+			Start:    now,
+			End:      now,
+			Counts:   report.Counts{Total: 3, Skipped: 1, Downloaded: 2},
+			NewBooks: []string{"first.txt", "second.txt"},
+			Books: []report.Book{
+				{Name: "exist.txt", Outcome: report.OutcomeSkipped},
+				{Name: "first.txt", Outcome: report.OutcomeDownloaded},
+				{Name: "second.txt", Outcome: report.OutcomeDownloaded},
+			},
+		},
+		LastSuccess: now,
+		NextRun:     now.Add(time.Hour),
+	}, st)
+	assert.True(t, dn.Ready(2))
+
+	fake.Advance(time.Hour)
+	fake.BlockUntil(1)
+
+	st = dn.Status()
+	assert.Equal(t, 1, st.Failures)
+	assert.Equal(t, now, st.LastSuccess)
+	require.Error(t, st.LastRun.Err)
+	assert.True(t, dn.Ready(2))
+	assert.False(t, dn.Ready(1))
+
+	fake.Advance(time.Hour)
+	fake.BlockUntil(1)
+
+	assert.Equal(t, 2, dn.Status().Failures)
+	assert.False(t, dn.Ready(2))
+
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestDaemon_StatusHook(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+
+	statuses := make(chan daemon.Status, 10)
+
+	dn := daemon.New(time.Hour, syncFunc(func(context.Context) error { return nil }),
+		daemon.WithClock(fake),
+		daemon.WithStatusHook(func(st daemon.Status) { statuses <- st }),
+	)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- dn.Sync(ctx) }()
+
+	fake.BlockUntil(1)
+	cancel()
+
+	require.ErrorIs(t, <-done, context.Canceled)
+
+	close(statuses)
+
+	var running []bool
+
+	for st := range statuses {
+		running = append(running, st.Running)
+	}
+
+	// Start, the run, its end and the next run.
+	assert.Equal(t, []bool{false, true, false, false}, running)
+	assert.Equal(t, now.Add(time.Hour), dn.Status().NextRun)
+}
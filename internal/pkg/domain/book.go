@@ -1,6 +1,18 @@
 package domain
 
+import "time"
+
 type Book struct {
+	// ID is the identifier of the book in the cloud.
+	ID       string
 	FileName string
 	Link     string
+	// Provider is the name of the provider of the book.
+	Provider string
+	// Format is the format of the file in the cloud, e.g. "epub".
+	Format string
+	// Size is the size of the file in the cloud in bytes, 0 if unknown.
+	Size int64
+	// Updated is the modification time of the file in the cloud.
+	Updated time.Time
 }
@@ -4,40 +4,112 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
 )
 
+// PartSuffix is the suffix of the file being downloaded.
+const PartSuffix = ".part"
+
+type Downloader struct {
+	client  *http.Client
+	metrics *metrics.Metrics
+}
+
+func New(opts ...Option) *Downloader {
+	d := &Downloader{
+		client: http.DefaultClient,
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// Download saves the file by the url to the destination with the default [Downloader].
 func Download(ctx context.Context, url, destination string) error {
+	return New().Download(ctx, url, destination)
+}
+
+// Download saves the file by the url to the destination.
+// The data is written to the file with [PartSuffix] which is renamed on success and removed on failure,
+// so an interrupted download never leaves a truncated file at the destination.
+func (d *Downloader) Download(ctx context.Context, url, destination string) error {
+	start := time.Now()
+
+	n, err := d.download(ctx, url, destination)
+	if err != nil {
+		d.metrics.BookFailed()
+
+		return err
+	}
+
+	elapsed := time.Since(start)
+
+	d.metrics.BookDownloaded(n, elapsed)
+
+	slog.InfoContext(ctx, "book downloaded", logging.Book(filepath.Base(destination)), logging.Bytes(n), logging.Duration(elapsed))
+
+	return nil
+}
+
+func (d *Downloader) download(ctx context.Context, url, destination string) (n int64, err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return 0, fmt.Errorf("create request: %w", err)
 	}
 
-	rsp, err := http.DefaultClient.Do(req)
+	rsp, err := d.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("http GET %s: %w", url, err)
+		return 0, redact.Error(fmt.Errorf("http GET %s: %w", url, err))
 	}
 
 	defer func() { _ = rsp.Body.Close() }()
 
 	if rsp.StatusCode != http.StatusOK {
-		return httpStatusError{rsp.StatusCode}
+		d.metrics.APIError(rsp.StatusCode)
+
+		return 0, httpStatusError{rsp.StatusCode}
 	}
 
-	file, err := os.Create(destination)
+	part := destination + PartSuffix
+
+	file, err := os.Create(part)
 	if err != nil {
-		return fmt.Errorf("create file %s: %w", destination, err)
+		return 0, fmt.Errorf("create file %s: %w", part, err)
 	}
 
-	defer func() { _ = file.Close() }()
+	defer func() {
+		if err != nil {
+			_ = os.Remove(part)
+		}
+	}()
 
-	_, err = io.Copy(file, rsp.Body)
+	n, err = io.Copy(file, rsp.Body)
 	if err != nil {
-		return fmt.Errorf("copy downloaded data to file %s: %w", destination, err)
+		_ = file.Close()
+
+		return 0, fmt.Errorf("copy downloaded data to file %s: %w", part, err)
 	}
 
-	return nil
+	if err = file.Close(); err != nil {
+		return 0, fmt.Errorf("close file %s: %w", part, err)
+	}
+
+	if err = os.Rename(part, destination); err != nil {
+		return 0, fmt.Errorf("rename file %s: %w", part, err)
+	}
+
+	return n, nil
 }
 
 type httpStatusError struct {
@@ -5,11 +5,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/download"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
 )
 
 func TestDownload(t *testing.T) {
@@ -55,3 +60,74 @@ func TestDownload_ContextCanceled(t *testing.T) {
 	err := download.Download(ctx, "http://foo", "bar")
 	require.ErrorIs(t, err, context.Canceled)
 }
+
+func TestDownload_Interrupted(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+
+		<-req.Context().Done()
+	}))
+
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	destination := filepath.Join(dir, "test_dest.txt")
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	// Cancels in the middle of the body when the partial file is created.
+	go func() {
+		defer cancel()
+
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(destination + download.PartSuffix)
+
+			return err == nil
+		}, time.Second, time.Millisecond)
+	}()
+
+	err := download.Download(ctx, srv.URL+"/test.txt", destination)
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorContains(t, err, "copy downloaded data")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestDownloader_Metrics(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/test.txt" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("test"))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	m := metrics.New()
+	d := download.New(download.WithMetrics(m), download.WithHTTPClient(srv.Client()))
+	dir := t.TempDir()
+
+	require.NoError(t, d.Download(t.Context(), srv.URL+"/test.txt", filepath.Join(dir, "test.txt")))
+	require.Error(t, d.Download(t.Context(), srv.URL+"/missing.txt", filepath.Join(dir, "missing.txt")))
+
+	var b strings.Builder
+
+	_, err := m.Registry().WriteTo(&b)
+	require.NoError(t, err)
+
+	assert.Contains(t, b.String(), "pbcsync_books_downloaded_total 1\n")
+	assert.Contains(t, b.String(), "pbcsync_downloaded_bytes_total 4\n")
+	assert.Contains(t, b.String(), "pbcsync_books_failed_total 1\n")
+	assert.Contains(t, b.String(), "pbcsync_api_errors_total{code=\"404\"} 1\n")
+}
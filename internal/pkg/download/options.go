@@ -0,0 +1,23 @@
+package download
+
+import (
+	"net/http"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+)
+
+type Option func(*Downloader)
+
+// WithHTTPClient sets the client of downloads, [http.DefaultClient] by default.
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Downloader) {
+		d.client = c
+	}
+}
+
+// WithMetrics sets the metrics of downloads.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(d *Downloader) {
+		d.metrics = m
+	}
+}
@@ -0,0 +1,40 @@
+package fakecloud
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Demo generates providers with books to fill the fake cloud.
+func Demo(providers, books int) []Provider {
+	ps := make([]Provider, providers)
+	updated := time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC)
+
+	for i := range ps {
+		shopID := strconv.Itoa(i + 1)
+
+		ps[i] = Provider{
+			Alias:  "provider-" + shopID,
+			Name:   "Provider " + shopID,
+			ShopID: shopID,
+			Books:  make([]Book, books),
+		}
+
+		for n := range ps[i].Books {
+			id := shopID + strconv.Itoa(n+1)
+			title := fmt.Sprintf("Book %d of provider %s", n+1, shopID)
+
+			ps[i].Books[n] = Book{
+				ID:      id,
+				Name:    fmt.Sprintf("book-%s-%d.txt", shopID, n+1),
+				Title:   title,
+				Authors: "Anonymous",
+				Content: []byte(title + "\n"),
+				Updated: updated,
+			}
+		}
+	}
+
+	return ps
+}
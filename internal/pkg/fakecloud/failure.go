@@ -0,0 +1,112 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Endpoint of the fake cloud to inject failures.
+type Endpoint string
+
+const (
+	EndpointProviders Endpoint = "providers"
+	EndpointLogin     Endpoint = "login"
+	EndpointBooks     Endpoint = "books"
+	EndpointFiles     Endpoint = "files"
+)
+
+// Endpoints lists all endpoints of the fake cloud.
+var Endpoints = []Endpoint{EndpointProviders, EndpointLogin, EndpointBooks, EndpointFiles}
+
+// Failure describes how a request is broken.
+type Failure struct {
+	// Status is the response status code. If zero, the request is served normally after the delays.
+	Status int
+	// RetryAfter sets the Retry-After header, useful with 429 and 503 codes.
+	RetryAfter time.Duration
+	// Delay is the wait before the response headers are sent.
+	Delay time.Duration
+	// SlowBody is the wait between chunks of the response body.
+	SlowBody time.Duration
+	// Times is how many requests are broken, zero means all requests.
+	Times int
+}
+
+func (f Failure) serve(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if !sleep(req, f.Delay) {
+		return
+	}
+
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Seconds())))
+	}
+
+	if f.Status != 0 {
+		writeError(w, f.Status, fmt.Sprintf("injected failure %d", f.Status))
+
+		return
+	}
+
+	if f.SlowBody > 0 {
+		w = &slowWriter{ResponseWriter: w, req: req, pause: f.SlowBody}
+	}
+
+	next(w, req)
+}
+
+// slowChunk is the size of the body chunk written between pauses.
+const slowChunk = 1024
+
+type slowWriter struct {
+	http.ResponseWriter
+	req     *http.Request
+	pause   time.Duration
+	started bool
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	var n int
+
+	for len(p) > 0 {
+		if w.started && !sleep(w.req, w.pause) {
+			return n, w.req.Context().Err()
+		}
+
+		w.started = true
+
+		chunk := p[:min(slowChunk, len(p))]
+
+		m, err := w.ResponseWriter.Write(chunk)
+		n += m
+
+		if err != nil {
+			return n, err
+		}
+
+		if f, ok := w.ResponseWriter.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		p = p[len(chunk):]
+	}
+
+	return n, nil
+}
+
+func sleep(req *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,384 @@
+// Package fakecloud is an in-memory stand-in of PocketBook Cloud API.
+// It serves providers, login, paginated books and downloadable files,
+// and allows to inject failures for testing and offline demos.
+package fakecloud
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pc "github.com/micronull/pocketbook-cloud-client"
+)
+
+// Path is the base path of the API, the same as the real one uses.
+const Path = pc.DefaultPath
+
+const defaultTokenTTL = time.Hour
+
+// Book is a file in the library of a provider.
+type Book struct {
+	ID      string
+	Name    string
+	Title   string
+	Authors string
+	Content []byte
+	Updated time.Time
+}
+
+// Provider is a shop linked to the account.
+type Provider struct {
+	Alias  string
+	Name   string
+	ShopID string
+	Books  []Book
+}
+
+type token struct {
+	shopID  string
+	expires time.Time
+}
+
+// Server is the fake cloud. It implements [http.Handler].
+type Server struct {
+	mux *http.ServeMux
+
+	mu        sync.Mutex
+	userName  string
+	password  string
+	providers []Provider
+	tokens    map[string]token
+	tokenTTL  time.Duration
+	pageLimit int
+	failures  map[Endpoint][]*Failure
+	requests  map[Endpoint]int
+}
+
+// New construct for [Server].
+func New(opts ...Option) *Server {
+	s := &Server{
+		mux:      http.NewServeMux(),
+		tokens:   make(map[string]token),
+		tokenTTL: defaultTokenTTL,
+		failures: make(map[Endpoint][]*Failure),
+		requests: make(map[Endpoint]int),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.mux.HandleFunc("GET "+Path+"auth/login", s.endpoint(EndpointProviders, s.handleProviders))
+	s.mux.HandleFunc("POST "+Path+"auth/login/{alias}", s.endpoint(EndpointLogin, s.handleLogin))
+	s.mux.HandleFunc("GET "+Path+"books", s.endpoint(EndpointBooks, s.handleBooks))
+	s.mux.HandleFunc("GET "+Path+"files/{shop}/{id}/{name}", s.endpoint(EndpointFiles, s.handleFile))
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(w, req)
+}
+
+// Fail injects the failure into the endpoint.
+// Failures of the same endpoint are applied in the order of injection.
+func (s *Server) Fail(endpoint Endpoint, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[endpoint] = append(s.failures[endpoint], &failure)
+}
+
+// ExpireTokens invalidates all issued tokens, so the clients must log in again.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.tokens)
+}
+
+// Requests returns how many requests the endpoint has received.
+func (s *Server) Requests(endpoint Endpoint) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.requests[endpoint]
+}
+
+func (s *Server) endpoint(endpoint Endpoint, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		s.mu.Lock()
+		s.requests[endpoint]++
+		failure := s.nextFailure(endpoint)
+		s.mu.Unlock()
+
+		if failure == nil {
+			next(w, req)
+
+			return
+		}
+
+		failure.serve(w, req, next)
+	}
+}
+
+func (s *Server) nextFailure(endpoint Endpoint) *Failure {
+	failures := s.failures[endpoint]
+	if len(failures) == 0 {
+		return nil
+	}
+
+	f := *failures[0]
+
+	if failures[0].Times > 0 {
+		failures[0].Times--
+
+		if failures[0].Times == 0 {
+			s.failures[endpoint] = failures[1:]
+		}
+	}
+
+	return &f
+}
+
+func (s *Server) handleProviders(w http.ResponseWriter, req *http.Request) {
+	type provider struct {
+		Alias  string `json:"alias"`
+		Name   string `json:"name"`
+		ShopID string `json:"shop_id"`
+	}
+
+	rsp := struct {
+		Providers []provider `json:"providers"`
+	}{
+		Providers: []provider{},
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.URL.Query().Get("username") == s.userName {
+		for _, p := range s.providers {
+			rsp.Providers = append(rsp.Providers, provider{Alias: p.Alias, Name: p.Name, ShopID: p.ShopID})
+		}
+	}
+
+	writeJSON(w, rsp)
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, req *http.Request) {
+	alias := req.PathValue("alias")
+	shopID := req.PostFormValue("shop_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.provider(shopID)
+
+	switch {
+	case !ok || p.Alias != alias:
+		writeError(w, http.StatusBadRequest, "unknown provider")
+
+		return
+	case req.PostFormValue("username") != s.userName || req.PostFormValue("password") != s.password:
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+
+		return
+	}
+
+	access, refresh := randomToken(), randomToken()
+
+	s.tokens[access] = token{
+		shopID:  shopID,
+		expires: time.Now().Add(s.tokenTTL),
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token":  access,
+		"token_type":    pc.TokenTypeBearer,
+		"expires_in":    int(s.tokenTTL.Seconds()),
+		"refresh_token": refresh,
+	})
+}
+
+func (s *Server) handleBooks(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	access := strings.TrimPrefix(req.Header.Get("Authorization"), string(pc.TokenTypeBearer)+" ")
+
+	shopID, ok := s.authorize(access)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid token")
+
+		return
+	}
+
+	p, _ := s.provider(shopID)
+
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+
+	if s.pageLimit > 0 && (limit == 0 || limit > s.pageLimit) {
+		limit = s.pageLimit
+	}
+
+	type item struct {
+		ID       string    `json:"id"`
+		Path     string    `json:"path"`
+		Title    string    `json:"title"`
+		MimeType string    `json:"mime_type"`
+		Bytes    int       `json:"bytes"`
+		FastHash string    `json:"fast_hash"`
+		Md5Hash  string    `json:"md5_hash"`
+		Link     string    `json:"link"`
+		Format   string    `json:"format"`
+		Mtime    time.Time `json:"mtime"`
+		Name     string    `json:"name"`
+		Metadata struct {
+			Title   string `json:"title"`
+			Authors string `json:"authors"`
+		} `json:"metadata"`
+	}
+
+	items := make([]item, 0)
+
+	for i := offset; limit > 0 && i < len(p.Books) && len(items) < limit; i++ {
+		bk := p.Books[i]
+		sum := md5.Sum(bk.Content)
+
+		it := item{
+			ID:       bk.ID,
+			Path:     "/" + bk.Name,
+			Title:    bk.Title,
+			MimeType: http.DetectContentType(bk.Content),
+			Bytes:    len(bk.Content),
+			FastHash: hex.EncodeToString(sum[:]),
+			Md5Hash:  base64.StdEncoding.EncodeToString(sum[:]),
+			Link:     s.fileLink(req, shopID, bk, access),
+			Format:   strings.TrimPrefix(pathExt(bk.Name), "."),
+			Mtime:    bk.Updated,
+			Name:     bk.Name,
+		}
+
+		it.Metadata.Title = bk.Title
+		it.Metadata.Authors = bk.Authors
+
+		items = append(items, it)
+	}
+
+	writeJSON(w, map[string]any{
+		"total": len(p.Books),
+		"items": items,
+	})
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, req *http.Request) {
+	s.mu.Lock()
+
+	shopID, ok := s.authorize(req.URL.Query().Get("access_token"))
+	if !ok || shopID != req.PathValue("shop") {
+		s.mu.Unlock()
+		writeError(w, http.StatusUnauthorized, "invalid token")
+
+		return
+	}
+
+	p, _ := s.provider(shopID)
+
+	var content []byte
+
+	found := false
+
+	for _, bk := range p.Books {
+		if bk.ID == req.PathValue("id") {
+			content, found = bk.Content, true
+
+			break
+		}
+	}
+
+	s.mu.Unlock()
+
+	if !found {
+		writeError(w, http.StatusNotFound, "file not found")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+
+	_, _ = w.Write(content)
+}
+
+func (s *Server) authorize(access string) (string, bool) {
+	t, ok := s.tokens[access]
+	if !ok || time.Now().After(t.expires) {
+		return "", false
+	}
+
+	return t.shopID, true
+}
+
+func (s *Server) provider(shopID string) (Provider, bool) {
+	for _, p := range s.providers {
+		if p.ShopID == shopID {
+			return p, true
+		}
+	}
+
+	return Provider{}, false
+}
+
+func (s *Server) fileLink(req *http.Request, shopID string, bk Book, access string) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     req.Host,
+		Path:     Path + "files/" + shopID + "/" + bk.ID + "/" + bk.Name,
+		RawQuery: url.Values{"access_token": []string{access}}.Encode(),
+	}
+
+	return u.String()
+}
+
+func pathExt(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i:]
+	}
+
+	return ""
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
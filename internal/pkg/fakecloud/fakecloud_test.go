@@ -0,0 +1,215 @@
+package fakecloud_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pc "github.com/micronull/pocketbook-cloud-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/apiurl"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/fakecloud"
+)
+
+const (
+	userName = "some user"
+	password = "some password"
+)
+
+func start(t *testing.T, opts ...fakecloud.Option) (*fakecloud.Server, *pc.Client) {
+	t.Helper()
+
+	fake := fakecloud.New(append([]fakecloud.Option{fakecloud.WithUser(userName, password)}, opts...)...)
+
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	base, err := apiurl.Parse(srv.URL + fakecloud.Path)
+	require.NoError(t, err)
+
+	client := pc.New(pc.WithHTTPClient(&http.Client{Transport: apiurl.NewTransport(base, nil)}))
+
+	return fake, client
+}
+
+func login(t *testing.T, client *pc.Client, provider fakecloud.Provider) string {
+	t.Helper()
+
+	token, err := client.Login(t.Context(), pc.LoginRequest{
+		ShopID:   provider.ShopID,
+		UserName: userName,
+		Password: password,
+		Provider: provider.Alias,
+	})
+	require.NoError(t, err)
+
+	return token.AccessToken
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	providers := fakecloud.Demo(2, 3)
+	_, client := start(t, fakecloud.WithProvider(providers[0]), fakecloud.WithProvider(providers[1]))
+
+	got, err := client.Providers(t.Context(), userName)
+	require.NoError(t, err)
+
+	require.Len(t, got, 2)
+	assert.Equal(t, pc.Provider{Alias: "provider-2", Name: "Provider 2", ShopID: "2"}, got[1])
+
+	token := login(t, client, providers[1])
+
+	books, err := client.Books(t.Context(), token, 0, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, books.Total)
+	assert.Empty(t, books.Books)
+
+	books, err = client.Books(t.Context(), token, books.Total, 0)
+	require.NoError(t, err)
+
+	require.Len(t, books.Books, 3)
+	assert.Equal(t, "book-2-1.txt", books.Books[0].Name)
+	assert.Equal(t, "Book 1 of provider 2", books.Books[0].MetaData.Title)
+
+	rsp, err := http.Get(books.Books[0].Link)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = rsp.Body.Close() })
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+	assert.Equal(t, "Book 1 of provider 2\n", string(body))
+}
+
+func TestServer_UnknownUser(t *testing.T) {
+	t.Parallel()
+
+	_, client := start(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	got, err := client.Providers(t.Context(), "unknown")
+	require.NoError(t, err)
+
+	assert.Empty(t, got)
+}
+
+func TestServer_Login_InvalidPassword(t *testing.T) {
+	t.Parallel()
+
+	provider := fakecloud.Demo(1, 1)[0]
+	_, client := start(t, fakecloud.WithProvider(provider))
+
+	_, err := client.Login(t.Context(), pc.LoginRequest{
+		ShopID:   provider.ShopID,
+		UserName: userName,
+		Password: "wrong",
+		Provider: provider.Alias,
+	})
+	require.ErrorContains(t, err, "401 Unauthorized")
+}
+
+func TestServer_PageLimit(t *testing.T) {
+	t.Parallel()
+
+	provider := fakecloud.Demo(1, 5)[0]
+	_, client := start(t, fakecloud.WithProvider(provider), fakecloud.WithPageLimit(2))
+
+	token := login(t, client, provider)
+
+	books, err := client.Books(t.Context(), token, 5, 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, books.Total)
+	require.Len(t, books.Books, 1)
+	assert.Equal(t, "book-1-5.txt", books.Books[0].Name)
+
+	books, err = client.Books(t.Context(), token, 5, 0)
+	require.NoError(t, err)
+
+	assert.Len(t, books.Books, 2)
+}
+
+func TestServer_ExpireTokens(t *testing.T) {
+	t.Parallel()
+
+	provider := fakecloud.Demo(1, 1)[0]
+	fake, client := start(t, fakecloud.WithProvider(provider))
+
+	token := login(t, client, provider)
+
+	fake.ExpireTokens()
+
+	_, err := client.Books(t.Context(), token, 0, 0)
+	require.ErrorContains(t, err, "401 Unauthorized")
+}
+
+func TestServer_Fail(t *testing.T) {
+	t.Parallel()
+
+	fake, client := start(t, fakecloud.WithProvider(fakecloud.Demo(1, 1)[0]))
+
+	fake.Fail(fakecloud.EndpointProviders, fakecloud.Failure{Status: http.StatusBadGateway, Times: 1})
+	fake.Fail(fakecloud.EndpointProviders, fakecloud.Failure{Status: http.StatusTooManyRequests, Times: 1})
+
+	_, err := client.Providers(t.Context(), userName)
+	require.ErrorContains(t, err, "502 Bad Gateway")
+
+	_, err = client.Providers(t.Context(), userName)
+	require.ErrorContains(t, err, "429 Too Many Requests")
+
+	_, err = client.Providers(t.Context(), userName)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, fake.Requests(fakecloud.EndpointProviders))
+}
+
+func TestServer_Fail_Delay(t *testing.T) {
+	t.Parallel()
+
+	fake, client := start(t)
+
+	fake.Fail(fakecloud.EndpointProviders, fakecloud.Failure{Delay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	t.Cleanup(cancel)
+
+	_, err := client.Providers(ctx, userName)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestServer_Fail_SlowBody(t *testing.T) {
+	t.Parallel()
+
+	provider := fakecloud.Demo(1, 1)[0]
+	provider.Books[0].Content = make([]byte, 3*1024)
+
+	fake, client := start(t, fakecloud.WithProvider(provider))
+
+	token := login(t, client, provider)
+
+	books, err := client.Books(t.Context(), token, 1, 0)
+	require.NoError(t, err)
+
+	fake.Fail(fakecloud.EndpointFiles, fakecloud.Failure{SlowBody: 20 * time.Millisecond})
+
+	start := time.Now()
+
+	rsp, err := http.Get(books.Books[0].Link)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = rsp.Body.Close() })
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+
+	assert.Len(t, body, 3*1024)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
@@ -0,0 +1,34 @@
+package fakecloud
+
+import "time"
+
+type Option func(*Server)
+
+// WithUser sets credentials of the account.
+func WithUser(userName, password string) Option {
+	return func(s *Server) {
+		s.userName = userName
+		s.password = password
+	}
+}
+
+// WithProvider links the provider and its books to the account.
+func WithProvider(provider Provider) Option {
+	return func(s *Server) {
+		s.providers = append(s.providers, provider)
+	}
+}
+
+// WithTokenTTL sets the lifetime of issued tokens.
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *Server) {
+		s.tokenTTL = ttl
+	}
+}
+
+// WithPageLimit limits how many books are returned by a single request.
+func WithPageLimit(limit int) Option {
+	return func(s *Server) {
+		s.pageLimit = limit
+	}
+}
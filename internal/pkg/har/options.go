@@ -0,0 +1,17 @@
+package har
+
+type Option func(*Recorder)
+
+// WithMaxBody sets how many bytes of each body are recorded, [DefaultMaxBody] by default.
+func WithMaxBody(n int) Option {
+	return func(r *Recorder) {
+		r.maxBody = n
+	}
+}
+
+// WithMaxEntries sets how many latest entries the file keeps at most, [DefaultMaxEntries] by default.
+func WithMaxEntries(n int) Option {
+	return func(r *Recorder) {
+		r.maxEntries = n
+	}
+}
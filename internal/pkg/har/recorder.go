@@ -0,0 +1,463 @@
+package har
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/version"
+)
+
+const (
+	// DefaultMaxBody is enough for the responses of the API, the books are truncated.
+	DefaultMaxBody = 64 << 10
+	// DefaultMaxEntries limits the file of the long-running daemon.
+	DefaultMaxEntries = 1000
+
+	// footer closes the entries and the log after the last entry, the entries are appended before it.
+	footer = "\n]}}\n"
+
+	// captureMargin is captured after the limit, so a secret at the end of the text is masked before the truncation.
+	captureMargin = 4 << 10
+
+	creatorName = "pocketbook-cloud-sync"
+	filePerm    = 0o600
+)
+
+// Recorder writes the exchanges of its transports to the file, it is safe for concurrent use.
+// Each exchange is appended before the closing brackets, so the file is complete even if the process is killed.
+// The file is rewritten only when it exceeds the max entries, then the oldest quarter of them is dropped.
+type Recorder struct {
+	path       string
+	maxBody    int
+	maxEntries int
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func New(path string, opts ...Option) *Recorder {
+	r := &Recorder{
+		path:       path,
+		maxBody:    DefaultMaxBody,
+		maxEntries: DefaultMaxEntries,
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
+}
+
+// Transport returns the transport recording the exchanges of next, [http.DefaultTransport] if nil.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &transport{rec: r, next: next}
+}
+
+// Entries returns the recorded entries from the oldest.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.entries)
+}
+
+func (r *Recorder) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, e)
+
+	var err error
+
+	switch {
+	case r.maxEntries > 0 && len(r.entries) > r.maxEntries:
+		r.entries = slices.Delete(r.entries, 0, len(r.entries)-r.maxEntries+r.maxEntries/4)
+		err = r.write()
+	case len(r.entries) == 1:
+		err = r.write()
+	default:
+		if err = r.append(e); err != nil {
+			err = r.write()
+		}
+	}
+
+	if err != nil {
+		slog.Warn("failed to write http trace", "error", err)
+	}
+}
+
+// write replaces the file with the entries, each entry is on its own line.
+func (r *Recorder) write() error {
+	head, err := json.Marshal(HAR{Log: Log{
+		Version: Version,
+		Creator: Creator{Name: creatorName, Version: version.Version()},
+		Entries: []Entry{},
+	}})
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(bytes.TrimSuffix(head, []byte("]}}")))
+
+	for i, e := range r.entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		buf.WriteByte('\n')
+		buf.Write(data)
+	}
+
+	buf.WriteString(footer)
+
+	return statefile.WriteFile(r.path, buf.Bytes(), filePerm)
+}
+
+// append writes the entry over the footer of the file.
+func (r *Recorder) append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.path, os.O_WRONLY, filePerm)
+	if err != nil {
+		return err
+	}
+
+	if _, err = f.Seek(-int64(len(footer)), io.SeekEnd); err == nil {
+		_, err = f.Write(slices.Concat([]byte(",\n"), data, []byte(footer)))
+	}
+
+	return errors.Join(err, f.Close())
+}
+
+type transport struct {
+	rec  *Recorder
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	tm := &timer{start: start}
+	traced := req.WithContext(httptrace.WithClientTrace(req.Context(), tm.trace()))
+
+	if body != nil {
+		traced.Body = io.NopCloser(bytes.NewReader(body))
+		traced.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+
+	e := Entry{
+		StartedDateTime: start,
+		Request:         t.rec.request(req, body),
+	}
+
+	rsp, err := t.next.RoundTrip(traced)
+	if err != nil {
+		e.Response = Response{Cookies: []Cookie{}, Headers: []NameValue{}, HeadersSize: -1, BodySize: -1}
+		e.Error = redact.String(err.Error())
+		e.Time, e.Timings = tm.finish(time.Now())
+
+		t.rec.add(e)
+
+		return nil, err
+	}
+
+	if e.Request.HTTPVersion == "" {
+		e.Request.HTTPVersion = rsp.Proto
+	}
+
+	e.Response = response(rsp)
+	rsp.Body = &bodyRecorder{
+		ReadCloser: rsp.Body,
+		max:        t.rec.maxBody + captureMargin,
+		done: func(captured []byte, size int64) {
+			e.Response.BodySize = size
+			e.Response.Content = t.rec.content(e.Response.Content.MimeType, captured, size)
+			e.Time, e.Timings = tm.finish(time.Now())
+
+			t.rec.add(e)
+		},
+	}
+
+	return rsp, nil
+}
+
+// readBody reads the body of the request to record it, the bodies of the API requests are small.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	defer func() { _ = req.Body.Close() }()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+
+	return body, nil
+}
+
+func (r *Recorder) request(req *http.Request, body []byte) Request {
+	u := redact.String(req.URL.String())
+
+	hr := Request{
+		Method:      req.Method,
+		URL:         u,
+		HTTPVersion: req.Proto,
+		Cookies:     cookies(req.Cookies()),
+		Headers:     headers(req.Header),
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+		BodySize:    int64(len(body)),
+	}
+
+	if parsed, err := url.Parse(u); err == nil {
+		hr.QueryString = values(parsed.Query())
+	}
+
+	if body != nil {
+		mimeType := req.Header.Get("Content-Type")
+		content := r.content(mimeType, body, int64(len(body)))
+
+		hr.PostData = &PostData{MimeType: mimeType, Params: []NameValue{}, Text: content.Text, Comment: content.Comment}
+
+		if mt, _, _ := mime.ParseMediaType(mimeType); mt == "application/x-www-form-urlencoded" {
+			if params, err := url.ParseQuery(content.Text); err == nil {
+				hr.PostData.Params = values(params)
+			}
+		}
+	}
+
+	return hr
+}
+
+func response(rsp *http.Response) Response {
+	hr := Response{
+		Status:      rsp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(rsp.Status, fmt.Sprint(rsp.StatusCode))),
+		HTTPVersion: rsp.Proto,
+		Cookies:     cookies(rsp.Cookies()),
+		Headers:     headers(rsp.Header),
+		Content:     Content{MimeType: rsp.Header.Get("Content-Type")},
+		HeadersSize: -1,
+	}
+
+	if loc := rsp.Header.Get("Location"); loc != "" {
+		hr.RedirectURL = redact.String(loc)
+	}
+
+	return hr
+}
+
+// content returns the masked and truncated text of the textual body, the binary bodies like books are omitted.
+func (r *Recorder) content(mimeType string, captured []byte, size int64) Content {
+	c := Content{Size: size, MimeType: mimeType}
+
+	switch {
+	case size == 0:
+	case !textual(mimeType):
+		c.Comment = "binary content is omitted"
+	default:
+		c.Text = redact.String(string(captured))
+
+		if size > int64(len(captured)) || len(c.Text) > r.maxBody {
+			c.Text = strings.ToValidUTF8(c.Text[:min(len(c.Text), r.maxBody)], "")
+			c.Comment = fmt.Sprintf("truncated to %d bytes", len(c.Text))
+		}
+	}
+
+	return c
+}
+
+func textual(mimeType string) bool {
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasPrefix(mt, "text/") ||
+		strings.HasSuffix(mt, "json") ||
+		strings.HasSuffix(mt, "xml") ||
+		mt == "application/x-www-form-urlencoded" ||
+		mt == "application/javascript"
+}
+
+// sensitiveHeaders are masked as a whole.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+func headers(h http.Header) []NameValue {
+	nvs := []NameValue{}
+
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[name] {
+			if slices.Contains(sensitiveHeaders, name) {
+				v = redact.Mask
+			}
+
+			nvs = append(nvs, NameValue{Name: name, Value: redact.String(v)})
+		}
+	}
+
+	return nvs
+}
+
+func cookies(cs []*http.Cookie) []Cookie {
+	hcs := make([]Cookie, 0, len(cs))
+
+	for _, c := range cs {
+		hcs = append(hcs, Cookie{Name: c.Name, Value: redact.Mask})
+	}
+
+	return hcs
+}
+
+func values(v url.Values) []NameValue {
+	nvs := []NameValue{}
+
+	for _, name := range slices.Sorted(maps.Keys(v)) {
+		for _, value := range v[name] {
+			nvs = append(nvs, NameValue{Name: name, Value: value})
+		}
+	}
+
+	return nvs
+}
+
+// bodyRecorder captures the beginning of the body and counts its size,
+// the entry is done at the end of the body or when it is closed.
+type bodyRecorder struct {
+	io.ReadCloser
+	max      int
+	captured []byte
+	size     int64
+	once     sync.Once
+	done     func(captured []byte, size int64)
+}
+
+func (b *bodyRecorder) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.size += int64(n)
+
+	if rest := b.max - len(b.captured); rest > 0 {
+		b.captured = append(b.captured, p[:min(n, rest)]...)
+	}
+
+	if err != nil {
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *bodyRecorder) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.finish()
+
+	return err
+}
+
+func (b *bodyRecorder) finish() {
+	b.once.Do(func() { b.done(b.captured, b.size) })
+}
+
+// timer measures the phases of the exchange by the trace of the client.
+type timer struct {
+	mu    sync.Mutex
+	start time.Time
+
+	gotConn, dnsStart, dnsDone, connectStart, connectDone, tlsStart, tlsDone, wrote, firstByte time.Time
+}
+
+func (t *timer) trace() *httptrace.ClientTrace {
+	set := func(field *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		*field = time.Now()
+	}
+
+	return &httptrace.ClientTrace{
+		GotConn:              func(httptrace.GotConnInfo) { set(&t.gotConn) },
+		DNSStart:             func(httptrace.DNSStartInfo) { set(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { set(&t.dnsDone) },
+		ConnectStart:         func(string, string) { set(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { set(&t.connectDone) },
+		TLSHandshakeStart:    func() { set(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&t.tlsDone) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&t.wrote) },
+		GotFirstResponseByte: func() { set(&t.firstByte) },
+	}
+}
+
+// finish returns the total time and the timings of the exchange ended at the time.
+func (t *timer) finish(end time.Time) (float64, Timings) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tm := Timings{
+		Blocked: -1,
+		DNS:     span(t.dnsStart, t.dnsDone),
+		Connect: span(t.connectStart, t.connectDone),
+		SSL:     span(t.tlsStart, t.tlsDone),
+		Send:    max(span(t.gotConn, t.wrote), 0),
+		Wait:    max(span(t.wrote, t.firstByte), 0),
+		Receive: max(span(t.firstByte, end), 0),
+	}
+
+	// The connection time includes the handshake.
+	if tm.Connect >= 0 && tm.SSL >= 0 {
+		tm.Connect += tm.SSL
+	}
+
+	return ms(end.Sub(t.start)), tm
+}
+
+// span returns milliseconds between the times or -1 if any of them isn't set.
+func span(from, to time.Time) float64 {
+	if from.IsZero() || to.IsZero() {
+		return -1
+	}
+
+	return ms(to.Sub(from))
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
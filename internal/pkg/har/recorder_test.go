@@ -0,0 +1,149 @@
+package har_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/har"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("Content-Type", "application/json")
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "c00kie"})
+			_, _ = io.WriteString(w, `{"access_token":"t0k3n-value","token_type":"bearer"}`)
+		case "/book.epub":
+			w.Header().Set("Content-Type", "application/epub+zip")
+			_, _ = io.WriteString(w, strings.Repeat("x", 100))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	rec := har.New(path, har.WithMaxBody(40))
+	client := &http.Client{Transport: rec.Transport(nil)}
+
+	form := url.Values{"username": {"reader"}, "password": {"hunter2"}}
+
+	rsp, err := client.PostForm(srv.URL+"/login?client_secret=s3cr3t&lang=en", form)
+	require.NoError(t, err)
+
+	body, err := io.ReadAll(rsp.Body)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+	assert.Contains(t, string(body), "t0k3n-value", "the client gets the original body")
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL+"/book.epub?X-Amz-Signature=abc123", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer t0k3n-value")
+
+	rsp, err = client.Do(req)
+	require.NoError(t, err)
+
+	_, err = io.Copy(io.Discard, rsp.Body)
+	require.NoError(t, err)
+	require.NoError(t, rsp.Body.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, secret := range []string{"hunter2", "s3cr3t", "t0k3n-value", "c00kie", "abc123"} {
+		assert.NotContains(t, string(data), secret)
+	}
+
+	var doc har.HAR
+
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, har.Version, doc.Log.Version)
+	require.Len(t, doc.Log.Entries, 2)
+
+	login := doc.Log.Entries[0]
+	assert.Equal(t, http.MethodPost, login.Request.Method)
+	assert.Equal(t, srv.URL+"/login?client_secret=REDACTED&lang=en", login.Request.URL)
+	assert.Equal(t, []har.NameValue{{Name: "client_secret", Value: "REDACTED"}, {Name: "lang", Value: "en"}}, login.Request.QueryString)
+	require.NotNil(t, login.Request.PostData)
+	assert.Equal(t, "password=REDACTED&username=reader", login.Request.PostData.Text)
+	assert.Equal(t, http.StatusOK, login.Response.Status)
+	assert.Equal(t, "OK", login.Response.StatusText)
+	assert.Equal(t, []har.Cookie{{Name: "session", Value: "REDACTED"}}, login.Response.Cookies)
+	assert.Equal(t, `{"access_token":"REDACTED","token_type":`, login.Response.Content.Text)
+	assert.Equal(t, "truncated to 40 bytes", login.Response.Content.Comment)
+	assert.GreaterOrEqual(t, login.Time, 0.0)
+	assert.GreaterOrEqual(t, login.Timings.Wait, 0.0)
+
+	book := doc.Log.Entries[1]
+	assert.Contains(t, book.Request.Headers, har.NameValue{Name: "Authorization", Value: "REDACTED"})
+	assert.Equal(t, int64(100), book.Response.BodySize)
+	assert.Equal(t, har.Content{Size: 100, MimeType: "application/epub+zip", Comment: "binary content is omitted"}, book.Response.Content)
+}
+
+func TestRecorder_Error(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	rec := har.New(path)
+	client := &http.Client{Transport: rec.Transport(nil)}
+
+	_, err := client.Get(srv.URL + "/books?token=t0k3n-value")
+	require.Error(t, err)
+
+	entries := rec.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, 0, entries[0].Response.Status)
+	assert.Contains(t, entries[0].Error, "connection refused")
+	assert.NotContains(t, entries[0].Error, "t0k3n-value")
+}
+
+func TestRecorder_MaxEntries(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.URL.Query().Get("n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "trace.har")
+	rec := har.New(path, har.WithMaxEntries(4))
+	client := &http.Client{Transport: rec.Transport(nil)}
+
+	for _, n := range []string{"1", "2", "3", "4", "5", "6"} {
+		rsp, err := client.Get(srv.URL + "/?n=" + n)
+		require.NoError(t, err)
+
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		require.NoError(t, rsp.Body.Close())
+	}
+
+	// The fifth entry drops the oldest quarter with the overflow, the sixth one is appended.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var doc har.HAR
+
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	for _, entries := range [][]har.Entry{rec.Entries(), doc.Log.Entries} {
+		require.Len(t, entries, 4)
+
+		for i, n := range []string{"3", "4", "5", "6"} {
+			assert.Equal(t, n, entries[i].Response.Content.Text)
+		}
+	}
+}
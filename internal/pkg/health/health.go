@@ -0,0 +1,121 @@
+// Package health serves the health, readiness and status endpoints of the daemon.
+package health
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+const (
+	PathHealth = "/healthz"
+	PathReady  = "/readyz"
+	PathStatus = "/status"
+)
+
+// DefaultReadyIntervals is how many schedule intervals the last successful sync stays ready by default.
+const DefaultReadyIntervals = 2
+
+type source interface {
+	Status() daemon.Status
+	Ready(intervals int) bool
+}
+
+type handler struct {
+	source    source
+	intervals int
+}
+
+// Handler returns the handler of the endpoints:
+// [PathHealth] answers while the process is alive,
+// [PathReady] answers successfully if the last sync has succeeded within the intervals of the schedule,
+// [PathStatus] returns the status as JSON.
+func Handler(src source, intervals int) http.Handler {
+	h := handler{source: src, intervals: intervals}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+PathHealth, h.health)
+	mux.HandleFunc("GET "+PathReady, h.ready)
+	mux.HandleFunc("GET "+PathStatus, h.status)
+
+	return mux
+}
+
+func (h handler) health(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+func (h handler) ready(w http.ResponseWriter, _ *http.Request) {
+	if !h.source.Ready(h.intervals) {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+
+		return
+	}
+
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+type status struct {
+	Running     bool       `json:"running"`
+	Ready       bool       `json:"ready"`
+	LastRun     *run       `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Failures    int        `json:"consecutive_failures"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+}
+
+type run struct {
+	Start      time.Time  `json:"start"`
+	End        *time.Time `json:"end,omitempty"`
+	Result     string     `json:"result"`
+	Error      string     `json:"error,omitempty"`
+	Total      int        `json:"total"`
+	Skipped    int        `json:"skipped"`
+	Downloaded int        `json:"downloaded"`
+}
+
+func (h handler) status(w http.ResponseWriter, _ *http.Request) {
+	st := h.source.Status()
+
+	rsp := status{
+		Running:     st.Running,
+		Ready:       h.source.Ready(h.intervals),
+		LastSuccess: optional(st.LastSuccess),
+		Failures:    st.Failures,
+		NextRun:     optional(st.NextRun),
+	}
+
+	if !st.LastRun.Start.IsZero() {
+		rsp.LastRun = &run{
+			Start:      st.LastRun.Start,
+			End:        optional(st.LastRun.End),
+			Result:     report.StatusSuccess,
+			Total:      st.LastRun.Total,
+			Skipped:    st.LastRun.Skipped,
+			Downloaded: st.LastRun.Downloaded,
+		}
+
+		if st.LastRun.Err != nil {
+			rsp.LastRun.Result = report.StatusError
+			rsp.LastRun.Error = st.LastRun.Err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		slog.Warn("failed to write status", "error", err)
+	}
+}
+
+func optional(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,154 @@
+package health_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/daemon"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/health"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+type sourceMock struct {
+	status    daemon.Status
+	ready     bool
+	intervals int
+}
+
+func (s *sourceMock) Status() daemon.Status {
+	return s.status
+}
+
+func (s *sourceMock) Ready(intervals int) bool {
+	s.intervals = intervals
+
+	return s.ready
+}
+
+func get(t *testing.T, h http.Handler, path string) (int, string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	return rec.Code, string(body)
+}
+
+func TestHandler_Health(t *testing.T) {
+	t.Parallel()
+
+	code, _ := get(t, health.Handler(&sourceMock{}, 2), health.PathHealth)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestHandler_Ready(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		ready    bool
+		expected int
+	}{
+		{
+			name:     "ready",
+			ready:    true,
+			expected: http.StatusOK,
+		},
+		{
+			name:     "not ready",
+			expected: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := &sourceMock{ready: tt.ready}
+
+			code, _ := get(t, health.Handler(src, 3), health.PathReady)
+			assert.Equal(t, tt.expected, code)
+			assert.Equal(t, 3, src.intervals)
+		})
+	}
+}
+
+func TestHandler_Status(t *testing.T) {
+	t.Parallel()
+
+	at := func(h int) time.Time { return time.Date(2025, time.February, 24, h, 0, 0, 0, time.UTC) }
+
+	tests := [...]struct {
+		name     string
+		status   daemon.Status
+		expected string
+	}{
+		{
+			name:     "before first run",
+			status:   daemon.Status{Running: true, NextRun: at(10)},
+			expected: `{"running":true,"ready":false,"consecutive_failures":0,"next_run":"2025-02-24T10:00:00Z"}`,
+		},
+		{
+			name: "success",
+			status: daemon.Status{
+				LastRun: report.Run{
+					Start:  at(10),
+					End:    at(11),
+					Counts: report.Counts{Total: 3, Skipped: 1, Downloaded: 2},
+				},
+				LastSuccess: at(11),
+				NextRun:     at(12),
+			},
+			expected: `{"running":false,"ready":false,"consecutive_failures":0,` +
+				`"last_run":{"start":"2025-02-24T10:00:00Z","end":"2025-02-24T11:00:00Z","result":"success","total":3,"skipped":1,"downloaded":2},` +
+				`"last_success":"2025-02-24T11:00:00Z","next_run":"2025-02-24T12:00:00Z"}`,
+		},
+		{
+			name: "error",
+			status: daemon.Status{
+				LastRun:  report.Run{Start: at(10), End: at(11), Err: errors.New("some error")},
+				Failures: 2,
+				NextRun:  at(12),
+			},
+			expected: `{"running":false,"ready":false,"consecutive_failures":2,` +
+				`"last_run":{"start":"2025-02-24T10:00:00Z","end":"2025-02-24T11:00:00Z","result":"error","error":"some error","total":0,"skipped":0,"downloaded":0},` +
+				`"next_run":"2025-02-24T12:00:00Z"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := get(t, health.Handler(&sourceMock{status: tt.status}, 2), health.PathStatus)
+			assert.Equal(t, http.StatusOK, code)
+			assert.JSONEq(t, tt.expected, body)
+		})
+	}
+}
+
+func TestListen(t *testing.T) {
+	t.Parallel()
+
+	srv, err := health.Listen("127.0.0.1:0", health.Handler(&sourceMock{}, 2))
+	require.NoError(t, err)
+
+	t.Cleanup(srv.Shutdown)
+
+	rsp, err := http.Get("http://" + srv.Addr() + health.PathHealth)
+	require.NoError(t, err)
+
+	_ = rsp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, rsp.StatusCode)
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server serves the endpoints in the background.
+type Server struct {
+	hs *http.Server
+	ln net.Listener
+}
+
+// Listen starts serving the handler on the address.
+func Listen(addr string, h http.Handler) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	s := &Server{
+		hs: &http.Server{
+			Handler:           h,
+			ReadHeaderTimeout: time.Minute,
+		},
+		ln: ln,
+	}
+
+	go func() {
+		if err := s.hs.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("health server failed", "error", err)
+		}
+	}()
+
+	slog.Info("health server is listening", "addr", ln.Addr().String())
+
+	return s, nil
+}
+
+// Addr returns the address the server listens.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Shutdown stops the server waiting for the active requests.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	_ = s.hs.Shutdown(ctx)
+}
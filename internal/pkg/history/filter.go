@@ -0,0 +1,58 @@
+package history
+
+import (
+	"strings"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+// Filter selects the runs of the journal, the zero fields match everything.
+type Filter struct {
+	// Status is [report.StatusSuccess] or [report.StatusError].
+	Status string
+	// Since and Until bound the start of the run, Until is exclusive.
+	Since time.Time
+	Until time.Time
+	// Book is the part of the book name matched regardless of the case.
+	// The books of the matched runs are narrowed to the matching ones, the runs without them are dropped.
+	Book string
+}
+
+// Apply returns the matching runs in the same order.
+func (f Filter) Apply(runs []report.Summary) []report.Summary {
+	var out []report.Summary
+
+	book := strings.ToLower(f.Book)
+
+	for _, r := range runs {
+		switch {
+		case f.Status != "" && r.Status != f.Status:
+			continue
+		case !f.Since.IsZero() && r.Start.Before(f.Since):
+			continue
+		case !f.Until.IsZero() && !r.Start.Before(f.Until):
+			continue
+		}
+
+		if book != "" {
+			var books []report.BookSummary
+
+			for _, b := range r.Books {
+				if strings.Contains(strings.ToLower(b.Name), book) {
+					books = append(books, b)
+				}
+			}
+
+			if len(books) == 0 {
+				continue
+			}
+
+			r.Books = books
+		}
+
+		out = append(out, r)
+	}
+
+	return out
+}
@@ -0,0 +1,83 @@
+package history_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestFilter_Apply(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	runs := []report.Summary{
+		{
+			RunID:  "first",
+			Status: report.StatusSuccess,
+			Start:  day,
+			Books:  []report.BookSummary{{Name: "Dune.epub", Outcome: report.OutcomeDownloaded}},
+		},
+		{
+			RunID:  "second",
+			Status: report.StatusError,
+			Start:  day.Add(24 * time.Hour),
+			Books: []report.BookSummary{
+				{Name: "Emma.pdf", Outcome: report.OutcomeFailed},
+				{Name: "Dune Messiah.epub", Outcome: report.OutcomeDownloaded},
+			},
+		},
+		{
+			RunID:  "third",
+			Status: report.StatusSuccess,
+			Start:  day.Add(48 * time.Hour),
+		},
+	}
+
+	tests := [...]struct {
+		name   string
+		filter history.Filter
+		expect []string
+	}{
+		{
+			name:   "all",
+			expect: []string{"first", "second", "third"},
+		},
+		{
+			name:   "status",
+			filter: history.Filter{Status: report.StatusSuccess},
+			expect: []string{"first", "third"},
+		},
+		{
+			name:   "dates",
+			filter: history.Filter{Since: day.Add(time.Hour), Until: day.Add(48 * time.Hour)},
+			expect: []string{"second"},
+		},
+		{
+			name:   "book",
+			filter: history.Filter{Book: "emma"},
+			expect: []string{"second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ids []string
+
+			for _, r := range tt.filter.Apply(runs) {
+				ids = append(ids, r.RunID)
+			}
+
+			assert.Equal(t, tt.expect, ids)
+		})
+	}
+
+	got := history.Filter{Book: "DUNE"}.Apply(runs)
+	assert.Equal(t, []report.BookSummary{{Name: "Dune Messiah.epub", Outcome: report.OutcomeDownloaded}}, got[1].Books)
+}
@@ -0,0 +1,209 @@
+// Package history keeps the journal of the runs and the file operations as JSON Lines.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+// FileName is the name of the journal in the state directory.
+const FileName = "history.jsonl"
+
+const (
+	dirPerm  = 0o700
+	filePerm = 0o600
+)
+
+// Journal appends the report of each run to the file and drops the runs older than the retention.
+// The skipped books are not recorded, they aren't touched by the run.
+type Journal struct {
+	mu        sync.Mutex
+	path      string
+	retention time.Duration
+	clock     clock.Clock
+}
+
+func New(path string, retention time.Duration, opts ...Option) *Journal {
+	j := &Journal{
+		path:      path,
+		retention: retention,
+		clock:     clock.Real{},
+	}
+
+	for _, o := range opts {
+		o(j)
+	}
+
+	return j
+}
+
+// Append records the run, the zero retention keeps all runs.
+// The run is appended to the end of the file, the journal is rewritten only to drop the expired runs.
+// The file is locked while it is changed, so the processes sharing the state directory don't lose the runs.
+func (j *Journal) Append(run report.Summary) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	books := make([]report.BookSummary, 0, len(run.Books))
+
+	for _, b := range run.Books {
+		if b.Outcome != report.OutcomeSkipped {
+			books = append(books, b)
+		}
+	}
+
+	run.Books = books
+
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(j.path), dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	f, err := os.OpenFile(j.path, os.O_RDWR|os.O_CREATE, filePerm)
+	if err != nil {
+		return fmt.Errorf("open journal: %w", err)
+	}
+
+	defer func() { _ = f.Close() }()
+
+	if err = lock.File(f); err != nil {
+		return fmt.Errorf("lock journal: %w", err)
+	}
+
+	if _, err = f.Seek(0, io.SeekEnd); err == nil {
+		_, err = f.Write(append(line, '\n'))
+	}
+
+	if err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	if err = j.prune(f); err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	return nil
+}
+
+// prune drops the runs older than the retention from the locked journal.
+// The runs are in the order of the end, so the file is rewritten only if the first one is expired.
+// It is rewritten in place to keep the lock, the kept runs are written over the beginning before the truncation,
+// so an interrupted rewrite may duplicate them but doesn't lose them.
+func (j *Journal) prune(f *os.File) error {
+	if j.retention <= 0 {
+		return nil
+	}
+
+	expired := j.clock.Now().Add(-j.retention)
+
+	first, err := readFirst(io.NewSectionReader(f, 0, math.MaxInt64))
+	if err != nil || first.End.IsZero() || !first.End.Before(expired) {
+		return err
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, math.MaxInt64))
+	if err != nil {
+		return fmt.Errorf("read journal: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+
+	for _, r := range parse(data) {
+		if r.End.Before(expired) {
+			continue
+		}
+
+		if err = enc.Encode(r); err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+	}
+
+	if _, err = f.WriteAt(buf.Bytes(), 0); err == nil {
+		err = f.Truncate(int64(buf.Len()))
+	}
+
+	if err != nil {
+		return fmt.Errorf("write journal: %w", err)
+	}
+
+	return nil
+}
+
+// readFirst returns the oldest run of the journal, the zero run if there are no runs.
+func readFirst(src io.Reader) (report.Summary, error) {
+	rd := bufio.NewReader(src)
+
+	for {
+		line, err := rd.ReadBytes('\n')
+
+		var r report.Summary
+
+		if json.Unmarshal(line, &r) == nil && r.RunID != "" {
+			return r, nil
+		}
+
+		if errors.Is(err, io.EOF) {
+			return report.Summary{}, nil
+		}
+
+		if err != nil {
+			return report.Summary{}, fmt.Errorf("read journal: %w", err)
+		}
+	}
+}
+
+// Read returns the runs of the journal from the oldest one.
+// The missing journal has no runs, the lines which aren't runs are skipped.
+func Read(path string) ([]report.Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	return parse(data), nil
+}
+
+// parse returns the runs of the lines, the lines which aren't runs are skipped.
+func parse(data []byte) []report.Summary {
+	var runs []report.Summary
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, len(data)+1)
+
+	for sc.Scan() {
+		var r report.Summary
+
+		if json.Unmarshal(sc.Bytes(), &r) == nil && r.RunID != "" {
+			runs = append(runs, r)
+		}
+	}
+
+	return runs
+}
@@ -0,0 +1,113 @@
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestJournal_Append(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	path := filepath.Join(t.TempDir(), "state", history.FileName)
+
+	j := history.New(path, 48*time.Hour, history.WithClock(fake))
+
+	old := report.Summary{RunID: "old", Status: report.StatusSuccess, Start: now, End: now}
+	require.NoError(t, j.Append(old))
+
+	fake.Advance(24 * time.Hour)
+
+	next := report.Summary{
+		RunID:  "next",
+		Status: report.StatusError,
+		Start:  now.Add(24 * time.Hour),
+		End:    now.Add(24 * time.Hour),
+		Books: []report.BookSummary{
+			{Name: "exist.txt", Outcome: report.OutcomeSkipped},
+			{Name: "first.txt", Outcome: report.OutcomeFailed, Reason: "refused"},
+		},
+	}
+	require.NoError(t, j.Append(next))
+
+	runs, err := history.Read(path)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "old", runs[0].RunID)
+	assert.Equal(t, []report.BookSummary{{Name: "first.txt", Outcome: report.OutcomeFailed, Reason: "refused"}}, runs[1].Books)
+
+	fake.Advance(36 * time.Hour)
+
+	require.NoError(t, j.Append(report.Summary{RunID: "last", Start: fake.Now(), End: fake.Now()}))
+
+	runs, err = history.Read(path)
+	require.NoError(t, err)
+
+	var ids []string
+
+	for _, r := range runs {
+		ids = append(ids, r.RunID)
+	}
+
+	assert.Equal(t, []string{"next", "last"}, ids)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestJournal_Append_WithoutRewrite(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), history.FileName)
+
+	// The journal is rewritten only to drop the expired runs, so the foreign line is kept.
+	existing := `{"run_id":"first","end":"2025-03-10T11:00:00Z"}` + "\nbroken\n"
+	require.NoError(t, os.WriteFile(path, []byte(existing), 0o600))
+
+	j := history.New(path, time.Hour, history.WithClock(clock.NewFake(now)))
+
+	require.NoError(t, j.Append(report.Summary{RunID: "second", Start: now, End: now}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), existing))
+
+	runs, err := history.Read(path)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "second", runs[1].RunID)
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), history.FileName)
+
+	runs, err := history.Read(path)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"run_id":"a","status":"success"}`+"\n"+
+		"broken\n"+
+		`{"run_id":"b","status":"error"}`+"\n"), 0o600))
+
+	runs, err = history.Read(path)
+	require.NoError(t, err)
+	assert.Equal(t, []report.Summary{
+		{RunID: "a", Status: report.StatusSuccess},
+		{RunID: "b", Status: report.StatusError},
+	}, runs)
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package history_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/history"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestJournal_Append_Processes(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), history.FileName)
+
+	// The expired run makes the first append rewrite the journal while the others append.
+	require.NoError(t, os.WriteFile(path, []byte(`{"run_id":"expired","end":"2025-03-09T12:00:00Z"}`+"\n"), 0o600))
+
+	const appends = 20
+
+	var wg sync.WaitGroup
+
+	// The journals open the file separately like the processes sharing the state directory.
+	for p := range 2 {
+		j := history.New(path, time.Hour, history.WithClock(clock.NewFake(now)))
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for n := range appends {
+				assert.NoError(t, j.Append(report.Summary{RunID: fmt.Sprintf("%d-%d", p, n), Start: now, End: now}))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	runs, err := history.Read(path)
+	require.NoError(t, err)
+	require.Len(t, runs, 2*appends)
+
+	for _, r := range runs {
+		assert.NotEqual(t, "expired", r.RunID)
+	}
+}
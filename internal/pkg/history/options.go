@@ -0,0 +1,12 @@
+package history
+
+import "github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+
+type Option func(*Journal)
+
+// WithClock sets the clock of the retention, the real one by default.
+func WithClock(c clock.Clock) Option {
+	return func(j *Journal) {
+		j.clock = c
+	}
+}
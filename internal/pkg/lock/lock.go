@@ -0,0 +1,142 @@
+// Package lock guards the sync directory against concurrent processes by an advisory file lock.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the lock file in the sync directory.
+const FileName = ".pbcsync.lock"
+
+// retryInterval is how often the lock is retried while waiting.
+const retryInterval = time.Second
+
+var (
+	ErrLocked = errors.New("directory is locked")
+
+	errBusy = errors.New("lock is busy")
+)
+
+// HeldError reports the process holding the lock.
+type HeldError struct {
+	PID int
+}
+
+func (e HeldError) Error() string {
+	switch {
+	case e.PID == 0:
+		return ErrLocked.Error() + " by another process"
+	case !processAlive(e.PID):
+		return fmt.Sprintf("%s by PID %d, which is not running here, maybe on another host or in another container",
+			ErrLocked, e.PID)
+	}
+
+	return fmt.Sprintf("%s by PID %d", ErrLocked, e.PID)
+}
+
+func (e HeldError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Lock is the acquired lock.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes the lock of the file waiting up to the duration, zero wait fails immediately if the lock is held.
+// The file keeps PID of the holder, the lock of the terminated process is released by the system.
+func Acquire(ctx context.Context, path string, wait time.Duration) (*Lock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(wait)
+
+	for {
+		err = tryLock(f)
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, errBusy) {
+			_ = f.Close()
+
+			return nil, fmt.Errorf("lock: %w", err)
+		}
+
+		if !time.Now().Before(deadline) {
+			pid := readPID(f)
+
+			_ = f.Close()
+
+			return nil, HeldError{PID: pid}
+		}
+
+		slog.Debug("waiting for lock", "path", path, "pid", readPID(f))
+
+		select {
+		case <-ctx.Done():
+			_ = f.Close()
+
+			return nil, fmt.Errorf("wait for lock: %w", ctx.Err())
+		case <-time.After(min(retryInterval, time.Until(deadline))):
+		}
+	}
+
+	if pid := readPID(f); pid != 0 && pid != os.Getpid() {
+		slog.Warn("stale lock is taken over", "path", path, "pid", pid)
+	}
+
+	if err = writePID(f, os.Getpid()); err != nil {
+		_ = f.Close()
+
+		return nil, fmt.Errorf("write pid: %w", err)
+	}
+
+	return &Lock{f: f}, nil
+}
+
+// Release frees the lock. The file is kept, because removing it races with the processes opening it.
+func (l *Lock) Release() error {
+	_ = l.f.Truncate(0)
+
+	if err := l.f.Close(); err != nil {
+		return fmt.Errorf("close lock file: %w", err)
+	}
+
+	return nil
+}
+
+// File blocks until it takes the exclusive lock of the open file, closing the file releases the lock.
+// It guards the files shared by the processes without the lock of the directory, like the journal of the runs.
+func File(f *os.File) error {
+	return flock(f)
+}
+
+func readPID(f *os.File) int {
+	data := make([]byte, 32)
+
+	n, _ := f.ReadAt(data, 0)
+
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data[:n])))
+
+	return pid
+}
+
+func writePID(f *os.File, pid int) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err := f.WriteAt([]byte(strconv.Itoa(pid)+"\n"), 0)
+
+	return err
+}
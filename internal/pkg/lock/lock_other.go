@@ -0,0 +1,19 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// tryLock always succeeds, because the advisory lock is not supported on this platform.
+func tryLock(*os.File) error {
+	return nil
+}
+
+// flock always succeeds, because the advisory lock is not supported on this platform.
+func flock(*os.File) error {
+	return nil
+}
+
+func processAlive(int) bool {
+	return true
+}
@@ -0,0 +1,132 @@
+//go:build unix
+
+package lock_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/lock"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), lock.FileName)
+
+	l, err := lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+
+	_, err = lock.Acquire(t.Context(), path, 0)
+	require.ErrorIs(t, err, lock.ErrLocked)
+	require.EqualError(t, err, "directory is locked by PID "+strconv.Itoa(os.Getpid()))
+
+	require.NoError(t, l.Release())
+
+	l, err = lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_Wait(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), lock.FileName)
+
+	held, err := lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+
+	time.AfterFunc(100*time.Millisecond, func() { _ = held.Release() })
+
+	l, err := lock.Acquire(t.Context(), path, 5*time.Second)
+	require.NoError(t, err)
+	require.NoError(t, l.Release())
+}
+
+func TestAcquire_Wait_Canceled(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), lock.FileName)
+
+	l, err := lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = l.Release() })
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = lock.Acquire(ctx, path, time.Hour)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAcquire_Stale(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), lock.FileName)
+
+	require.NoError(t, os.WriteFile(path, []byte("2147483646\n"), 0o644))
+
+	l, err := lock.Acquire(t.Context(), path, 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid())+"\n", string(data))
+
+	require.NoError(t, l.Release())
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	held, err := os.Create(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.File(held))
+
+	// The file is locked by the other open file until it is closed.
+	_, err = lock.Acquire(t.Context(), path, 0)
+	require.ErrorIs(t, err, lock.ErrLocked)
+
+	closed := make(chan struct{})
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		close(closed)
+
+		_ = held.Close()
+	})
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = f.Close() })
+
+	require.NoError(t, lock.File(f))
+
+	select {
+	case <-closed:
+	default:
+		t.Fatal("the lock is taken before the holder closed the file")
+	}
+}
+
+func TestHeldError(t *testing.T) {
+	t.Parallel()
+
+	assert.EqualError(t, lock.HeldError{PID: 2147483646},
+		"directory is locked by PID 2147483646, which is not running here, maybe on another host or in another container")
+	assert.EqualError(t, lock.HeldError{}, "directory is locked by another process")
+}
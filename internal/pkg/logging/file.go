@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+)
+
+// Rotation limits of the log file, the zero values are unlimited.
+type Rotation struct {
+	// MaxSize is the size in bytes after which the file is rotated.
+	MaxSize int64
+	// MaxAge is the age after which the file is rotated.
+	MaxAge time.Duration
+	// MaxBackups is how many rotated files are kept.
+	MaxBackups int
+}
+
+// backupLayout is the time of the rotation in the names of the rotated files.
+const backupLayout = "20060102T150405.000"
+
+// File is the log file rotated by the size and the age, it is safe for concurrent use.
+// The rotated file is renamed to the name with the time of the rotation, e.g. sync.log.20250224T103000.000 in UTC.
+type File struct {
+	path     string
+	rotation Rotation
+	clock    clock.Clock
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	started time.Time
+}
+
+// OpenFile opens the log file for appending, creating it if necessary.
+// The age of the file is counted from the last rotation, it survives restarts.
+func OpenFile(path string, r Rotation, opts ...FileOption) (*File, error) {
+	f := &File{
+		path:     path,
+		rotation: r,
+		clock:    clock.Real{},
+	}
+
+	for _, o := range opts {
+		o(f)
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	f.started = f.clock.Now()
+
+	if backups, err := f.backups(); err == nil && len(backups) > 0 {
+		f.started = backups[len(backups)-1].rotated
+	}
+
+	return f, nil
+}
+
+// Write writes the record to the file, rotating it before if the record exceeds the limits.
+// The record is written to the current file if the rotation fails, the error of the rotation is returned.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var rerr error
+
+	if f.expired(len(p)) {
+		if rerr = f.rotate(); rerr != nil && f.file == nil {
+			return 0, rerr
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+
+	return n, errors.Join(rerr, err)
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	return f.file.Close()
+}
+
+// expired reports whether the file must be rotated before writing n bytes. The empty file is never rotated.
+func (f *File) expired(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+
+	if f.rotation.MaxSize > 0 && f.size+int64(n) > f.rotation.MaxSize {
+		return true
+	}
+
+	return f.rotation.MaxAge > 0 && f.clock.Now().Sub(f.started) >= f.rotation.MaxAge
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	st, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("stat log file: %w", err)
+	}
+
+	f.file, f.size = file, st.Size()
+
+	return nil
+}
+
+// rotate renames the file and opens the new one. The file is nil only if it can't be opened again.
+func (f *File) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+
+	if err != nil {
+		return errors.Join(fmt.Errorf("close log file: %w", err), f.open())
+	}
+
+	now := f.clock.Now()
+
+	if err := os.Rename(f.path, f.path+"."+now.UTC().Format(backupLayout)); err != nil {
+		// The log goes on to the same file, the rotation is retried by the next record.
+		return errors.Join(fmt.Errorf("rename log file: %w", err), f.open())
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.started = now
+
+	return f.prune()
+}
+
+type backup struct {
+	path    string
+	rotated time.Time
+}
+
+// backups returns the rotated files from the oldest.
+func (f *File) backups() ([]backup, error) {
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("list rotated files: %w", err)
+	}
+
+	var backups []backup
+
+	for _, m := range matches {
+		t, err := time.Parse(backupLayout, strings.TrimPrefix(m, f.path+"."))
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, backup{path: m, rotated: t})
+	}
+
+	slices.SortFunc(backups, func(a, b backup) int { return a.rotated.Compare(b.rotated) })
+
+	return backups, nil
+}
+
+// prune removes the oldest rotated files beyond the limit.
+func (f *File) prune() error {
+	if f.rotation.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > f.rotation.MaxBackups {
+		if err := os.Remove(backups[0].path); err != nil {
+			return fmt.Errorf("remove rotated file: %w", err)
+		}
+
+		backups = backups[1:]
+	}
+
+	return nil
+}
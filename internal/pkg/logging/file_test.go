@@ -0,0 +1,128 @@
+package logging_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+)
+
+func TestFile_MaxSize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sync.log")
+	fake := clock.NewFake(time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC))
+
+	f, err := logging.OpenFile(path, logging.Rotation{MaxSize: 10, MaxBackups: 2}, logging.WithClock(fake))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = f.Close() })
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+
+		fake.Advance(time.Second)
+	}
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+
+	// The oldest rotated file with "first" is removed.
+	second, err := os.ReadFile(path + ".20250224T103002.000")
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(second))
+
+	third, err := os.ReadFile(path + ".20250224T103003.000")
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(third))
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, matches, 2)
+}
+
+func TestFile_RenameError(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sync.log")
+	fake := clock.NewFake(time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC))
+
+	f, err := logging.OpenFile(path, logging.Rotation{MaxSize: 10}, logging.WithClock(fake))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = f.Close() })
+
+	// The non-empty directory in place of the rotated file fails the rename.
+	backup := path + ".20250224T103000.000"
+
+	require.NoError(t, os.MkdirAll(filepath.Join(backup, "busy"), 0o700))
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("second\n"))
+	require.ErrorContains(t, err, "rename log file")
+
+	require.NoError(t, os.RemoveAll(backup))
+
+	fake.Advance(time.Second)
+
+	_, err = f.Write([]byte("third\n"))
+	require.NoError(t, err)
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(current))
+
+	rotated, err := os.ReadFile(path + ".20250224T103001.000")
+	require.NoError(t, err)
+	assert.Equal(t, "first\nsecond\n", string(rotated))
+}
+
+func TestFile_MaxAge(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "sync.log")
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	rotation := logging.Rotation{MaxAge: 24 * time.Hour}
+
+	f, err := logging.OpenFile(path, rotation, logging.WithClock(fake))
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	fake.Advance(24 * time.Hour)
+
+	_, err = f.Write([]byte("second\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	rotated, err := os.ReadFile(path + ".20250225T103000.000")
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(rotated))
+
+	// The age is counted from the last rotation after reopening.
+	fake.Advance(24 * time.Hour)
+
+	f, err = logging.OpenFile(path, rotation, logging.WithClock(fake))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = f.Close() })
+
+	_, err = f.Write([]byte("third\n"))
+	require.NoError(t, err)
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(current))
+}
@@ -0,0 +1,176 @@
+// Package logging configures the default logger: the format, the level, the output file with rotation,
+// and the attributes carried by the context.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+)
+
+// Format of log records.
+type Format string
+
+const (
+	// FormatText is the human-readable format of the standard logger: time, level, message and key=value attributes.
+	FormatText Format = "text"
+	// FormatJSON is a JSON object per line.
+	FormatJSON Format = "json"
+	// FormatLogfmt is key=value pairs including time, level and message.
+	FormatLogfmt Format = "logfmt"
+)
+
+var errUnknownFormat = errors.New("unknown format")
+
+// ParseFormat parses the format by its name, the empty name is [FormatText].
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatJSON, FormatLogfmt:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w %q", errUnknownFormat, s)
+	}
+}
+
+// ParseLevel parses the level by its name: debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("parse level: %w", err)
+	}
+
+	return l, nil
+}
+
+// Keys of the attributes used across the packages.
+const (
+	KeyRunID    = "run_id"
+	KeyBook     = "book"
+	KeyProvider = "provider"
+	KeyBytes    = "bytes"
+	KeyDuration = "duration"
+)
+
+// RunID is the attribute of the synchronization run.
+func RunID(id string) slog.Attr {
+	return slog.String(KeyRunID, id)
+}
+
+// Book is the attribute of the book by its file name.
+func Book(name string) slog.Attr {
+	return slog.String(KeyBook, name)
+}
+
+// Provider is the attribute of the provider by its shop ID.
+func Provider(shopID string) slog.Attr {
+	return slog.String(KeyProvider, shopID)
+}
+
+func Bytes(n int64) slog.Attr {
+	return slog.Int64(KeyBytes, n)
+}
+
+func Duration(d time.Duration) slog.Attr {
+	return slog.Duration(KeyDuration, d)
+}
+
+type attrsKey struct{}
+
+// WithAttrs returns the context carrying the attributes added to every record logged with it.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+
+	return context.WithValue(ctx, attrsKey{}, append(slices.Clip(prev), attrs...))
+}
+
+// contextHandler adds the attributes of the context to the records.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr); len(attrs) > 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// NewHandler returns the handler writing the records in the format.
+// The records get the attributes of the context set by [WithAttrs] and have the secrets masked by [redact.NewHandler].
+func NewHandler(w io.Writer, f Format, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var h slog.Handler
+
+	switch f {
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FormatLogfmt:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		h = newTextHandler(w, opts)
+	}
+
+	return contextHandler{redact.NewHandler(h)}
+}
+
+// Config of the default logger.
+type Config struct {
+	Format Format
+	Level  slog.Level
+	// Output is the writer of the log without the file, the standard output by default.
+	Output io.Writer
+	// File is the path of the log file, the empty path is the output.
+	File     string
+	Rotation Rotation
+}
+
+var level slog.LevelVar
+
+// Setup replaces the default logger. The log file stays open until the process exits,
+// so the records logged right before the exit are not lost.
+func Setup(cfg Config) error {
+	w := cfg.Output
+	if w == nil {
+		w = os.Stdout
+	}
+
+	if cfg.File != "" {
+		f, err := OpenFile(cfg.File, cfg.Rotation)
+		if err != nil {
+			return err
+		}
+
+		w = f
+	}
+
+	level.Set(cfg.Level)
+	slog.SetDefault(slog.New(NewHandler(w, cfg.Format, &level)))
+
+	return nil
+}
+
+// SetLevel changes the level of the default logger set by [Setup].
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
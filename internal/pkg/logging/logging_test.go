@@ -0,0 +1,104 @@
+package logging_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+)
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		expected logging.Format
+		err      string
+	}{
+		{name: "", expected: logging.FormatText},
+		{name: "text", expected: logging.FormatText},
+		{name: "json", expected: logging.FormatJSON},
+		{name: "logfmt", expected: logging.FormatLogfmt},
+		{name: "xml", err: `unknown format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := logging.ParseFormat(tt.name)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, f)
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	l, err := logging.ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+
+	_, err = logging.ParseLevel("loud")
+	require.Error(t, err)
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		format   logging.Format
+		expected string
+	}{
+		{
+			format:   logging.FormatText,
+			expected: "2025/02/24 10:30:00 INFO download book=first.txt bytes=42 run_id=abc\n2025/02/24 10:30:00 WARN finished\n",
+		},
+		{
+			format: logging.FormatJSON,
+			expected: `{"time":"2025-02-24T10:30:00Z","level":"INFO","msg":"download","book":"first.txt","bytes":42,"run_id":"abc"}` + "\n" +
+				`{"time":"2025-02-24T10:30:00Z","level":"WARN","msg":"finished"}` + "\n",
+		},
+		{
+			format: logging.FormatLogfmt,
+			expected: "time=2025-02-24T10:30:00.000Z level=INFO msg=download book=first.txt bytes=42 run_id=abc\n" +
+				"time=2025-02-24T10:30:00.000Z level=WARN msg=finished\n",
+		},
+	}
+
+	now := time.Date(2025, time.February, 24, 10, 30, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			h := logging.NewHandler(&buf, tt.format, slog.LevelInfo)
+			ctx := logging.WithAttrs(t.Context(), logging.RunID("abc"))
+
+			debug := slog.NewRecord(now, slog.LevelDebug, "skipped", 0)
+			require.False(t, h.Enabled(ctx, debug.Level))
+
+			r := slog.NewRecord(now, slog.LevelInfo, "download", 0)
+			r.AddAttrs(logging.Book("first.txt"), logging.Bytes(42))
+			require.NoError(t, h.Handle(ctx, r))
+
+			require.NoError(t, h.Handle(t.Context(), slog.NewRecord(now, slog.LevelWarn, "finished", 0)))
+
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package logging
+
+import "github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+
+type FileOption func(*File)
+
+// WithClock sets the clock of the rotation by the age.
+func WithClock(clk clock.Clock) FileOption {
+	return func(f *File) {
+		f.clock = clk
+	}
+}
@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// textHandler writes the records like the standard logger: "2006/01/02 15:04:05 INFO message key=value".
+// The attributes are formatted by [slog.TextHandler] and prefixed by the time, the level and the message.
+type textHandler struct {
+	inner slog.Handler
+	out   *prefixWriter
+}
+
+func newTextHandler(w io.Writer, opts *slog.HandlerOptions) *textHandler {
+	out := &prefixWriter{w: w}
+
+	inner := slog.NewTextHandler(out, &slog.HandlerOptions{
+		Level: opts.Level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 {
+				switch a.Key {
+				case slog.TimeKey, slog.LevelKey, slog.MessageKey:
+					return slog.Attr{}
+				}
+			}
+
+			return a
+		},
+	})
+
+	return &textHandler{inner: inner, out: out}
+}
+
+func (h *textHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *textHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+
+	h.out.prefix = h.out.prefix[:0]
+
+	if !r.Time.IsZero() {
+		h.out.prefix = r.Time.AppendFormat(h.out.prefix, "2006/01/02 15:04:05 ")
+	}
+
+	h.out.prefix = append(h.out.prefix, r.Level.String()...)
+	h.out.prefix = append(h.out.prefix, ' ')
+	h.out.prefix = append(h.out.prefix, r.Message...)
+
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &textHandler{inner: h.inner.WithAttrs(attrs), out: h.out}
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	return &textHandler{inner: h.inner.WithGroup(name), out: h.out}
+}
+
+// prefixWriter writes the prefix before the line of the attributes.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	line := make([]byte, 0, len(w.prefix)+len(p)+1)
+	line = append(line, w.prefix...)
+
+	// The line without attributes is only the newline.
+	if len(p) > 1 {
+		line = append(line, ' ')
+	}
+
+	line = append(line, p...)
+
+	if _, err := w.w.Write(line); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
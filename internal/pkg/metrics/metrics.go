@@ -0,0 +1,131 @@
+// Package metrics exposes statistics of synchronizations in the Prometheus text format.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+const namespace = "pbcsync_"
+
+// durationBuckets are upper bounds of durations in seconds.
+var durationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// Default is the metrics of the process.
+var Default = New()
+
+// Metrics of synchronizations, nil records nothing.
+type Metrics struct {
+	registry *Registry
+
+	runs             Counter
+	lastSuccess      Gauge
+	booksListed      Counter
+	booksSkipped     Counter
+	booksDownloaded  Counter
+	booksFailed      Counter
+	downloadedBytes  Counter
+	downloadDuration Histogram
+	listingDuration  Histogram
+	apiErrors        Counter
+}
+
+func New() *Metrics {
+	r := &Registry{}
+
+	m := &Metrics{
+		registry: r,
+		runs: r.Counter(namespace+"runs_total",
+			"Synchronization runs by result, the runs stopped by the shutdown are not counted.", "result"),
+		lastSuccess: r.Gauge(namespace+"last_success_timestamp_seconds",
+			"Unix time of the end of the last successful synchronization."),
+		booksListed: r.Counter(namespace+"books_listed_total",
+			"Books found in the cloud."),
+		booksSkipped: r.Counter(namespace+"books_skipped_total",
+			"Books skipped because they exist in the directory."),
+		booksDownloaded: r.Counter(namespace+"books_downloaded_total",
+			"Books downloaded successfully."),
+		booksFailed: r.Counter(namespace+"books_failed_total",
+			"Books failed to download."),
+		downloadedBytes: r.Counter(namespace+"downloaded_bytes_total",
+			"Bytes of downloaded books."),
+		downloadDuration: r.Histogram(namespace+"download_duration_seconds",
+			"Duration of book downloads.", durationBuckets),
+		listingDuration: r.Histogram(namespace+"listing_duration_seconds",
+			"Duration of listing books of all providers.", durationBuckets),
+		apiErrors: r.Counter(namespace+"api_errors_total",
+			"Errors of PocketBook Cloud API by HTTP status code.", "code"),
+	}
+
+	m.runs.Add(0, report.StatusSuccess)
+	m.runs.Add(0, report.StatusError)
+
+	return m
+}
+
+// Registry returns the registry to serve or write the metrics.
+func (m *Metrics) Registry() *Registry {
+	return m.registry
+}
+
+// RunFinished counts the run ended at the time.
+func (m *Metrics) RunFinished(end time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	if err != nil {
+		m.runs.Inc(report.StatusError)
+
+		return
+	}
+
+	m.runs.Inc(report.StatusSuccess)
+	m.lastSuccess.Set(float64(end.UnixMilli()) / 1000)
+}
+
+func (m *Metrics) BooksListed(n int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.booksListed.Add(float64(n))
+	m.listingDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) BookSkipped() {
+	if m == nil {
+		return
+	}
+
+	m.booksSkipped.Inc()
+}
+
+func (m *Metrics) BookDownloaded(bytes int64, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.booksDownloaded.Inc()
+	m.downloadedBytes.Add(float64(bytes))
+	m.downloadDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) BookFailed() {
+	if m == nil {
+		return
+	}
+
+	m.booksFailed.Inc()
+}
+
+// APIError counts the error response of the API.
+func (m *Metrics) APIError(code int) {
+	if m == nil {
+		return
+	}
+
+	m.apiErrors.Inc(strconv.Itoa(code))
+}
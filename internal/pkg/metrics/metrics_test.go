@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New()
+
+	m.RunFinished(time.Unix(1740393000, 0), nil)
+	m.RunFinished(time.Unix(1740396600, 0), errors.New("some error"))
+	m.BooksListed(3, 2*time.Second)
+	m.BookSkipped()
+	m.BookDownloaded(1024, 300*time.Millisecond)
+	m.BookFailed()
+	m.APIError(503)
+
+	var b strings.Builder
+
+	_, err := m.Registry().WriteTo(&b)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`pbcsync_runs_total{result="error"} 1`,
+		`pbcsync_runs_total{result="success"} 1`,
+		`pbcsync_last_success_timestamp_seconds 1.740393e+09`,
+		`pbcsync_books_listed_total 3`,
+		`pbcsync_books_skipped_total 1`,
+		`pbcsync_books_downloaded_total 1`,
+		`pbcsync_books_failed_total 1`,
+		`pbcsync_downloaded_bytes_total 1024`,
+		`pbcsync_download_duration_seconds_bucket{le="0.5"} 1`,
+		`pbcsync_listing_duration_seconds_bucket{le="1"} 0`,
+		`pbcsync_listing_duration_seconds_bucket{le="2.5"} 1`,
+		`pbcsync_api_errors_total{code="503"} 1`,
+	} {
+		assert.Contains(t, b.String(), line+"\n")
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	t.Parallel()
+
+	var m *metrics.Metrics
+
+	assert.NotPanics(t, func() {
+		m.RunFinished(time.Now(), nil)
+		m.BooksListed(3, time.Second)
+		m.BookSkipped()
+		m.BookDownloaded(1024, time.Second)
+		m.BookFailed()
+		m.APIError(503)
+	})
+}
@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Registry keeps the metrics and writes them in the Prometheus text format.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labels []string
+	value  float64
+	// counts of the histogram by buckets, the last one is +Inf.
+	counts []uint64
+}
+
+func (r *Registry) register(f *family) *family {
+	f.series = make(map[string]*series)
+
+	if len(f.labels) == 0 {
+		f.get(nil)
+	}
+
+	r.mu.Lock()
+	r.families = append(r.families, f)
+	r.mu.Unlock()
+
+	return f
+}
+
+func (f *family) get(labels []string) *series {
+	if len(labels) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s: expected %d label values, got %d", f.name, len(f.labels), len(labels)))
+	}
+
+	key := strings.Join(labels, "\xff")
+
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: slices.Clone(labels)}
+
+		if f.typ == typeHistogram {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+
+		f.series[key] = s
+	}
+
+	return s
+}
+
+// Counter is the metric which only increases.
+type Counter struct{ f *family }
+
+func (r *Registry) Counter(name, help string, labels ...string) Counter {
+	return Counter{r.register(&family{name: name, help: help, typ: typeCounter, labels: labels})}
+}
+
+// Add increases the counter of the label values.
+func (c Counter) Add(v float64, labels ...string) {
+	c.f.mu.Lock()
+	c.f.get(labels).value += v
+	c.f.mu.Unlock()
+}
+
+func (c Counter) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+// Gauge is the metric which is set to arbitrary values.
+type Gauge struct{ f *family }
+
+func (r *Registry) Gauge(name, help string, labels ...string) Gauge {
+	return Gauge{r.register(&family{name: name, help: help, typ: typeGauge, labels: labels})}
+}
+
+func (g Gauge) Set(v float64, labels ...string) {
+	g.f.mu.Lock()
+	g.f.get(labels).value = v
+	g.f.mu.Unlock()
+}
+
+// Histogram counts observations by buckets.
+type Histogram struct{ f *family }
+
+// Histogram registers the histogram, the buckets are upper bounds in increasing order.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) Histogram {
+	return Histogram{r.register(&family{name: name, help: help, typ: typeHistogram, labels: labels, buckets: buckets})}
+}
+
+func (h Histogram) Observe(v float64, labels ...string) {
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+
+	s := h.f.get(labels)
+	s.value += v
+
+	i, _ := slices.BinarySearch(h.f.buckets, v)
+	s.counts[i]++
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		f.write(cw)
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+
+	return cw.n, cw.err
+}
+
+// Handler serves the metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// WriteFile writes the metrics to the file atomically, as the textfile collector of node_exporter requires.
+func (r *Registry) WriteFile(path string) error {
+	var b strings.Builder
+
+	if _, err := r.WriteTo(&b); err != nil {
+		return fmt.Errorf("write metrics: %w", err)
+	}
+
+	if err := statefile.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+func (f *family) write(w *countWriter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.printf("# HELP %s %s\n", f.name, f.help)
+	w.printf("# TYPE %s %s\n", f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		s := f.series[k]
+
+		if f.typ != typeHistogram {
+			w.printf("%s%s %s\n", f.name, f.labelPairs(s.labels), formatFloat(s.value))
+
+			continue
+		}
+
+		var cumulative uint64
+
+		for i, c := range s.counts {
+			cumulative += c
+
+			le := math.Inf(1)
+			if i < len(f.buckets) {
+				le = f.buckets[i]
+			}
+
+			w.printf("%s_bucket%s %d\n", f.name, f.labelPairs(s.labels, "le", formatFloat(le)), cumulative)
+		}
+
+		w.printf("%s_sum%s %s\n", f.name, f.labelPairs(s.labels), formatFloat(s.value))
+		w.printf("%s_count%s %d\n", f.name, f.labelPairs(s.labels), cumulative)
+	}
+}
+
+// labelPairs formats the label values with the extra name and value pairs.
+func (f *family) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+
+	for i, v := range values {
+		pairs = append(pairs, f.labels[i]+"="+strconv.Quote(v))
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) printf(format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
@@ -0,0 +1,86 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	t.Parallel()
+
+	r := &metrics.Registry{}
+
+	c := r.Counter("test_total", "Test counter.", "code")
+	c.Inc("500")
+	c.Add(2, "401")
+	c.Inc("500")
+
+	g := r.Gauge("test_gauge", "Test gauge.")
+	g.Set(1.5)
+
+	h := r.Histogram("test_seconds", "Test histogram.", []float64{0.5, 1})
+	h.Observe(0.25)
+	h.Observe(1)
+	h.Observe(3)
+
+	const expected = `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{code="401"} 2
+test_total{code="500"} 2
+# HELP test_gauge Test gauge.
+# TYPE test_gauge gauge
+test_gauge 1.5
+# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.5"} 1
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="+Inf"} 3
+test_seconds_sum 4.25
+test_seconds_count 3
+`
+
+	var b strings.Builder
+
+	n, err := r.WriteTo(&b)
+	require.NoError(t, err)
+	assert.Equal(t, expected, b.String())
+	assert.Equal(t, int64(len(expected)), n)
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	t.Parallel()
+
+	r := &metrics.Registry{}
+	r.Counter("test_total", "Test counter.", "path").Inc(`a"b`)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, rec.Body.String(), `test_total{path="a\"b"} 1`)
+}
+
+func TestRegistry_WriteFile(t *testing.T) {
+	t.Parallel()
+
+	r := &metrics.Registry{}
+	r.Gauge("test_gauge", "Test gauge.").Set(1)
+
+	path := filepath.Join(t.TempDir(), "pbcsync.prom")
+
+	require.NoError(t, r.WriteFile(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "test_gauge 1\n")
+}
@@ -0,0 +1,251 @@
+// Package mqtt is a minimal MQTT 3.1.1 client publishing messages.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// DefaultKeepAlive is the keep alive interval sent to the broker.
+// The client doesn't ping, so a connection idle for longer is closed by the broker.
+const DefaultKeepAlive = time.Minute
+
+var (
+	errUnknownScheme = errors.New("unknown scheme")
+	errRefused       = errors.New("connection refused")
+	errUnexpected    = errors.New("unexpected packet")
+	errInvalidQoS    = errors.New("invalid QoS")
+)
+
+// Config of the connection.
+type Config struct {
+	// Broker is the URL of the broker: tcp://host:1883, or ssl://, tls:// and mqtts:// for TLS, default port 8883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// TLS is the configuration of TLS connections, by default the system roots are trusted.
+	TLS *tls.Config
+}
+
+// ParseBroker returns the address of the broker and whether it uses TLS.
+func ParseBroker(broker string) (addr string, secure bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, fmt.Errorf("parse broker: %w", err)
+	}
+
+	port := "1883"
+
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		secure, port = true, "8883"
+	default:
+		return "", false, fmt.Errorf("%w %q", errUnknownScheme, u.Scheme)
+	}
+
+	if u.Port() != "" {
+		port = u.Port()
+	}
+
+	return net.JoinHostPort(u.Hostname(), port), secure, nil
+}
+
+// Conn is the connection to the broker, it is not safe for concurrent use.
+type Conn struct {
+	conn   net.Conn
+	nextID uint16
+}
+
+// Dial connects to the broker with a clean session. The deadline of the context applies to the whole connection.
+func Dial(ctx context.Context, cfg Config) (*Conn, error) {
+	addr, secure, err := ParseBroker(cfg.Broker)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if secure {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.TLS != nil {
+			tlsConfig = cfg.TLS.Clone()
+		}
+
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c := &Conn{conn: conn}
+
+	if err = c.connect(cfg); err != nil {
+		_ = conn.Close()
+
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Conn) connect(cfg Config) error {
+	const cleanSession = 0x02
+
+	flags := byte(cleanSession)
+
+	if cfg.Username != "" {
+		flags |= 0x80
+	}
+
+	if cfg.Password != "" {
+		flags |= 0x40
+	}
+
+	body := AppendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(DefaultKeepAlive/time.Second))
+	body = AppendString(body, cfg.ClientID)
+
+	if cfg.Username != "" {
+		body = AppendString(body, cfg.Username)
+	}
+
+	if cfg.Password != "" {
+		body = AppendString(body, cfg.Password)
+	}
+
+	if err := WritePacket(c.conn, Packet{Type: TypeConnect, Body: body}); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	p, err := c.expect(TypeConnAck)
+	if err != nil {
+		return fmt.Errorf("connack: %w", err)
+	}
+
+	if len(p.Body) != 2 {
+		return fmt.Errorf("connack: %w", errMalformed)
+	}
+
+	if code := p.Body[1]; code != 0 {
+		return fmt.Errorf("%w: %s", errRefused, refusedReason(code))
+	}
+
+	return nil
+}
+
+func refusedReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("code %d", code)
+	}
+}
+
+// Publish sends the message and waits for its acknowledgement if QoS is 1 or 2.
+func (c *Conn) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	if qos > 2 {
+		return fmt.Errorf("%w %d", errInvalidQoS, qos)
+	}
+
+	flags := qos << 1
+	if retain {
+		flags |= 1
+	}
+
+	body := AppendString(nil, topic)
+
+	var id uint16
+
+	if qos > 0 {
+		c.nextID++
+		if c.nextID == 0 {
+			c.nextID = 1
+		}
+
+		id = c.nextID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+
+	body = append(body, payload...)
+
+	if err := WritePacket(c.conn, Packet{Type: TypePublish, Flags: flags, Body: body}); err != nil {
+		return fmt.Errorf("publish %s: %w", topic, err)
+	}
+
+	switch qos {
+	case 1:
+		return c.ack(TypePubAck, id)
+	case 2:
+		if err := c.ack(TypePubRec, id); err != nil {
+			return err
+		}
+
+		rel := Packet{Type: TypePubRel, Flags: 0x02, Body: binary.BigEndian.AppendUint16(nil, id)}
+		if err := WritePacket(c.conn, rel); err != nil {
+			return fmt.Errorf("pubrel: %w", err)
+		}
+
+		return c.ack(TypePubComp, id)
+	}
+
+	return nil
+}
+
+func (c *Conn) ack(typ byte, id uint16) error {
+	p, err := c.expect(typ)
+	if err != nil {
+		return fmt.Errorf("acknowledge %d: %w", id, err)
+	}
+
+	if len(p.Body) != 2 || binary.BigEndian.Uint16(p.Body) != id {
+		return fmt.Errorf("acknowledge %d: %w", id, errMalformed)
+	}
+
+	return nil
+}
+
+func (c *Conn) expect(typ byte) (Packet, error) {
+	p, err := ReadPacket(c.conn)
+	if err != nil {
+		return Packet{}, err
+	}
+
+	if p.Type != typ {
+		return Packet{}, fmt.Errorf("%w %d", errUnexpected, p.Type)
+	}
+
+	return p, nil
+}
+
+// Close disconnects from the broker.
+func (c *Conn) Close() error {
+	err := WritePacket(c.conn, Packet{Type: TypeDisconnect})
+
+	return errors.Join(err, c.conn.Close())
+}
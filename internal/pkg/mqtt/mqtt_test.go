@@ -0,0 +1,123 @@
+package mqtt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt/mqtttest"
+)
+
+func TestConn_Publish(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name string
+		tls  bool
+		qos  byte
+	}{
+		{name: "qos 0", qos: 0},
+		{name: "qos 1", qos: 1},
+		{name: "qos 2", qos: 2},
+		{name: "tls", tls: true, qos: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			broker := mqtttest.NewBroker()
+			if tt.tls {
+				broker = mqtttest.NewTLSBroker()
+			}
+
+			broker.Username, broker.Password = "user", "secret"
+
+			conn, err := mqtt.Dial(t.Context(), mqtt.Config{
+				Broker:   broker.URL,
+				ClientID: "pbcsync",
+				Username: "user",
+				Password: "secret",
+				TLS:      broker.ClientTLS(),
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, conn.Publish("pbcsync/state", []byte(`{"status":"success"}`), tt.qos, true))
+			require.NoError(t, conn.Publish("pbcsync/book", []byte("first.epub"), tt.qos, false))
+			require.NoError(t, conn.Close())
+
+			broker.Close()
+
+			assert.Equal(t, []mqtttest.Message{
+				{ClientID: "pbcsync", Topic: "pbcsync/state", Payload: `{"status":"success"}`, QoS: tt.qos, Retain: true},
+				{ClientID: "pbcsync", Topic: "pbcsync/book", Payload: "first.epub", QoS: tt.qos},
+			}, broker.Messages())
+		})
+	}
+}
+
+func TestDial_Refused(t *testing.T) {
+	t.Parallel()
+
+	broker := mqtttest.NewBroker()
+	broker.Username, broker.Password = "user", "secret"
+
+	t.Cleanup(broker.Close)
+
+	_, err := mqtt.Dial(t.Context(), mqtt.Config{Broker: broker.URL, Username: "user", Password: "wrong"})
+	require.EqualError(t, err, "connection refused: bad user name or password")
+}
+
+func TestParseBroker(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		broker  string
+		addr    string
+		secure  bool
+		wantErr bool
+	}{
+		{broker: "tcp://broker.lan", addr: "broker.lan:1883"},
+		{broker: "mqtt://broker.lan:1884", addr: "broker.lan:1884"},
+		{broker: "ssl://broker.lan", addr: "broker.lan:8883", secure: true},
+		{broker: "mqtts://10.0.0.2:8884", addr: "10.0.0.2:8884", secure: true},
+		{broker: "http://broker.lan", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.broker, func(t *testing.T) {
+			t.Parallel()
+
+			addr, secure, err := mqtt.ParseBroker(tt.broker)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.addr, addr)
+			assert.Equal(t, tt.secure, secure)
+		})
+	}
+}
+
+func TestPacket(t *testing.T) {
+	t.Parallel()
+
+	// The body of 200 bytes needs two bytes of the remaining length.
+	body := []byte(strings.Repeat("x", 200))
+
+	var buf bytes.Buffer
+
+	require.NoError(t, mqtt.WritePacket(&buf, mqtt.Packet{Type: mqtt.TypePublish, Flags: 0x03, Body: body}))
+	assert.Equal(t, []byte{0x33, 0xc8, 0x01}, buf.Bytes()[:3])
+
+	p, err := mqtt.ReadPacket(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, mqtt.Packet{Type: mqtt.TypePublish, Flags: 0x03, Body: body}, p)
+}
@@ -0,0 +1,260 @@
+// Package mqtttest provides an in-process MQTT broker stand-in for tests.
+package mqtttest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt"
+)
+
+// Message is a published message received by the broker.
+type Message struct {
+	ClientID string
+	Topic    string
+	Payload  string
+	QoS      byte
+	Retain   bool
+}
+
+// Broker accepts connections and records the published messages.
+// The messages are not delivered to anybody, retained ones are kept by their topic.
+type Broker struct {
+	// URL of the broker, e.g. tcp://127.0.0.1:50000.
+	URL string
+	// Username and Password are required from clients if set.
+	Username string
+	Password string
+
+	listener  net.Listener
+	clientTLS *tls.Config
+	wg        sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	retained map[string]Message
+}
+
+// NewBroker starts the broker accepting plain connections.
+func NewBroker() *Broker {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mqtttest: failed to listen: " + err.Error())
+	}
+
+	return start(l, "tcp://")
+}
+
+// NewTLSBroker starts the broker accepting TLS connections, see [Broker.ClientTLS].
+func NewTLSBroker() *Broker {
+	// The test server of net/http provides the certificate for 127.0.0.1.
+	certSrv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	certSrv.StartTLS()
+	certSrv.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: certSrv.TLS.Certificates,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		panic("mqtttest: failed to listen: " + err.Error())
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certSrv.Certificate())
+
+	b := start(l, "ssl://")
+	b.clientTLS = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+
+	return b
+}
+
+func start(l net.Listener, scheme string) *Broker {
+	b := &Broker{
+		URL:      scheme + l.Addr().String(),
+		listener: l,
+		retained: map[string]Message{},
+	}
+
+	b.wg.Add(1)
+
+	go func() {
+		defer b.wg.Done()
+
+		b.serve()
+	}()
+
+	return b
+}
+
+// ClientTLS returns the client configuration trusting the certificate of the TLS broker.
+func (b *Broker) ClientTLS() *tls.Config {
+	return b.clientTLS
+}
+
+// Close stops the broker and waits for the connected clients to disconnect,
+// so all messages of the closed connections are recorded.
+func (b *Broker) Close() {
+	_ = b.listener.Close()
+	b.wg.Wait()
+}
+
+// Messages returns all published messages.
+func (b *Broker) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.messages...)
+}
+
+// Retained returns the retained message of the topic.
+func (b *Broker) Retained(topic string) (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m, ok := b.retained[topic]
+
+	return m, ok
+}
+
+func (b *Broker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		b.wg.Add(1)
+
+		go func() {
+			defer b.wg.Done()
+
+			b.handle(conn)
+		}()
+	}
+}
+
+func (b *Broker) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	clientID, ok := b.connect(conn)
+	if !ok {
+		return
+	}
+
+	for {
+		p, err := mqtt.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch p.Type {
+		case mqtt.TypePublish:
+			id, ok := b.publish(clientID, p)
+			if !ok {
+				return
+			}
+
+			switch qos := p.Flags >> 1 & 0x03; qos {
+			case 1:
+				_ = mqtt.WritePacket(conn, mqtt.Packet{Type: mqtt.TypePubAck, Body: id})
+			case 2:
+				_ = mqtt.WritePacket(conn, mqtt.Packet{Type: mqtt.TypePubRec, Body: id})
+			}
+		case mqtt.TypePubRel:
+			_ = mqtt.WritePacket(conn, mqtt.Packet{Type: mqtt.TypePubComp, Body: p.Body})
+		default:
+			return
+		}
+	}
+}
+
+// connect handles the CONNECT packet and returns the client ID, false if the client is refused.
+func (b *Broker) connect(conn net.Conn) (string, bool) {
+	p, err := mqtt.ReadPacket(conn)
+	if err != nil || p.Type != mqtt.TypeConnect {
+		return "", false
+	}
+
+	_, rest, err := mqtt.ReadString(p.Body)
+	if err != nil || len(rest) < 4 {
+		return "", false
+	}
+
+	flags := rest[1]
+
+	clientID, rest, err := mqtt.ReadString(rest[4:])
+	if err != nil {
+		return "", false
+	}
+
+	var username, password string
+
+	if flags&0x80 != 0 {
+		if username, rest, err = mqtt.ReadString(rest); err != nil {
+			return "", false
+		}
+	}
+
+	if flags&0x40 != 0 {
+		if password, _, err = mqtt.ReadString(rest); err != nil {
+			return "", false
+		}
+	}
+
+	const badCredentials = 4
+
+	if b.Username != "" && (username != b.Username || password != b.Password) {
+		_ = mqtt.WritePacket(conn, mqtt.Packet{Type: mqtt.TypeConnAck, Body: []byte{0, badCredentials}})
+
+		return "", false
+	}
+
+	return clientID, mqtt.WritePacket(conn, mqtt.Packet{Type: mqtt.TypeConnAck, Body: []byte{0, 0}}) == nil
+}
+
+// publish records the message and returns its packet ID.
+func (b *Broker) publish(clientID string, p mqtt.Packet) ([]byte, bool) {
+	topic, rest, err := mqtt.ReadString(p.Body)
+	if err != nil {
+		return nil, false
+	}
+
+	m := Message{
+		ClientID: clientID,
+		Topic:    topic,
+		QoS:      p.Flags >> 1 & 0x03,
+		Retain:   p.Flags&1 != 0,
+	}
+
+	var id []byte
+
+	if m.QoS > 0 {
+		if len(rest) < 2 {
+			return nil, false
+		}
+
+		id = binary.BigEndian.AppendUint16(nil, binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+	}
+
+	m.Payload = string(rest)
+
+	b.mu.Lock()
+	b.messages = append(b.messages, m)
+
+	switch {
+	case m.Retain && m.Payload == "":
+		delete(b.retained, m.Topic)
+	case m.Retain:
+		b.retained[m.Topic] = m
+	}
+	b.mu.Unlock()
+
+	return id, true
+}
@@ -0,0 +1,126 @@
+package mqtt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Types of MQTT 3.1.1 control packets.
+const (
+	TypeConnect    byte = 1
+	TypeConnAck    byte = 2
+	TypePublish    byte = 3
+	TypePubAck     byte = 4
+	TypePubRec     byte = 5
+	TypePubRel     byte = 6
+	TypePubComp    byte = 7
+	TypeDisconnect byte = 14
+)
+
+// maxRemainingLength is the limit of the variable length encoding.
+const maxRemainingLength = 268_435_455
+
+var (
+	errMalformed = errors.New("malformed packet")
+	errTooLarge  = errors.New("packet too large")
+)
+
+// Packet is an MQTT control packet, Flags are the lower bits of the fixed header.
+type Packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// ReadPacket reads the packet from r.
+func ReadPacket(r io.Reader) (Packet, error) {
+	var b [1]byte
+
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return Packet{}, fmt.Errorf("read header: %w", err)
+	}
+
+	p := Packet{Type: b[0] >> 4, Flags: b[0] & 0x0f}
+
+	var length, shift int
+
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return Packet{}, fmt.Errorf("read length: %w", err)
+		}
+
+		length |= int(b[0]&0x7f) << shift
+
+		if b[0]&0x80 == 0 {
+			break
+		}
+
+		if shift += 7; shift > 21 {
+			return Packet{}, fmt.Errorf("%w: length", errMalformed)
+		}
+	}
+
+	p.Body = make([]byte, length)
+
+	if _, err := io.ReadFull(r, p.Body); err != nil {
+		return Packet{}, fmt.Errorf("read body: %w", err)
+	}
+
+	return p, nil
+}
+
+// WritePacket writes the packet to w.
+func WritePacket(w io.Writer, p Packet) error {
+	if len(p.Body) > maxRemainingLength {
+		return errTooLarge
+	}
+
+	buf := make([]byte, 0, len(p.Body)+5)
+	buf = append(buf, p.Type<<4|p.Flags&0x0f)
+
+	for n := len(p.Body); ; {
+		b := byte(n & 0x7f)
+
+		if n >>= 7; n > 0 {
+			buf = append(buf, b|0x80)
+
+			continue
+		}
+
+		buf = append(buf, b)
+
+		break
+	}
+
+	buf = append(buf, p.Body...)
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("write packet: %w", err)
+	}
+
+	return nil
+}
+
+// AppendString appends the string with its length prefix.
+func AppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+
+	return append(b, s...)
+}
+
+// ReadString reads the string with its length prefix, it returns the rest of the data.
+func ReadString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errMalformed
+	}
+
+	n := int(binary.BigEndian.Uint16(b))
+
+	if len(b) < 2+n {
+		return "", nil, errMalformed
+	}
+
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
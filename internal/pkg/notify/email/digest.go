@@ -0,0 +1,42 @@
+package email
+
+import (
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+// Digest summarizes the runs since the previous email, it is the data of the [Template].
+type Digest struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Runs is how many runs are summarized.
+	Runs int `json:"runs"`
+	// Total is how many books are in the cloud by the last run which has listed them.
+	Total    int       `json:"total"`
+	NewBooks []string  `json:"new_books"`
+	Failures []Failure `json:"failures"`
+}
+
+type Failure struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+func (d *Digest) add(run report.Run) {
+	if d.Runs == 0 {
+		d.Since = run.Start
+	}
+
+	d.Runs++
+	d.Until = run.End
+	d.NewBooks = append(d.NewBooks, run.NewBooks...)
+
+	if run.Total > 0 {
+		d.Total = run.Total
+	}
+
+	if run.Err != nil {
+		d.Failures = append(d.Failures, Failure{Time: run.End, Error: run.Err.Error()})
+	}
+}
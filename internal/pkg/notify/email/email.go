@@ -0,0 +1,358 @@
+// Package email sends the reports of synchronization runs by SMTP, per run or as a periodic digest.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+// DefaultTimeout limits the delivery of an email.
+const DefaultTimeout = 30 * time.Second
+
+// Security of the connection to the SMTP server.
+type Security string
+
+const (
+	// SecurityNone sends in plain text, the credentials are sent only to localhost.
+	SecurityNone Security = "none"
+	// SecuritySTARTTLS upgrades the plain connection to TLS, usually on port 587.
+	SecuritySTARTTLS Security = "starttls"
+	// SecurityTLS connects by TLS, usually on port 465.
+	SecurityTLS Security = "tls"
+)
+
+var (
+	errUnknownSecurity = errors.New("unknown security")
+	errNoSTARTTLS      = errors.New("server doesn't support STARTTLS")
+)
+
+// ParseSecurity parses the security by its name, the empty name is [SecuritySTARTTLS].
+func ParseSecurity(s string) (Security, error) {
+	switch sec := Security(s); sec {
+	case "":
+		return SecuritySTARTTLS, nil
+	case SecurityNone, SecuritySTARTTLS, SecurityTLS:
+		return sec, nil
+	default:
+		return "", fmt.Errorf("%w %q", errUnknownSecurity, s)
+	}
+}
+
+// Config of the SMTP server and the addresses.
+type Config struct {
+	// Addr is host:port of the SMTP server.
+	Addr     string
+	Security Security
+	// Username enables the PLAIN authentication.
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+type Email struct {
+	cfg       Config
+	tmpl      *Template
+	tlsConfig *tls.Config
+	clock     clock.Clock
+	timeout   time.Duration
+	digest    time.Duration
+	stateFile string
+
+	mu    sync.Mutex
+	state *state
+}
+
+var _ notify.Notifier = (*Email)(nil)
+
+// state of the digest being collected, it is persisted to collect the runs of different processes.
+type state struct {
+	PeriodStart time.Time `json:"period_start"`
+	Digest      Digest    `json:"digest"`
+}
+
+// New construct for [Email].
+func New(cfg Config, opts ...Option) *Email {
+	e := &Email{
+		cfg:     cfg,
+		tmpl:    DefaultTemplate(),
+		clock:   clock.Real{},
+		timeout: DefaultTimeout,
+	}
+
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
+}
+
+// Notify sends the report of the run. In digest mode the run is added to the digest
+// which is sent by the first run after the end of the period.
+// The SMTP session is bounded by the timeout only, a canceled context still gets the email out.
+func (e *Email) Notify(ctx context.Context, run report.Run) error {
+	if e.digest <= 0 {
+		var d Digest
+		d.add(run)
+
+		return e.send(ctx, d)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == nil {
+		e.state = e.loadState()
+	}
+
+	now := e.clock.Now()
+
+	if e.state.PeriodStart.IsZero() {
+		e.state.PeriodStart = now
+	}
+
+	e.state.Digest.add(run)
+
+	if now.Sub(e.state.PeriodStart) < e.digest {
+		e.saveState()
+
+		return nil
+	}
+
+	if err := e.send(ctx, e.state.Digest); err != nil {
+		// The runs stay in the digest until it is sent.
+		e.saveState()
+
+		return err
+	}
+
+	e.state = &state{PeriodStart: now}
+	e.saveState()
+
+	return nil
+}
+
+func (e *Email) send(ctx context.Context, d Digest) error {
+	subject, text, html, err := e.tmpl.render(d)
+	if err != nil {
+		return fmt.Errorf("email template: %w", err)
+	}
+
+	msg, err := e.message(subject, text, html)
+	if err != nil {
+		return fmt.Errorf("email message: %w", err)
+	}
+
+	if err = e.deliver(ctx, msg); err != nil {
+		return fmt.Errorf("email to %s: %w", e.cfg.Addr, err)
+	}
+
+	return nil
+}
+
+func (e *Email) message(subject, text, html string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := []string{
+		"From: " + e.cfg.From,
+		"To: " + strings.Join(e.cfg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject)),
+		"Date: " + e.clock.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+
+	for _, h := range header {
+		buf.WriteString(h + "\r\n")
+	}
+
+	if html == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		if err := writeQuoted(&buf, text); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+
+	buf.WriteString("Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create part: %w", err)
+		}
+
+		if err = writeQuoted(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("close multipart: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuoted(w io.Writer, s string) error {
+	qw := quotedprintable.NewWriter(w)
+
+	if _, err := qw.Write([]byte(s)); err != nil {
+		return fmt.Errorf("write body: %w", err)
+	}
+
+	if err := qw.Close(); err != nil {
+		return fmt.Errorf("write body: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Email) deliver(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("parse address: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.timeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if e.cfg.Security == SecurityTLS {
+		conn = tls.Client(conn, e.tlsClientConfig(host))
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+
+		return fmt.Errorf("greeting: %w", err)
+	}
+
+	defer func() { _ = c.Close() }()
+
+	if e.cfg.Security == SecuritySTARTTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errNoSTARTTLS
+		}
+
+		if err = c.StartTLS(e.tlsClientConfig(host)); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if e.cfg.Username != "" {
+		if err = c.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err = c.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+
+	for _, to := range e.cfg.To {
+		if err = c.Rcpt(to); err != nil {
+			return fmt.Errorf("rcpt to %s: %w", to, err)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+
+	if _, err = w.Write(msg); err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	if err = c.Quit(); err != nil {
+		return fmt.Errorf("quit: %w", err)
+	}
+
+	return nil
+}
+
+func (e *Email) tlsClientConfig(host string) *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if e.tlsConfig != nil {
+		cfg = e.tlsConfig.Clone()
+	}
+
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	return cfg
+}
+
+func (e *Email) loadState() *state {
+	st := &state{}
+
+	if e.stateFile == "" {
+		return st
+	}
+
+	if err := statefile.Load(e.stateFile, st); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("email digest state is ignored", "error", err)
+		}
+
+		return &state{}
+	}
+
+	return st
+}
+
+func (e *Email) saveState() {
+	if e.stateFile == "" {
+		return
+	}
+
+	if err := statefile.Save(e.stateFile, e.state); err != nil {
+		slog.Warn("failed to save email digest state", "error", err)
+	}
+}
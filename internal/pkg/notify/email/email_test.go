@@ -0,0 +1,390 @@
+package email_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/email"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+type message struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer is the in-process stand-in of an SMTP server accepting every message.
+type smtpServer struct {
+	listener net.Listener
+	security email.Security
+	tls      *tls.Config
+	messages chan message
+}
+
+// startSMTP starts the server and returns it with the client TLS configuration trusting its certificate.
+func startSMTP(t *testing.T, security email.Security) (*smtpServer, *tls.Config) {
+	t.Helper()
+
+	// The test server of net/http provides the certificate for 127.0.0.1.
+	certSrv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	certSrv.StartTLS()
+	certSrv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certSrv.Certificate())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpServer{
+		listener: l,
+		security: security,
+		tls:      &tls.Config{Certificates: certSrv.TLS.Certificates, MinVersion: tls.VersionTLS12},
+		messages: make(chan message, 10),
+	}
+
+	t.Cleanup(func() { _ = l.Close() })
+
+	go s.serve()
+
+	return s, &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+}
+
+func (s *smtpServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	secure := s.security == email.SecurityTLS
+	if secure {
+		conn = tls.Server(conn, s.tls)
+	}
+
+	tp := textproto.NewConn(conn)
+
+	var msg message
+
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			if s.security == email.SecuritySTARTTLS && !secure {
+				_ = tp.PrintfLine("250-localhost\r\n250-STARTTLS\r\n250 AUTH PLAIN")
+			} else {
+				_ = tp.PrintfLine("250-localhost\r\n250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			_ = tp.PrintfLine("220 ready")
+
+			conn = tls.Server(conn, s.tls)
+			tp = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			msg.auth = string(creds)
+
+			_ = tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			msg.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+
+			_ = tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+
+			_ = tp.PrintfLine("250 ok")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+
+			data, _ := tp.ReadDotBytes()
+			msg.data = string(data)
+			s.messages <- msg
+			msg = message{}
+
+			_ = tp.PrintfLine("250 queued")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// parts returns the subject and the bodies of the message by their content types.
+func parts(t *testing.T, data string) (string, map[string]string) {
+	t.Helper()
+
+	m, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(data)))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	bodies := map[string]string{}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(m.Body)
+		require.NoError(t, err)
+
+		bodies[mediaType] = string(body)
+
+		return subject, bodies
+	}
+
+	mr := multipart.NewReader(m.Body, params["boundary"])
+
+	for {
+		p, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+
+		partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(p)
+		require.NoError(t, err)
+
+		bodies[partType] = string(body)
+	}
+
+	return subject, bodies
+}
+
+var testRun = report.Run{
+	Start:    time.Date(2025, time.March, 1, 3, 0, 0, 0, time.UTC),
+	End:      time.Date(2025, time.March, 1, 3, 1, 0, 0, time.UTC),
+	Counts:   report.Counts{Total: 3, Skipped: 1, Downloaded: 2},
+	NewBooks: []string{"Война и мир.epub", "Tom & Jerry.pdf"},
+}
+
+func TestEmail_Notify(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		security email.Security
+	}{
+		{name: "none", security: email.SecurityNone},
+		{name: "starttls", security: email.SecuritySTARTTLS},
+		{name: "tls", security: email.SecurityTLS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, tlsConfig := startSMTP(t, tt.security)
+
+			e := email.New(email.Config{
+				Addr:     srv.addr(),
+				Security: tt.security,
+				Username: "user",
+				Password: "secret",
+				From:     "pbcsync@example.com",
+				To:       []string{"first@example.com", "second@example.com"},
+			}, email.WithTLSConfig(tlsConfig))
+
+			require.NoError(t, e.Notify(t.Context(), testRun))
+
+			msg := <-srv.messages
+			assert.Equal(t, "\x00user\x00secret", msg.auth)
+			assert.Equal(t, "pbcsync@example.com", msg.from)
+			assert.Equal(t, []string{"first@example.com", "second@example.com"}, msg.to)
+
+			subject, bodies := parts(t, msg.data)
+			assert.Equal(t, "PocketBook Cloud Sync: 2 new books", subject)
+			assert.Contains(t, bodies["text/plain"], "- Война и мир.epub\n- Tom & Jerry.pdf\n")
+			assert.Contains(t, bodies["text/plain"], "Books in the cloud: 3")
+			assert.Contains(t, bodies["text/html"], "<li>Tom &amp; Jerry.pdf</li>")
+		})
+	}
+}
+
+func TestEmail_Notify_Error(t *testing.T) {
+	t.Parallel()
+
+	// The server without STARTTLS support.
+	srv, _ := startSMTP(t, email.SecurityNone)
+
+	e := email.New(email.Config{
+		Addr:     srv.addr(),
+		Security: email.SecuritySTARTTLS,
+		From:     "pbcsync@example.com",
+		To:       []string{"first@example.com"},
+	})
+
+	require.ErrorContains(t, e.Notify(t.Context(), testRun), "server doesn't support STARTTLS")
+	assert.Empty(t, srv.messages)
+}
+
+func TestEmail_Notify_Digest(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := startSMTP(t, email.SecurityNone)
+
+	now := time.Date(2025, time.March, 1, 3, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(now)
+	stateFile := filepath.Join(t.TempDir(), "digest.json")
+
+	// Each run is made by a new process.
+	notify := func(run report.Run) {
+		e := email.New(email.Config{
+			Addr:     srv.addr(),
+			Security: email.SecurityNone,
+			From:     "pbcsync@example.com",
+			To:       []string{"first@example.com"},
+		}, email.WithDigest(24*time.Hour, stateFile), email.WithClock(fake))
+
+		require.NoError(t, e.Notify(t.Context(), run))
+	}
+
+	notify(report.Run{Start: now, End: now, Counts: report.Counts{Total: 2}, NewBooks: []string{"first.epub"}})
+	fake.Advance(12 * time.Hour)
+	notify(report.Run{Start: fake.Now(), End: fake.Now(), Err: errors.New("unauthorized")})
+
+	assert.Empty(t, srv.messages)
+
+	fake.Advance(12 * time.Hour)
+	notify(report.Run{Start: fake.Now(), End: fake.Now(), Counts: report.Counts{Total: 3}, NewBooks: []string{"second.pdf"}})
+
+	subject, bodies := parts(t, (<-srv.messages).data)
+	assert.Equal(t, "PocketBook Cloud Sync: 1 failed of 3 runs", subject)
+	assert.Equal(t, "Runs: 3 from 2025-03-01 03:00 to 2025-03-02 03:00\n"+
+		"Books in the cloud: 3\n\n"+
+		"New books:\n- first.epub\n- second.pdf\n\n"+
+		"Failures:\n- 2025-03-01 15:00: unauthorized\n", bodies["text/plain"])
+
+	// The next period starts empty.
+	fake.Advance(time.Hour)
+	notify(report.Run{Start: fake.Now(), End: fake.Now()})
+
+	assert.Empty(t, srv.messages)
+}
+
+func TestParseTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "text only", src: `{{ define "subject" }}{{ .Runs }} runs{{ end }}{{ define "text" }}{{ len .NewBooks }} new{{ end }}`},
+		{name: "no subject", src: `{{ define "text" }}{{ .Runs }}{{ end }}`, wantErr: `template is not defined: "subject"`},
+		{name: "syntax error", src: `{{ define "subject" }}`, wantErr: "parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "email.tmpl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.src), 0o600))
+
+			tmpl, err := email.ParseTemplate(path)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			srv, _ := startSMTP(t, email.SecurityNone)
+
+			e := email.New(email.Config{
+				Addr:     srv.addr(),
+				Security: email.SecurityNone,
+				From:     "pbcsync@example.com",
+				To:       []string{"first@example.com"},
+			}, email.WithTemplate(tmpl))
+
+			require.NoError(t, e.Notify(t.Context(), testRun))
+
+			subject, bodies := parts(t, (<-srv.messages).data)
+			assert.Equal(t, "1 runs", subject)
+			assert.Equal(t, map[string]string{"text/plain": "2 new\n"}, bodies)
+		})
+	}
+}
+
+func TestParseSecurity(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		value   string
+		want    email.Security
+		wantErr bool
+	}{
+		{value: "", want: email.SecuritySTARTTLS},
+		{value: "none", want: email.SecurityNone},
+		{value: "starttls", want: email.SecuritySTARTTLS},
+		{value: "tls", want: email.SecurityTLS},
+		{value: "ssl", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := email.ParseSecurity(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
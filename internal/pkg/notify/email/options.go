@@ -0,0 +1,40 @@
+package email
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+)
+
+type Option func(*Email)
+
+// WithTemplate sets the template of the email instead of [DefaultTemplate].
+func WithTemplate(t *Template) Option {
+	return func(e *Email) {
+		e.tmpl = t
+	}
+}
+
+// WithDigest collects the runs and sends them once per period instead of an email per run.
+// The digest being collected is persisted to the state file, so it survives restarts.
+func WithDigest(period time.Duration, stateFile string) Option {
+	return func(e *Email) {
+		e.digest = period
+		e.stateFile = stateFile
+	}
+}
+
+// WithTLSConfig sets the TLS configuration, e.g. trusted certificates of a private server.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(e *Email) {
+		e.tlsConfig = cfg
+	}
+}
+
+// WithClock sets the clock, useful for testing.
+func WithClock(c clock.Clock) Option {
+	return func(e *Email) {
+		e.clock = c
+	}
+}
@@ -0,0 +1,97 @@
+package email
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"text/template"
+)
+
+//go:embed default.tmpl
+var defaultTemplate string
+
+var errNoTemplate = errors.New("template is not defined")
+
+// Template renders the subject and the parts of the email.
+// The source defines the templates "subject", "text" and optionally "html", the data is [Digest].
+type Template struct {
+	text *template.Template
+	html *htmltemplate.Template
+}
+
+// DefaultTemplate returns the built-in template.
+func DefaultTemplate() *Template {
+	t, err := parseTemplate(defaultTemplate)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// ParseTemplate reads the template from the file.
+func ParseTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return parseTemplate(string(data))
+}
+
+func parseTemplate(src string) (*Template, error) {
+	text, err := template.New("email").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	for _, name := range []string{"subject", "text"} {
+		if text.Lookup(name) == nil {
+			return nil, fmt.Errorf("%w: %q", errNoTemplate, name)
+		}
+	}
+
+	t := &Template{text: text}
+
+	if text.Lookup("html") != nil {
+		// The HTML part is parsed separately to escape the values.
+		if t.html, err = htmltemplate.New("email").Parse(src); err != nil {
+			return nil, fmt.Errorf("parse html: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+// render returns the subject, the text and the HTML part, the last one is empty if it is not defined.
+func (t *Template) render(d Digest) (subject, text, html string, err error) {
+	var buf bytes.Buffer
+
+	if err = t.text.ExecuteTemplate(&buf, "subject", d); err != nil {
+		return "", "", "", fmt.Errorf("execute subject: %w", err)
+	}
+
+	subject = buf.String()
+	buf.Reset()
+
+	if err = t.text.ExecuteTemplate(&buf, "text", d); err != nil {
+		return "", "", "", fmt.Errorf("execute text: %w", err)
+	}
+
+	text = buf.String()
+
+	if t.html == nil {
+		return subject, text, "", nil
+	}
+
+	buf.Reset()
+
+	if err = t.html.ExecuteTemplate(&buf, "html", d); err != nil {
+		return "", "", "", fmt.Errorf("execute html: %w", err)
+	}
+
+	return subject, text, buf.String(), nil
+}
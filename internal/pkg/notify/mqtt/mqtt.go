@@ -0,0 +1,307 @@
+// Package mqtt publishes the reports of synchronization runs to an MQTT broker,
+// optionally with Home Assistant discovery messages.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	client "github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/version"
+)
+
+const (
+	// DefaultTopic is the prefix of the topics.
+	DefaultTopic = "pbcsync"
+	// DefaultDiscoveryPrefix is the discovery prefix of Home Assistant.
+	DefaultDiscoveryPrefix = "homeassistant"
+	// DefaultTimeout limits the publishing of a report.
+	DefaultTimeout = 30 * time.Second
+)
+
+// Topics under the prefix.
+const (
+	// TopicState receives the state of the last run.
+	TopicState = "state"
+	// TopicBook receives an event per downloaded book.
+	TopicBook = "book"
+)
+
+const (
+	// EventDownloaded is the type of the book events.
+	EventDownloaded = "downloaded"
+)
+
+// Config of the publisher.
+type Config struct {
+	client.Config
+	// Topic is the prefix of the topics.
+	Topic string
+	QoS   byte
+	// Retain keeps the last state on the broker for new subscribers.
+	Retain bool
+	// Discovery is the discovery prefix of Home Assistant, the empty prefix disables discovery.
+	Discovery string
+}
+
+// State is the payload of [TopicState].
+type State struct {
+	Status      string     `json:"status"`
+	Error       string     `json:"error"`
+	Start       time.Time  `json:"start"`
+	End         time.Time  `json:"end"`
+	Total       int        `json:"total"`
+	Skipped     int        `json:"skipped"`
+	Downloaded  int        `json:"downloaded"`
+	LastSuccess *time.Time `json:"last_success"`
+}
+
+// Book is the payload of [TopicBook].
+type Book struct {
+	EventType string `json:"event_type"`
+	Name      string `json:"name"`
+}
+
+type Publisher struct {
+	cfg       Config
+	stateFile string
+	timeout   time.Duration
+
+	mu          sync.Mutex
+	announced   bool
+	lastSuccess *time.Time
+	loaded      bool
+}
+
+var _ notify.Notifier = (*Publisher)(nil)
+
+// New construct for [Publisher]. The client ID is derived from the topic if it is empty.
+func New(cfg Config, opts ...Option) *Publisher {
+	if cfg.ClientID == "" {
+		cfg.ClientID = nodeID(cfg.Topic)
+	}
+
+	p := &Publisher{cfg: cfg, timeout: DefaultTimeout}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Notify publishes the state of the run and the events of the downloaded books.
+// Discovery messages are published once per process before the first state.
+// The broker connection lives until the timeout even if the context is canceled,
+// so the retained state isn't left at the run before the shutdown.
+func (p *Publisher) Notify(ctx context.Context, run report.Run) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := p.state(run)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.timeout)
+	defer cancel()
+
+	conn, err := client.Dial(ctx, p.cfg.Config)
+	if err != nil {
+		return redact.Error(fmt.Errorf("mqtt %s: %w", p.cfg.Broker, err))
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	if p.cfg.Discovery != "" && !p.announced {
+		if err = p.announce(conn); err != nil {
+			return fmt.Errorf("mqtt discovery: %w", err)
+		}
+
+		p.announced = true
+	}
+
+	if err = p.publish(conn, p.topic(TopicState), st, p.cfg.Retain); err != nil {
+		return fmt.Errorf("mqtt state: %w", err)
+	}
+
+	for _, name := range run.NewBooks {
+		if err = p.publish(conn, p.topic(TopicBook), Book{EventType: EventDownloaded, Name: name}, false); err != nil {
+			return fmt.Errorf("mqtt book: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// state returns the payload of the run and remembers the time of the last success.
+func (p *Publisher) state(run report.Run) State {
+	if !p.loaded {
+		p.lastSuccess, p.loaded = p.loadLastSuccess(), true
+	}
+
+	st := State{
+		Status:     report.StatusSuccess,
+		Start:      run.Start,
+		End:        run.End,
+		Total:      run.Total,
+		Skipped:    run.Skipped,
+		Downloaded: run.Downloaded,
+	}
+
+	if run.Err != nil {
+		st.Status, st.Error = report.StatusError, run.Err.Error()
+	} else {
+		end := run.End
+		p.lastSuccess = &end
+		p.saveLastSuccess()
+	}
+
+	st.LastSuccess = p.lastSuccess
+
+	return st
+}
+
+func (p *Publisher) topic(name string) string {
+	return p.cfg.Topic + "/" + name
+}
+
+func (p *Publisher) publish(conn *client.Conn, topic string, v any, retain bool) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	return conn.Publish(topic, payload, p.cfg.QoS, retain)
+}
+
+var nonIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func nodeID(topic string) string {
+	return nonIDChars.ReplaceAllString(topic, "_")
+}
+
+type discoveryDevice struct {
+	Identifiers []string `json:"identifiers"`
+	Name        string   `json:"name"`
+	SWVersion   string   `json:"sw_version"`
+}
+
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template,omitempty"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	StateClass        string          `json:"state_class,omitempty"`
+	UnitOfMeasurement string          `json:"unit_of_measurement,omitempty"`
+	Icon              string          `json:"icon,omitempty"`
+	EventTypes        []string        `json:"event_types,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+// announce publishes the retained discovery messages of the sensors and the book event.
+func (p *Publisher) announce(conn *client.Conn) error {
+	node := nodeID(p.cfg.Topic)
+	device := discoveryDevice{
+		Identifiers: []string{node},
+		Name:        "PocketBook Cloud Sync",
+		SWVersion:   version.Version(),
+	}
+
+	sensor := func(object, name, value string) discoveryConfig {
+		return discoveryConfig{
+			Name:          name,
+			UniqueID:      node + "_" + object,
+			StateTopic:    p.topic(TopicState),
+			ValueTemplate: "{{ value_json." + value + " }}",
+			Device:        device,
+		}
+	}
+
+	status := sensor("status", "Status", "status")
+	status.Icon = "mdi:cloud-sync"
+
+	total := sensor("total", "Books", "total")
+	total.StateClass, total.UnitOfMeasurement, total.Icon = "measurement", "books", "mdi:bookshelf"
+
+	downloaded := sensor("downloaded", "Downloaded", "downloaded")
+	downloaded.StateClass, downloaded.UnitOfMeasurement, downloaded.Icon = "measurement", "books", "mdi:book-arrow-down"
+
+	lastRun := sensor("last_run", "Last run", "end")
+	lastRun.DeviceClass = "timestamp"
+
+	lastSuccess := sensor("last_success", "Last success", "last_success")
+	lastSuccess.DeviceClass = "timestamp"
+
+	book := discoveryConfig{
+		Name:       "Book",
+		UniqueID:   node + "_book",
+		StateTopic: p.topic(TopicBook),
+		EventTypes: []string{EventDownloaded},
+		Icon:       "mdi:book-plus",
+		Device:     device,
+	}
+
+	configs := []struct {
+		component string
+		config    discoveryConfig
+	}{
+		{"sensor", status},
+		{"sensor", total},
+		{"sensor", downloaded},
+		{"sensor", lastRun},
+		{"sensor", lastSuccess},
+		{"event", book},
+	}
+
+	for _, c := range configs {
+		topic := p.cfg.Discovery + "/" + c.component + "/" + node + "/" + c.config.UniqueID + "/config"
+
+		if err := p.publish(conn, topic, c.config, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type state struct {
+	LastSuccess *time.Time `json:"last_success"`
+}
+
+func (p *Publisher) loadLastSuccess() *time.Time {
+	if p.stateFile == "" {
+		return nil
+	}
+
+	var st state
+
+	if err := statefile.Load(p.stateFile, &st); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("mqtt state is ignored", "error", err)
+		}
+
+		return nil
+	}
+
+	return st.LastSuccess
+}
+
+func (p *Publisher) saveLastSuccess() {
+	if p.stateFile == "" {
+		return
+	}
+
+	if err := statefile.Save(p.stateFile, state{LastSuccess: p.lastSuccess}); err != nil {
+		slog.Warn("failed to save mqtt state", "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+package mqtt_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	client "github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/mqtt/mqtttest"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/mqtt"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+var (
+	start = time.Date(2025, time.March, 1, 3, 0, 0, 0, time.UTC)
+	end   = start.Add(time.Minute)
+)
+
+func TestPublisher_Notify(t *testing.T) {
+	t.Parallel()
+
+	broker := mqtttest.NewBroker()
+	stateFile := filepath.Join(t.TempDir(), "mqtt.json")
+
+	newPublisher := func() *mqtt.Publisher {
+		return mqtt.New(mqtt.Config{
+			Config: client.Config{Broker: broker.URL, ClientID: "pbcsync"},
+			Topic:  "home/pbcsync",
+			QoS:    1,
+			Retain: true,
+		}, mqtt.WithStateFile(stateFile))
+	}
+
+	run := report.Run{
+		Start:    start,
+		End:      end,
+		Counts:   report.Counts{Total: 3, Skipped: 1, Downloaded: 2},
+		NewBooks: []string{"first.epub", "second.pdf"},
+	}
+
+	require.NoError(t, newPublisher().Notify(t.Context(), run))
+
+	// The last success is kept by the failed run of the next process.
+	failed := report.Run{Start: end.Add(time.Hour), End: end.Add(time.Hour), Err: errors.New("unauthorized")}
+
+	require.NoError(t, newPublisher().Notify(t.Context(), failed))
+
+	broker.Close()
+
+	messages := broker.Messages()
+	require.Len(t, messages, 4)
+
+	assert.Equal(t, "home/pbcsync/state", messages[0].Topic)
+	assert.True(t, messages[0].Retain)
+	assert.Equal(t, byte(1), messages[0].QoS)
+	assert.JSONEq(t, `{
+		"status": "success",
+		"error": "",
+		"start": "2025-03-01T03:00:00Z",
+		"end": "2025-03-01T03:01:00Z",
+		"total": 3,
+		"skipped": 1,
+		"downloaded": 2,
+		"last_success": "2025-03-01T03:01:00Z"
+	}`, messages[0].Payload)
+
+	assert.Equal(t, mqtttest.Message{
+		ClientID: "pbcsync",
+		Topic:    "home/pbcsync/book",
+		Payload:  `{"event_type":"downloaded","name":"first.epub"}`,
+		QoS:      1,
+	}, messages[1])
+	assert.Equal(t, `{"event_type":"downloaded","name":"second.pdf"}`, messages[2].Payload)
+
+	state, ok := broker.Retained("home/pbcsync/state")
+	require.True(t, ok)
+	assert.JSONEq(t, `{
+		"status": "error",
+		"error": "unauthorized",
+		"start": "2025-03-01T04:01:00Z",
+		"end": "2025-03-01T04:01:00Z",
+		"total": 0,
+		"skipped": 0,
+		"downloaded": 0,
+		"last_success": "2025-03-01T03:01:00Z"
+	}`, state.Payload)
+}
+
+func TestPublisher_Notify_Discovery(t *testing.T) {
+	t.Parallel()
+
+	broker := mqtttest.NewTLSBroker()
+	broker.Username, broker.Password = "user", "secret"
+
+	p := mqtt.New(mqtt.Config{
+		Config: client.Config{
+			Broker:   broker.URL,
+			ClientID: "pbcsync",
+			Username: "user",
+			Password: "secret",
+			TLS:      broker.ClientTLS(),
+		},
+		Topic:     mqtt.DefaultTopic,
+		Discovery: mqtt.DefaultDiscoveryPrefix,
+	})
+
+	run := report.Run{Start: start, End: end}
+
+	require.NoError(t, p.Notify(t.Context(), run))
+	require.NoError(t, p.Notify(t.Context(), run))
+
+	broker.Close()
+
+	// Discovery is published once per process.
+	assert.Len(t, broker.Messages(), 8)
+
+	for _, topic := range []string{
+		"homeassistant/sensor/pbcsync/pbcsync_status/config",
+		"homeassistant/sensor/pbcsync/pbcsync_total/config",
+		"homeassistant/sensor/pbcsync/pbcsync_downloaded/config",
+		"homeassistant/sensor/pbcsync/pbcsync_last_run/config",
+		"homeassistant/sensor/pbcsync/pbcsync_last_success/config",
+		"homeassistant/event/pbcsync/pbcsync_book/config",
+	} {
+		m, ok := broker.Retained(topic)
+		require.True(t, ok, topic)
+
+		var cfg map[string]any
+		require.NoError(t, json.Unmarshal([]byte(m.Payload), &cfg))
+		assert.NotEmpty(t, cfg["state_topic"], topic)
+	}
+
+	m, _ := broker.Retained("homeassistant/sensor/pbcsync/pbcsync_last_success/config")
+	assert.JSONEq(t, `{
+		"name": "Last success",
+		"unique_id": "pbcsync_last_success",
+		"state_topic": "pbcsync/state",
+		"value_template": "{{ value_json.last_success }}",
+		"device_class": "timestamp",
+		"device": {"identifiers": ["pbcsync"], "name": "PocketBook Cloud Sync", "sw_version": "undefined"}
+	}`, m.Payload)
+
+	// The state isn't retained by default.
+	_, ok := broker.Retained("pbcsync/state")
+	assert.False(t, ok)
+}
+
+func TestPublisher_Notify_Error(t *testing.T) {
+	t.Parallel()
+
+	broker := mqtttest.NewBroker()
+	broker.Username, broker.Password = "user", "secret"
+
+	t.Cleanup(broker.Close)
+
+	p := mqtt.New(mqtt.Config{
+		Config: client.Config{Broker: broker.URL, Username: "user", Password: "wrong"},
+		Topic:  mqtt.DefaultTopic,
+	})
+
+	require.ErrorContains(t, p.Notify(t.Context(), report.Run{}), "bad user name or password")
+}
@@ -0,0 +1,11 @@
+package mqtt
+
+type Option func(*Publisher)
+
+// WithStateFile sets the file to persist the time of the last success,
+// so it is published after restart or between runs without daemon mode.
+func WithStateFile(path string) Option {
+	return func(p *Publisher) {
+		p.stateFile = path
+	}
+}
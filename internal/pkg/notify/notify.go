@@ -0,0 +1,143 @@
+// Package notify sends the reports of synchronization runs to the notification targets.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+// Mode selects the runs to notify about.
+type Mode string
+
+const (
+	// ModeAlways notifies about every run.
+	ModeAlways Mode = "always"
+	// ModeChange notifies about runs downloading new books or changing the result of the previous run.
+	ModeChange Mode = "change"
+	// ModeFailure notifies about failed runs and the first successful run after them.
+	ModeFailure Mode = "failure"
+)
+
+var errUnknownMode = errors.New("unknown mode")
+
+// ParseMode parses the mode by its name, the empty name is [ModeAlways].
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return ModeAlways, nil
+	case ModeAlways, ModeChange, ModeFailure:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w %q", errUnknownMode, s)
+	}
+}
+
+// Notifier sends the report of a run to a target.
+type Notifier interface {
+	Notify(ctx context.Context, run report.Run) error
+}
+
+// Dispatcher sends the reports selected by the mode to all notifiers, the nil one sends nothing.
+type Dispatcher struct {
+	mode      Mode
+	notifiers []Notifier
+	stateFile string
+
+	mu    sync.Mutex
+	state *state
+}
+
+// state is the result of the previous run, it is persisted to detect changes between processes.
+type state struct {
+	Failed bool `json:"failed"`
+}
+
+func New(mode Mode, notifiers []Notifier, opts ...Option) *Dispatcher {
+	d := &Dispatcher{mode: mode, notifiers: notifiers}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// Observe sends the report if the mode selects it. Failed notifications are logged.
+func (d *Dispatcher) Observe(ctx context.Context, run report.Run) {
+	if d == nil {
+		return
+	}
+
+	if !d.selects(run) {
+		slog.Debug("notification skipped", "mode", d.mode)
+
+		return
+	}
+
+	for _, n := range d.notifiers {
+		if err := n.Notify(ctx, run); err != nil {
+			slog.Warn("failed to send notification", "error", err)
+		}
+	}
+}
+
+// selects reports whether the run is notified and remembers its result for the next one.
+func (d *Dispatcher) selects(run report.Run) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.state == nil {
+		d.state = d.loadState()
+	}
+
+	failed := run.Err != nil
+	changed := d.state != nil && failed != d.state.Failed
+
+	d.state = &state{Failed: failed}
+	d.saveState()
+
+	switch d.mode {
+	case ModeChange:
+		return changed || len(run.NewBooks) > 0
+	case ModeFailure:
+		return failed || changed
+	default:
+		return true
+	}
+}
+
+// loadState returns the persisted state or nil if the result of the previous run is unknown.
+func (d *Dispatcher) loadState() *state {
+	if d.stateFile == "" {
+		return nil
+	}
+
+	var st state
+
+	if err := statefile.Load(d.stateFile, &st); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("notification state is ignored", "error", err)
+		}
+
+		return nil
+	}
+
+	return &st
+}
+
+func (d *Dispatcher) saveState() {
+	if d.stateFile == "" {
+		return
+	}
+
+	if err := statefile.Save(d.stateFile, d.state); err != nil {
+		slog.Warn("failed to save notification state", "error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+type notifierFunc func(ctx context.Context, run report.Run) error
+
+func (f notifierFunc) Notify(ctx context.Context, run report.Run) error {
+	return f(ctx, run)
+}
+
+func TestParseMode(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name    string
+		value   string
+		want    notify.Mode
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: notify.ModeAlways},
+		{name: "always", value: "always", want: notify.ModeAlways},
+		{name: "change", value: "change", want: notify.ModeChange},
+		{name: "failure", value: "failure", want: notify.ModeFailure},
+		{name: "unknown", value: "never", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := notify.ParseMode(tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDispatcher_Observe(t *testing.T) {
+	t.Parallel()
+
+	errSync := errors.New("sync")
+
+	// Runs: nothing new, new books, failure, failure, recovery, nothing new.
+	runs := []report.Run{
+		{},
+		{NewBooks: []string{"first.txt"}},
+		{Err: errSync},
+		{Err: errSync},
+		{},
+		{},
+	}
+
+	tests := [...]struct {
+		name string
+		mode notify.Mode
+		want []int
+	}{
+		{name: "always", mode: notify.ModeAlways, want: []int{0, 1, 2, 3, 4, 5}},
+		{name: "change", mode: notify.ModeChange, want: []int{1, 2, 4}},
+		{name: "failure", mode: notify.ModeFailure, want: []int{2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []int
+
+			current := 0
+			n := notifierFunc(func(context.Context, report.Run) error {
+				got = append(got, current)
+
+				return errors.New("ignored")
+			})
+
+			d := notify.New(tt.mode, []notify.Notifier{n})
+
+			for i, run := range runs {
+				current = i
+				d.Observe(t.Context(), run)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDispatcher_Observe_StateFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "notify.json")
+
+	var c int
+
+	n := notifierFunc(func(context.Context, report.Run) error { c++; return nil })
+
+	// Each run is made by a new process.
+	for _, run := range []report.Run{{Err: errors.New("sync")}, {}, {}} {
+		notify.New(notify.ModeFailure, []notify.Notifier{n}, notify.WithStateFile(path)).Observe(t.Context(), run)
+	}
+
+	assert.Equal(t, 2, c)
+}
+
+func TestDispatcher_Observe_Nil(t *testing.T) {
+	t.Parallel()
+
+	var d *notify.Dispatcher
+
+	assert.NotPanics(t, func() { d.Observe(t.Context(), report.Run{}) })
+}
@@ -0,0 +1,11 @@
+package notify
+
+type Option func(*Dispatcher)
+
+// WithStateFile sets the file to persist the result of the last run,
+// so the change of the result is detected after restart or between runs without daemon mode.
+func WithStateFile(path string) Option {
+	return func(d *Dispatcher) {
+		d.stateFile = path
+	}
+}
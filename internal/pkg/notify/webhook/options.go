@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"net/http"
+	"text/template"
+	"time"
+)
+
+type Option func(*Webhook)
+
+// WithHTTPClient sets the client of requests, [http.DefaultClient] by default.
+func WithHTTPClient(c *http.Client) Option {
+	return func(w *Webhook) {
+		w.client = c
+	}
+}
+
+// WithTemplate sets the template of the body instead of the JSON [Payload].
+// The body is sent as JSON if the result is valid JSON, otherwise as plain text.
+func WithTemplate(t *template.Template) Option {
+	return func(w *Webhook) {
+		w.tmpl = t
+	}
+}
+
+// WithRetry sets how many times the report is sent and the first delay between attempts.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(w *Webhook) {
+		w.attempts = max(attempts, 1)
+		w.backoff = backoff
+	}
+}
+
+// WithDeadline limits the whole delivery, [DefaultDeadline] by default.
+func WithDeadline(d time.Duration) Option {
+	return func(w *Webhook) {
+		w.deadline = d
+	}
+}
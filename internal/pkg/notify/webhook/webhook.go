@@ -0,0 +1,224 @@
+// Package webhook posts the reports of synchronization runs to HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+const (
+	// DefaultAttempts is how many times the report is sent before giving up.
+	DefaultAttempts = 3
+	// DefaultBackoff is the first delay before resending, it doubles after each attempt.
+	DefaultBackoff = time.Second
+	// DefaultTimeout limits each attempt.
+	DefaultTimeout = 10 * time.Second
+	// DefaultDeadline limits the whole delivery with all the attempts and delays.
+	DefaultDeadline = time.Minute
+)
+
+// Payload is the JSON body of the webhook and the data of the body template.
+type Payload struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Total      int       `json:"total"`
+	Skipped    int       `json:"skipped"`
+	Downloaded int       `json:"downloaded"`
+	NewBooks   []string  `json:"new_books"`
+}
+
+// NewPayload converts the report of the run.
+func NewPayload(run report.Run) Payload {
+	p := Payload{
+		Status:     report.StatusSuccess,
+		Start:      run.Start,
+		End:        run.End,
+		Total:      run.Total,
+		Skipped:    run.Skipped,
+		Downloaded: run.Downloaded,
+		NewBooks:   run.NewBooks,
+	}
+
+	if run.Err != nil {
+		p.Status, p.Error = report.StatusError, run.Err.Error()
+	}
+
+	if p.NewBooks == nil {
+		p.NewBooks = []string{}
+	}
+
+	return p
+}
+
+type Webhook struct {
+	url      string
+	client   *http.Client
+	tmpl     *template.Template
+	attempts int
+	backoff  time.Duration
+	timeout  time.Duration
+	deadline time.Duration
+}
+
+var _ notify.Notifier = (*Webhook)(nil)
+
+// New construct for [Webhook] posting to the url.
+func New(url string, opts ...Option) *Webhook {
+	w := &Webhook{
+		url:      url,
+		client:   http.DefaultClient,
+		attempts: DefaultAttempts,
+		backoff:  DefaultBackoff,
+		timeout:  DefaultTimeout,
+		deadline: DefaultDeadline,
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	return w
+}
+
+// ParseTemplate reads the template of the body from the file.
+// Besides the fields of [Payload] the template may use the functions:
+// json - the value encoded as JSON, e.g. to quote the error; join - strings.Join.
+func ParseTemplate(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": toJSON,
+		"join": strings.Join,
+	}).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+
+	return string(data), err
+}
+
+// Notify posts the report. Network errors, rate limits and server errors are retried with backoff
+// until the attempts or the deadline run out. The post outlives the cancellation of the context,
+// so the webhook still gets the report of the run interrupted by the shutdown.
+func (w *Webhook) Notify(ctx context.Context, run report.Run) error {
+	body, contentType, err := w.body(NewPayload(run))
+	if err != nil {
+		return fmt.Errorf("webhook body: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.deadline)
+	defer cancel()
+
+	backoff := w.backoff
+
+	for attempt := 1; ; attempt++ {
+		err = w.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+
+		var perm permanentError
+		if errors.As(err, &perm) || attempt >= w.attempts {
+			return redact.Error(fmt.Errorf("webhook %s: attempt %d: %w", w.url, attempt, err))
+		}
+
+		timer := time.NewTimer(backoff)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+
+			return redact.Error(fmt.Errorf("webhook %s: attempt %d: %w", w.url, attempt, errors.Join(err, ctx.Err())))
+		}
+
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) body(p Payload) ([]byte, string, error) {
+	if w.tmpl == nil {
+		data, err := json.Marshal(p)
+
+		return data, "application/json", err
+	}
+
+	var buf bytes.Buffer
+
+	if err := w.tmpl.Execute(&buf, p); err != nil {
+		return nil, "", fmt.Errorf("execute template: %w", err)
+	}
+
+	if json.Valid(buf.Bytes()) {
+		return buf.Bytes(), "application/json", nil
+	}
+
+	return buf.Bytes(), "text/plain; charset=utf-8", nil
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{fmt.Errorf("create request: %w", err)}
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	rsp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http POST: %w", err)
+	}
+
+	defer func() { _ = rsp.Body.Close() }()
+
+	_, _ = io.Copy(io.Discard, rsp.Body)
+
+	switch code := rsp.StatusCode; {
+	case code >= 200 && code < 300:
+		return nil
+	case code == http.StatusTooManyRequests || code >= 500:
+		return fmt.Errorf("http status %d", code)
+	default:
+		return permanentError{fmt.Errorf("http status %d", code)}
+	}
+}
+
+// permanentError is not retried.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,201 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/notify/webhook"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+type request struct {
+	contentType string
+	body        string
+}
+
+func receiver(t *testing.T, codes ...int) (*httptest.Server, <-chan request) {
+	t.Helper()
+
+	requests := make(chan request, 10)
+
+	var n atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{contentType: r.Header.Get("Content-Type"), body: string(body)}
+
+		if i := int(n.Add(1)) - 1; i < len(codes) {
+			w.WriteHeader(codes[i])
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+var testRun = report.Run{
+	Start:    time.Date(2025, time.March, 1, 3, 0, 0, 0, time.UTC),
+	End:      time.Date(2025, time.March, 1, 3, 1, 0, 0, time.UTC),
+	Counts:   report.Counts{Total: 3, Skipped: 1, Downloaded: 2},
+	NewBooks: []string{"first.epub", "second.pdf"},
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := receiver(t)
+
+	err := webhook.New(srv.URL).Notify(t.Context(), testRun)
+	require.NoError(t, err)
+
+	req := <-requests
+	assert.Equal(t, "application/json", req.contentType)
+	assert.JSONEq(t, `{
+		"status": "success",
+		"start": "2025-03-01T03:00:00Z",
+		"end": "2025-03-01T03:01:00Z",
+		"total": 3,
+		"skipped": 1,
+		"downloaded": 2,
+		"new_books": ["first.epub", "second.pdf"]
+	}`, req.body)
+}
+
+func TestWebhook_Notify_Error(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := receiver(t)
+
+	run := report.Run{Start: testRun.Start, End: testRun.End, Err: errors.New("unauthorized")}
+
+	err := webhook.New(srv.URL).Notify(t.Context(), run)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"status": "error",
+		"error": "unauthorized",
+		"start": "2025-03-01T03:00:00Z",
+		"end": "2025-03-01T03:01:00Z",
+		"total": 0,
+		"skipped": 0,
+		"downloaded": 0,
+		"new_books": []
+	}`, (<-requests).body)
+}
+
+func TestWebhook_Notify_Template(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name        string
+		template    string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json",
+			template:    `{"text": {{ printf "%s: %s" .Status (join .NewBooks ", ") | json }}}`,
+			contentType: "application/json",
+			body:        `{"text": "success: first.epub, second.pdf"}`,
+		},
+		{
+			name:        "text",
+			template:    `Downloaded {{ .Downloaded }} of {{ .Total }} books`,
+			contentType: "text/plain; charset=utf-8",
+			body:        `Downloaded 2 of 3 books`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "webhook.tmpl")
+			require.NoError(t, os.WriteFile(path, []byte(tt.template), 0o600))
+
+			tmpl, err := webhook.ParseTemplate(path)
+			require.NoError(t, err)
+
+			srv, requests := receiver(t)
+
+			err = webhook.New(srv.URL, webhook.WithTemplate(tmpl)).Notify(t.Context(), testRun)
+			require.NoError(t, err)
+
+			req := <-requests
+			assert.Equal(t, tt.contentType, req.contentType)
+			assert.Equal(t, tt.body, req.body)
+		})
+	}
+}
+
+func TestParseTemplate_Error(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "webhook.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{ .Status `), 0o600))
+
+	_, err := webhook.ParseTemplate(path)
+	require.Error(t, err)
+}
+
+func TestWebhook_Notify_Retry(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name     string
+		codes    []int
+		requests int
+		wantErr  bool
+	}{
+		{name: "server error", codes: []int{http.StatusBadGateway, http.StatusServiceUnavailable}, requests: 3},
+		{name: "rate limit", codes: []int{http.StatusTooManyRequests}, requests: 2},
+		{name: "attempts exhausted", codes: []int{500, 500, 500}, requests: 3, wantErr: true},
+		{name: "client error", codes: []int{http.StatusBadRequest}, requests: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv, requests := receiver(t, tt.codes...)
+
+			err := webhook.New(srv.URL, webhook.WithRetry(3, time.Millisecond)).Notify(t.Context(), testRun)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Len(t, requests, tt.requests)
+		})
+	}
+}
+
+func TestWebhook_Notify_Deadline(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := receiver(t, http.StatusBadGateway, http.StatusBadGateway)
+
+	start := time.Now()
+
+	err := webhook.New(srv.URL,
+		webhook.WithRetry(3, time.Hour),
+		webhook.WithDeadline(50*time.Millisecond),
+	).Notify(t.Context(), testRun)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, requests, 1)
+}
@@ -0,0 +1,86 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// handler masks the secrets in the messages and the attributes before the inner handler.
+type handler struct {
+	inner slog.Handler
+}
+
+// NewHandler returns the handler masking the secrets of the records, the values of sensitive attributes,
+// like "password" or "token", are masked as a whole.
+func NewHandler(inner slog.Handler) slog.Handler {
+	return handler{inner: inner}
+}
+
+func (h handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, String(r.Message), r.PC)
+
+	r.Attrs(func(a slog.Attr) bool {
+		masked.AddAttrs(attr(a))
+
+		return true
+	})
+
+	return h.inner.Handle(ctx, masked)
+}
+
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+
+	for i, a := range attrs {
+		masked[i] = attr(a)
+	}
+
+	return handler{inner: h.inner.WithAttrs(masked)}
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{inner: h.inner.WithGroup(name)}
+}
+
+func attr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() != slog.KindGroup && sensitiveKey(a.Key) {
+		return slog.String(a.Key, Mask)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(String(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		masked := make([]slog.Attr, len(group))
+
+		for i, g := range group {
+			masked[i] = attr(g)
+		}
+
+		a.Value = slog.GroupValue(masked...)
+	case slog.KindAny:
+		v := a.Value.Any()
+
+		if err, ok := v.(error); ok {
+			a.Value = slog.AnyValue(Error(err))
+
+			break
+		}
+
+		// The other values keep their type unless they contain a secret.
+		if s := fmt.Sprint(v); String(s) != s {
+			a.Value = slog.StringValue(String(s))
+		}
+	default:
+	}
+
+	return a
+}
@@ -0,0 +1,56 @@
+package redact_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+)
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	redact.Register("handler-secret")
+
+	var buf bytes.Buffer
+
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})
+
+	log := slog.New(redact.NewHandler(inner)).With("client_secret", "with-secret")
+
+	log.Debug("login with handler-secret",
+		"link", "https://cdn.example.com/book.epub?signature=abc123",
+		"password", "hunter2",
+		"error", errors.New("Bearer t0k3n rejected"),
+		slog.Group("request", "token", "t0k3n", "path", "/books"),
+		"signal", "terminated",
+		"accessToken", "t0k3n",
+		"tokens", 3,
+	)
+
+	assert.JSONEq(t, `{
+		"level": "DEBUG",
+		"msg": "login with REDACTED",
+		"client_secret": "REDACTED",
+		"link": "https://cdn.example.com/book.epub?signature=REDACTED",
+		"password": "REDACTED",
+		"error": "Bearer REDACTED rejected",
+		"request": {"token": "REDACTED", "path": "/books"},
+		"signal": "terminated",
+		"accessToken": "REDACTED",
+		"tokens": 3
+	}`, buf.String())
+}
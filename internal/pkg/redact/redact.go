@@ -0,0 +1,202 @@
+// Package redact masks secrets in log records and errors: registered values like passwords,
+// credentials in URLs, authorization headers and sensitive key=value pairs.
+package redact
+
+import (
+	"cmp"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Mask replaces the secrets.
+const Mask = "REDACTED"
+
+// minSecretLen is the length of the shortest registered secret,
+// the shorter values would mask unrelated text.
+const minSecretLen = 4
+
+var (
+	mu       sync.RWMutex
+	secrets  []string
+	replacer = strings.NewReplacer()
+)
+
+// Register adds the secret values masked wherever they appear, e.g. the password and the client secret.
+// The empty and too short values are ignored.
+func Register(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, v := range values {
+		if len(v) >= minSecretLen && !slices.Contains(secrets, v) {
+			secrets = append(secrets, v)
+		}
+	}
+
+	// The longer secrets first, so a secret containing another one is masked as a whole.
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	pairs := make([]string, 0, len(secrets)*2)
+
+	for _, s := range secrets {
+		pairs = append(pairs, s, Mask)
+	}
+
+	replacer = strings.NewReplacer(pairs...)
+}
+
+var (
+	urlRe  = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+	authRe = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-zA-Z0-9\-._~+/]+=*`)
+	pairRe = regexp.MustCompile(`(?i)\b([a-z0-9_-]+)(["']?\s*[=:]\s*["']?)[^\s"'&,;]+`)
+)
+
+// String masks the secrets in the text.
+func String(s string) string {
+	mu.RLock()
+	s = replacer.Replace(s)
+	mu.RUnlock()
+
+	s = urlRe.ReplaceAllStringFunc(s, func(u string) string {
+		trimmed := trimPunct(u)
+
+		return URL(trimmed) + u[len(trimmed):]
+	})
+	s = authRe.ReplaceAllString(s, "$1 "+Mask)
+
+	return pairRe.ReplaceAllStringFunc(s, func(pair string) string {
+		m := pairRe.FindStringSubmatch(pair)
+		if !secretName(m[1], pairWords) {
+			return pair
+		}
+
+		return m[1] + m[2] + Mask + pair[len(trimPunct(pair)):]
+	})
+}
+
+// trimPunct trims the punctuation after the value, e.g. "GET https://...: refused".
+func trimPunct(s string) string {
+	return strings.TrimRight(s, ".,:;)]")
+}
+
+// URL masks the password of the user info and the values of sensitive query parameters,
+// like tokens and signatures of pre-signed links. The order of the parameters is kept.
+func URL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	changed := false
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Mask)
+		changed = true
+	}
+
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+
+		for i, p := range params {
+			key, _, ok := strings.Cut(p, "=")
+			if ok && sensitiveParam(key) {
+				params[i] = key + "=" + Mask
+				changed = true
+			}
+		}
+
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	if !changed {
+		return raw
+	}
+
+	return u.String()
+}
+
+var (
+	// keyWords are the last words of the names of secrets, e.g. "client_secret" or "accessToken".
+	keyWords = []string{"pass", "password", "passwd", "secret", "token", "credential", "credentials", "authorization", "apikey"}
+	// pairWords are the same for the pairs in the text, the authorization header is masked by authRe keeping the scheme.
+	pairWords = []string{"password", "passwd", "secret", "token", "apikey"}
+	// paramWords are the same for the query parameters, including the signatures of pre-signed links
+	// like "sig" and "X-Amz-Signature".
+	paramWords = []string{
+		"pass", "password", "passwd", "secret", "token", "credential", "credentials", "authorization", "apikey",
+		"key", "auth", "session", "sig", "signature",
+	}
+)
+
+// sensitiveKey reports whether the key of an attribute denotes a secret.
+func sensitiveKey(name string) bool {
+	return secretName(name, keyWords)
+}
+
+// secretName reports whether the last word of the name is one of the words, so "tokens" or "secret_count" are not secrets.
+// "api_key" and "X-Api-Key" count as the word "apikey".
+func secretName(name string, words []string) bool {
+	parts := splitWords(name)
+	if len(parts) == 0 {
+		return false
+	}
+
+	last := parts[len(parts)-1]
+
+	if last == "key" && len(parts) > 1 && parts[len(parts)-2] == "api" {
+		last = "apikey"
+	}
+
+	return slices.Contains(words, last)
+}
+
+// splitWords splits the name into the lower-case words by the separators and the camel case.
+func splitWords(name string) []string {
+	var words []string
+
+	for _, field := range strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		start := 0
+		runes := []rune(field)
+
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+
+		words = append(words, strings.ToLower(string(runes[start:])))
+	}
+
+	return words
+}
+
+// sensitiveParam reports whether the query parameter denotes a secret, e.g. the signature of a pre-signed link.
+func sensitiveParam(name string) bool {
+	return secretName(name, paramWords)
+}
+
+// Error returns the error with the masked message, it unwraps to the original error.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return redactedError{err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return String(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,235 @@
+// Package report collects statistics of a synchronization run.
+package report
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+)
+
+// Counts of books processed by a synchronization run.
+type Counts struct {
+	// Total is how many books are found in the cloud.
+	Total int
+	// Skipped is how many books already exist in the directory.
+	Skipped int
+	// Downloaded is how many books are downloaded.
+	Downloaded int
+}
+
+// Status of a run in the reports, notifications and metrics.
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// Outcome of a book in a synchronization run.
+type Outcome string
+
+const (
+	OutcomeDownloaded Outcome = "downloaded"
+	// OutcomeSkipped is the book already existing in the directory.
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeFiltered is the book which can't be synced, e.g. without the download link.
+	OutcomeFiltered Outcome = "filtered"
+	OutcomeFailed   Outcome = "failed"
+)
+
+// Book is the outcome of a book by its file name.
+type Book struct {
+	Name    string
+	Outcome Outcome
+	// Reason why the book is filtered or failed.
+	Reason string
+	// Bytes and Duration of the download.
+	Bytes    int64
+	Duration time.Duration
+}
+
+// Collector accumulates the counts and the outcomes of books of a run, it is safe for concurrent use.
+type Collector struct {
+	mu     sync.Mutex
+	counts Counts
+	books  []Book
+	watch  func(Counts)
+}
+
+func (c *Collector) SetTotal(n int) {
+	if c == nil {
+		return
+	}
+
+	c.update(func() { c.counts.Total = n })
+}
+
+// Skip records the book existing in the directory by its file name.
+func (c *Collector) Skip(name string) {
+	if c == nil {
+		return
+	}
+
+	c.update(func() {
+		c.counts.Skipped++
+		c.books = append(c.books, Book{Name: name, Outcome: OutcomeSkipped})
+	})
+}
+
+// Download records the downloaded book by its file name, its size and the time of the download.
+func (c *Collector) Download(name string, size int64, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	c.update(func() {
+		c.counts.Downloaded++
+		c.books = append(c.books, Book{Name: name, Outcome: OutcomeDownloaded, Bytes: size, Duration: d})
+	})
+}
+
+// Filter records the book which can't be synced, it isn't counted in the total.
+func (c *Collector) Filter(name, reason string) {
+	if c == nil {
+		return
+	}
+
+	c.update(func() {
+		c.books = append(c.books, Book{Name: name, Outcome: OutcomeFiltered, Reason: reason})
+	})
+}
+
+// Fail records the book failed to download.
+func (c *Collector) Fail(name string, err error) {
+	if c == nil {
+		return
+	}
+
+	c.update(func() {
+		c.books = append(c.books, Book{Name: name, Outcome: OutcomeFailed, Reason: redact.String(err.Error())})
+	})
+}
+
+// update changes the collector and passes the new counts to the watcher.
+func (c *Collector) update(fn func()) {
+	c.mu.Lock()
+	fn()
+	counts := c.counts
+	c.mu.Unlock()
+
+	if c.watch != nil {
+		c.watch(counts)
+	}
+}
+
+func (c *Collector) Counts() Counts {
+	if c == nil {
+		return Counts{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.counts
+}
+
+// Books returns file names of the downloaded books.
+func (c *Collector) Books() []string {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var names []string
+
+	for _, b := range c.books {
+		if b.Outcome == OutcomeDownloaded {
+			names = append(names, b.Name)
+		}
+	}
+
+	return names
+}
+
+// Results returns the outcomes of all recorded books in the order of recording.
+func (c *Collector) Results() []Book {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.books)
+}
+
+type (
+	collectorKey struct{}
+	watcherKey   struct{}
+)
+
+// NewContext returns the context carrying the collector for the synchronization.
+func NewContext(ctx context.Context, c *Collector) context.Context {
+	return context.WithValue(ctx, collectorKey{}, c)
+}
+
+// FromContext returns the collector of the context or nil.
+func FromContext(ctx context.Context) *Collector {
+	c, _ := ctx.Value(collectorKey{}).(*Collector)
+
+	return c
+}
+
+// WithWatcher returns the context making [Collect] pass the counts to the function
+// at the start of each run and after each change, e.g. to show the progress.
+func WithWatcher(ctx context.Context, watch func(Counts)) context.Context {
+	return context.WithValue(ctx, watcherKey{}, watch)
+}
+
+// Run is the result of a synchronization run.
+type Run struct {
+	// ID identifies the run in the logs.
+	ID    string
+	Start time.Time
+	End   time.Time
+	// Err is nil for the successful run, its message has the secrets masked by [redact.Error].
+	Err error
+	Counts
+	// NewBooks are file names of the downloaded books.
+	NewBooks []string
+	// Books are the outcomes of all books.
+	Books []Book
+}
+
+// Collect runs the synchronization with the collector in the context and returns its report.
+// The records logged by the synchronization have the ID of the run.
+func Collect(ctx context.Context, clk clock.Clock, sync func(ctx context.Context) error) Run {
+	c := &Collector{}
+	run := Run{ID: newID(), Start: clk.Now()}
+	ctx = logging.WithAttrs(ctx, logging.RunID(run.ID))
+
+	if watch, ok := ctx.Value(watcherKey{}).(func(Counts)); ok {
+		c.watch = watch
+		watch(Counts{})
+	}
+
+	run.Err = redact.Error(sync(NewContext(ctx, c)))
+	run.End = clk.Now()
+	run.Counts, run.NewBooks, run.Books = c.Counts(), c.Books(), c.Results()
+
+	return run
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,120 @@
+package report_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := &report.Collector{}
+	ctx := report.NewContext(t.Context(), c)
+
+	rep := report.FromContext(ctx)
+	rep.SetTotal(3)
+	rep.Skip("exist.txt")
+	rep.Download("first.txt", 1024, time.Second)
+	rep.Download("second.txt", 2048, 2*time.Second)
+	rep.Filter("no-link.txt", "link is empty")
+	rep.Fail("third.txt", errors.New("http GET https://cdn.example.com/third.txt?signature=abc: refused"))
+
+	assert.Equal(t, report.Counts{Total: 3, Skipped: 1, Downloaded: 2}, c.Counts())
+	assert.Equal(t, []string{"first.txt", "second.txt"}, c.Books())
+	assert.Equal(t, []report.Book{
+		{Name: "exist.txt", Outcome: report.OutcomeSkipped},
+		{Name: "first.txt", Outcome: report.OutcomeDownloaded, Bytes: 1024, Duration: time.Second},
+		{Name: "second.txt", Outcome: report.OutcomeDownloaded, Bytes: 2048, Duration: 2 * time.Second},
+		{Name: "no-link.txt", Outcome: report.OutcomeFiltered, Reason: "link is empty"},
+		{Name: "third.txt", Outcome: report.OutcomeFailed, Reason: "http GET https://cdn.example.com/third.txt?signature=REDACTED: refused"},
+	}, c.Results())
+}
+
+func TestCollector_Nil(t *testing.T) {
+	t.Parallel()
+
+	rep := report.FromContext(t.Context())
+	assert.Nil(t, rep)
+
+	rep.SetTotal(3)
+	rep.Skip("exist.txt")
+	rep.Download("first.txt", 0, 0)
+
+	assert.Equal(t, report.Counts{}, rep.Counts())
+	assert.Nil(t, rep.Books())
+}
+
+func TestCollect(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	fake := clock.NewFake(now)
+	errSync := errors.New("http GET https://cdn.example.com/book.epub?signature=abc: connection refused")
+
+	var buf bytes.Buffer
+
+	log := slog.New(logging.NewHandler(&buf, logging.FormatLogfmt, slog.LevelInfo))
+
+	run := report.Collect(t.Context(), fake, func(ctx context.Context) error {
+		log.InfoContext(ctx, "sync")
+
+		rep := report.FromContext(ctx)
+		rep.SetTotal(2)
+		rep.Download("first.txt", 1024, time.Second)
+
+		fake.Advance(time.Minute)
+
+		return errSync
+	})
+
+	require.ErrorIs(t, run.Err, errSync)
+	require.EqualError(t, run.Err, "http GET https://cdn.example.com/book.epub?signature=REDACTED: connection refused")
+
+	assert.Len(t, run.ID, 16)
+	assert.Contains(t, buf.String(), "run_id="+run.ID)
+
+	assert.Equal(t, report.Run{
+		ID:       run.ID,
+		Start:    now,
+		End:      now.Add(time.Minute),
+		Err:      run.Err,
+		Counts:   report.Counts{Total: 2, Downloaded: 1},
+		NewBooks: []string{"first.txt"},
+		Books:    []report.Book{{Name: "first.txt", Outcome: report.OutcomeDownloaded, Bytes: 1024, Duration: time.Second}},
+	}, run)
+}
+
+func TestCollect_Watcher(t *testing.T) {
+	t.Parallel()
+
+	var got []report.Counts
+
+	ctx := report.WithWatcher(t.Context(), func(c report.Counts) { got = append(got, c) })
+
+	report.Collect(ctx, clock.Real{}, func(ctx context.Context) error {
+		rep := report.FromContext(ctx)
+		rep.SetTotal(2)
+		rep.Skip("exist.txt")
+		rep.Download("first.txt", 0, 0)
+
+		return nil
+	})
+
+	assert.Equal(t, []report.Counts{
+		{},
+		{Total: 2},
+		{Total: 2, Skipped: 1},
+		{Total: 2, Skipped: 1, Downloaded: 1},
+	}, got)
+}
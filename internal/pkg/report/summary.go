@@ -0,0 +1,71 @@
+package report
+
+import "time"
+
+// Summary is the machine-readable report of the run for auditing and scripting.
+type Summary struct {
+	RunID      string    `json:"run_id"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMS int64     `json:"duration_ms"`
+	// Config is the fingerprint of the configuration without secrets, it changes with the settings.
+	Config     string        `json:"config"`
+	Total      int           `json:"total"`
+	Skipped    int           `json:"skipped"`
+	Downloaded int           `json:"downloaded"`
+	Filtered   int           `json:"filtered"`
+	Failed     int           `json:"failed"`
+	Bytes      int64         `json:"bytes"`
+	Books      []BookSummary `json:"books"`
+}
+
+type BookSummary struct {
+	Name       string  `json:"name"`
+	Outcome    Outcome `json:"outcome"`
+	Reason     string  `json:"reason,omitempty"`
+	Bytes      int64   `json:"bytes,omitempty"`
+	DurationMS int64   `json:"duration_ms,omitempty"`
+}
+
+// NewSummary converts the report of the run made with the configuration by its fingerprint.
+func NewSummary(run Run, config string) Summary {
+	s := Summary{
+		RunID:      run.ID,
+		Status:     StatusSuccess,
+		Start:      run.Start,
+		End:        run.End,
+		DurationMS: run.End.Sub(run.Start).Milliseconds(),
+		Config:     config,
+		Total:      run.Total,
+		Skipped:    run.Skipped,
+		Downloaded: run.Downloaded,
+		Books:      make([]BookSummary, 0, len(run.Books)),
+	}
+
+	if run.Err != nil {
+		s.Status, s.Error = StatusError, run.Err.Error()
+	}
+
+	for _, b := range run.Books {
+		switch b.Outcome {
+		case OutcomeFiltered:
+			s.Filtered++
+		case OutcomeFailed:
+			s.Failed++
+		default:
+		}
+
+		s.Bytes += b.Bytes
+		s.Books = append(s.Books, BookSummary{
+			Name:       b.Name,
+			Outcome:    b.Outcome,
+			Reason:     b.Reason,
+			Bytes:      b.Bytes,
+			DurationMS: b.Duration.Milliseconds(),
+		})
+	}
+
+	return s
+}
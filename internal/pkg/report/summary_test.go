@@ -0,0 +1,67 @@
+package report_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
+)
+
+func TestNewSummary(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, time.February, 24, 3, 0, 0, 0, time.UTC)
+
+	run := report.Run{
+		ID:     "0123456789abcdef",
+		Start:  start,
+		End:    start.Add(90 * time.Second),
+		Err:    errors.New("download third.txt: http status code: 503 Service Unavailable"),
+		Counts: report.Counts{Total: 3, Skipped: 1, Downloaded: 1},
+		Books: []report.Book{
+			{Name: "exist.txt", Outcome: report.OutcomeSkipped},
+			{Name: "first.txt", Outcome: report.OutcomeDownloaded, Bytes: 2048, Duration: 1500 * time.Millisecond},
+			{Name: "no-link.txt", Outcome: report.OutcomeFiltered, Reason: "link is empty"},
+			{Name: "third.txt", Outcome: report.OutcomeFailed, Reason: "http status code: 503 Service Unavailable"},
+		},
+	}
+
+	data, err := json.Marshal(report.NewSummary(run, "cafe"))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"run_id": "0123456789abcdef",
+		"status": "error",
+		"error": "download third.txt: http status code: 503 Service Unavailable",
+		"start": "2025-02-24T03:00:00Z",
+		"end": "2025-02-24T03:01:30Z",
+		"duration_ms": 90000,
+		"config": "cafe",
+		"total": 3,
+		"skipped": 1,
+		"downloaded": 1,
+		"filtered": 1,
+		"failed": 1,
+		"bytes": 2048,
+		"books": [
+			{"name": "exist.txt", "outcome": "skipped"},
+			{"name": "first.txt", "outcome": "downloaded", "bytes": 2048, "duration_ms": 1500},
+			{"name": "no-link.txt", "outcome": "filtered", "reason": "link is empty"},
+			{"name": "third.txt", "outcome": "failed", "reason": "http status code: 503 Service Unavailable"}
+		]
+	}`, string(data))
+}
+
+func TestNewSummary_Empty(t *testing.T) {
+	t.Parallel()
+
+	s := report.NewSummary(report.Run{}, "")
+
+	assert.Equal(t, report.StatusSuccess, s.Status)
+	assert.NotNil(t, s.Books, "the books are the empty array in JSON")
+}
@@ -1,14 +1,22 @@
-//go:generate mockgen -source $GOFILE -typed -destination mocks/$GOFILE -package mocks -typed -mock_names client=Client
+//go:generate mockgen -source $GOFILE -typed -destination mocks/$GOFILE -package mocks -typed -mock_names client=Client,tokens=Tokens
 package books
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"sync"
+	"time"
 
 	pbclient "github.com/micronull/pocketbook-cloud-client"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/logging"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/redact"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
 )
 
 type client interface {
@@ -17,77 +25,306 @@ type client interface {
 	Books(ctx context.Context, token string, limit, offset int) (pbclient.Books, error)
 }
 
+type tokens interface {
+	Token(key string) (pbclient.Token, bool)
+	Save(key string, token pbclient.Token) error
+}
+
+const (
+	// tokenExpiryMargin is how long before the expiration a cached token is no longer used.
+	tokenExpiryMargin = time.Minute
+	// DefaultConcurrency is how many providers are processed at the same time by default.
+	DefaultConcurrency = 4
+)
+
 type Repository struct {
-	client client
-	login  string
-	pswd   string
+	client      client
+	login       string
+	pswd        string
+	token       string
+	tokens      tokens
+	providers   providerFilter
+	concurrency int
+	metrics     *metrics.Metrics
 }
 
-func New(client client, login, password string) *Repository {
-	return &Repository{
-		client: client,
-		login:  login,
-		pswd:   password,
+func New(client client, login, password string, opts ...Option) *Repository {
+	r := &Repository{
+		client:      client,
+		login:       login,
+		pswd:        password,
+		concurrency: DefaultConcurrency,
 	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	return r
 }
 
 func (r Repository) Books(ctx context.Context) ([]domain.Book, error) {
+	start := time.Now()
+
 	providers, err := r.client.Providers(ctx, r.login)
-	if err != nil {
+	if err = r.observe(err); err != nil {
 		return nil, fmt.Errorf("get providers: %w", err)
 	}
 
-	books := make([]domain.Book, 0)
+	selected := make([]pbclient.Provider, 0, len(providers))
 
 	for i := 0; i < len(providers); i++ {
 		provider := providers[i]
 
-		token, err := r.client.Login(ctx, pbclient.LoginRequest{
-			ShopID:   provider.ShopID,
-			UserName: r.login,
-			Password: r.pswd,
-			Provider: provider.Alias,
+		if reason, skip := r.providers.skip(provider); skip {
+			slog.DebugContext(ctx, "provider skipped",
+				"reason", reason,
+				logging.Provider(provider.ShopID),
+				"provider_name", provider.Name,
+				"provider_alias", provider.Alias,
+			)
+
+			continue
+		}
+
+		selected = append(selected, provider)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([][]domain.Book, len(selected))
+	sem := make(chan struct{}, max(r.concurrency, 1))
+
+	var wg sync.WaitGroup
+
+	for i, provider := range selected {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			bks, err := r.providerBooks(ctx, provider)
+			if err != nil {
+				cancel(fmt.Errorf("provider %s: %w", provider.ShopID, err))
+
+				return
+			}
+
+			results[i] = bks
+		}()
+	}
+
+	wg.Wait()
+
+	if err = context.Cause(ctx); err != nil {
+		return nil, err
+	}
+
+	books := make([]domain.Book, 0)
+
+	for _, bks := range results {
+		books = append(books, bks...)
+	}
+
+	r.metrics.BooksListed(len(books), time.Since(start))
+
+	return books, nil
+}
+
+func (r Repository) providerBooks(ctx context.Context, provider pbclient.Provider) ([]domain.Book, error) {
+	ctx = logging.WithAttrs(ctx, logging.Provider(provider.ShopID))
+	s := &session{repo: r, provider: provider}
+
+	pbooks, err := s.books(ctx, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get books count: %w", err)
+	}
+
+	slog.DebugContext(ctx, "books",
+		"total", pbooks.Total,
+		"provider_name", provider.Name,
+		"provider_alias", provider.Alias,
+	)
+
+	if pbooks.Total == 0 {
+		return nil, nil
+	}
+
+	pbks, err := s.pages(ctx, pbooks.Total)
+	if err != nil {
+		return nil, fmt.Errorf("get books: %w", err)
+	}
+
+	books := make([]domain.Book, 0, len(pbks))
+
+	for n := 0; n < len(pbks); n++ {
+		pbook := pbks[n]
+
+		if pbook.Link == "" {
+			slog.WarnContext(ctx, "book link is empty", "book_id", pbook.ID, logging.Book(pbook.Name))
+
+			report.FromContext(ctx).Filter(pbook.Name, "link is empty")
+
+			continue
+		}
+
+		books = append(books, domain.Book{
+			ID:       pbook.ID,
+			FileName: pbook.Name,
+			Link:     pbook.Link,
+			Provider: provider.Name,
+			Format:   pbook.Format,
+			Size:     int64(pbook.Bytes),
+			Updated:  pbook.Mtime,
 		})
+	}
+
+	return books, nil
+}
+
+// session requests books of a single provider.
+// It reuses a cached or pre-obtained token and logs in with the password only when there is no usable token.
+type session struct {
+	repo     Repository
+	provider pbclient.Provider
+	token    string
+	authed   bool
+	// fresh is true when the token has just been received by login.
+	fresh bool
+}
+
+// pages requests the books page by page, the cloud may return fewer books than asked.
+// An empty page ends the listing before the total to not loop on a shrinking library.
+func (s *session) pages(ctx context.Context, total int) ([]pbclient.Book, error) {
+	pbks := make([]pbclient.Book, 0, total)
+
+	for len(pbks) < total {
+		page, err := s.books(ctx, total-len(pbks), len(pbks))
 		if err != nil {
-			return nil, fmt.Errorf("login: %w", err)
+			return nil, err
 		}
 
-		pbooks, err := r.client.Books(ctx, token.AccessToken, 0, 0)
-		if err != nil {
-			return nil, fmt.Errorf("get books count: %w", err)
+		if len(page.Books) == 0 {
+			break
 		}
 
-		slog.Debug("books",
-			"total", pbooks.Total,
-			"provider_shop_id", provider.ShopID,
-			"provider_name", provider.Name,
-			"provider_alias", provider.Alias,
-		)
+		pbks = append(pbks, page.Books...)
+	}
 
-		if pbooks.Total == 0 {
-			continue
+	return pbks, nil
+}
+
+func (s *session) books(ctx context.Context, limit, offset int) (pbclient.Books, error) {
+	if !s.authed {
+		if err := s.auth(ctx); err != nil {
+			return pbclient.Books{}, err
 		}
 
-		pbooks, err = r.client.Books(ctx, token.AccessToken, pbooks.Total, 0)
-		if err != nil {
-			return nil, fmt.Errorf("get books: %w", err)
+		s.authed = true
+	}
+
+	pbooks, err := s.repo.client.Books(ctx, s.token, limit, offset)
+	if err = s.repo.observe(err); err == nil || s.fresh || s.repo.pswd == "" || !isUnauthorized(err) {
+		return pbooks, err
+	}
+
+	slog.DebugContext(ctx, "token is rejected, login again", "error", err)
+
+	if err = s.loginWithPassword(ctx); err != nil {
+		return pbclient.Books{}, err
+	}
+
+	pbooks, err = s.repo.client.Books(ctx, s.token, limit, offset)
+
+	return pbooks, s.repo.observe(err)
+}
+
+// auth takes the valid cached token, then the pre-obtained one and logs in with the password without them.
+// The client can't refresh tokens, so the token of the login replaces the expired or rejected one in the cache
+// and is used instead of the stale pre-obtained token by the next runs.
+func (s *session) auth(ctx context.Context) error {
+	if s.repo.tokens != nil {
+		if token, ok := s.repo.tokens.Token(s.key()); ok && token.ExpiresIn.After(time.Now().Add(tokenExpiryMargin)) {
+			slog.DebugContext(ctx, "use cached token", "expires", token.ExpiresIn)
+
+			s.token = token.AccessToken
+			redact.Register(token.AccessToken, token.RefreshToken)
+
+			return nil
 		}
+	}
 
-		for n := 0; n < len(pbooks.Books); n++ {
-			pbook := pbooks.Books[n]
+	if s.repo.token != "" {
+		s.token = s.repo.token
 
-			if pbook.Link == "" {
-				slog.Warn("book link is empty", "book_id", pbook.ID, "book_name", pbook.Name)
+		return nil
+	}
 
-				continue
-			}
+	return s.loginWithPassword(ctx)
+}
+
+func (s *session) loginWithPassword(ctx context.Context) error {
+	slog.DebugContext(ctx, "login", "provider_alias", s.provider.Alias)
 
-			books = append(books, domain.Book{
-				FileName: pbook.Name,
-				Link:     pbook.Link,
-			})
+	token, err := s.repo.client.Login(ctx, pbclient.LoginRequest{
+		ShopID:   s.provider.ShopID,
+		UserName: s.repo.login,
+		Password: s.repo.pswd,
+		Provider: s.provider.Alias,
+	})
+	if err = s.repo.observe(err); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	s.token = token.AccessToken
+	s.fresh = true
+
+	redact.Register(token.AccessToken, token.RefreshToken)
+
+	if s.repo.tokens != nil {
+		if err = s.repo.tokens.Save(s.key(), token); err != nil {
+			slog.WarnContext(ctx, "failed to cache token", "error", err)
 		}
 	}
 
-	return books, nil
+	return nil
+}
+
+func (s *session) key() string {
+	return s.repo.login + "/" + s.provider.ShopID
+}
+
+// observe counts the error response of the API in the metrics.
+func (r Repository) observe(err error) error {
+	if code, ok := statusCode(err); ok {
+		r.metrics.APIError(code)
+	}
+
+	return err
+}
+
+func isUnauthorized(err error) bool {
+	code, ok := statusCode(err)
+
+	return ok && code == http.StatusUnauthorized
+}
+
+func statusCode(err error) (int, bool) {
+	var httpErr interface {
+		Code() int
+	}
+
+	if !errors.As(err, &httpErr) {
+		return 0, false
+	}
+
+	return httpErr.Code(), true
 }
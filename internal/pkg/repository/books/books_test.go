@@ -1,9 +1,13 @@
 package books_test
 
 import (
+	"context"
 	"errors"
-	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	pbclient "github.com/micronull/pocketbook-cloud-client"
 	"github.com/stretchr/testify/assert"
@@ -11,6 +15,8 @@ import (
 	"go.uber.org/mock/gomock"
 
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/domain"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/report"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/books"
 	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/books/mocks"
 )
@@ -32,6 +38,7 @@ func TestRepository_Books(t *testing.T) {
 		Return([]pbclient.Provider{
 			{
 				Alias:  "provider-1",
+				Name:   "Provider 1",
 				ShopID: "1",
 			},
 			{
@@ -58,33 +65,33 @@ func TestRepository_Books(t *testing.T) {
 		).
 		Return(pbclient.Token{AccessToken: "token-2"}, nil)
 
-	nr := rand.N(100)
-
 	gomock.InOrder(
 		clientMock.EXPECT().
 			Books(gomock.Any(), "token-1", 0, 0).
-			Return(pbclient.Books{Total: nr}, nil),
+			Return(pbclient.Books{Total: 1}, nil),
 		clientMock.EXPECT().
-			Books(gomock.Any(), "token-1", nr, 0).
+			Books(gomock.Any(), "token-1", 1, 0).
 			Return(pbclient.Books{
 				Total: 1,
 				Books: []pbclient.Book{
 					{
-						Link: "https://example.com/first.txt",
-						Name: "first.txt",
+						ID:     "1-1",
+						Link:   "https://example.com/first.txt",
+						Name:   "first.txt",
+						Format: "txt",
+						Bytes:  1024,
+						Mtime:  time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC),
 					},
 				},
 			}, nil),
 	)
 
-	nr = rand.N(100)
-
 	gomock.InOrder(
 		clientMock.EXPECT().
 			Books(gomock.Any(), "token-2", 0, 0).
-			Return(pbclient.Books{Total: nr}, nil),
+			Return(pbclient.Books{Total: 1}, nil),
 		clientMock.EXPECT().
-			Books(gomock.Any(), "token-2", nr, 0).
+			Books(gomock.Any(), "token-2", 1, 0).
 			Return(pbclient.Books{
 				Total: 1,
 				Books: []pbclient.Book{
@@ -101,8 +108,13 @@ func TestRepository_Books(t *testing.T) {
 
 	expected := []domain.Book{
 		{
+			ID:       "1-1",
 			FileName: "first.txt",
 			Link:     "https://example.com/first.txt",
+			Provider: "Provider 1",
+			Format:   "txt",
+			Size:     1024,
+			Updated:  time.Date(2025, time.March, 10, 12, 0, 0, 0, time.UTC),
 		},
 		{
 			FileName: "second.txt",
@@ -113,6 +125,69 @@ func TestRepository_Books(t *testing.T) {
 	require.Equal(t, expected, got)
 }
 
+func TestRepository_Books_Pages(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		last   []pbclient.Book
+		expect []domain.Book
+	}{
+		{
+			name: "total",
+			last: []pbclient.Book{{Name: "3.txt", Link: "https://example.com/3"}},
+			expect: []domain.Book{
+				{FileName: "1.txt", Link: "https://example.com/1"},
+				{FileName: "2.txt", Link: "https://example.com/2"},
+				{FileName: "3.txt", Link: "https://example.com/3"},
+			},
+		},
+		{
+			name: "empty page",
+			expect: []domain.Book{
+				{FileName: "1.txt", Link: "https://example.com/1"},
+				{FileName: "2.txt", Link: "https://example.com/2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			clientMock := mocks.NewClient(mockCtrl)
+			repo := books.New(clientMock, "", "", books.WithToken("token"))
+
+			clientMock.EXPECT().
+				Providers(gomock.Any(), gomock.Any()).
+				Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+			gomock.InOrder(
+				clientMock.EXPECT().
+					Books(gomock.Any(), "token", 0, 0).
+					Return(pbclient.Books{Total: 3}, nil),
+				clientMock.EXPECT().
+					Books(gomock.Any(), "token", 3, 0).
+					Return(pbclient.Books{
+						Total: 3,
+						Books: []pbclient.Book{
+							{Name: "1.txt", Link: "https://example.com/1"},
+							{Name: "2.txt", Link: "https://example.com/2"},
+						},
+					}, nil),
+				clientMock.EXPECT().
+					Books(gomock.Any(), "token", 1, 2).
+					Return(pbclient.Books{Total: 3, Books: tt.last}, nil),
+			)
+
+			got, err := repo.Books(t.Context())
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, got)
+		})
+	}
+}
+
 var errStub = errors.New("stub error")
 
 func TestRepository_Books_Error_Provider(t *testing.T) {
@@ -296,6 +371,473 @@ func TestRepository_Books_EmptyLink(t *testing.T) {
 			},
 		}, nil)
 
+	collector := &report.Collector{}
+
+	bks, err := repo.Books(report.NewContext(t.Context(), collector))
+	require.NoError(t, err)
+	assert.Empty(t, bks)
+	assert.Equal(t, []report.Book{{Name: "unknown.txt", Outcome: report.OutcomeFiltered, Reason: "link is empty"}}, collector.Results())
+}
+
+func TestRepository_Books_Providers(t *testing.T) {
+	t.Parallel()
+
+	providers := []pbclient.Provider{
+		{Alias: "provider-1", ShopID: "1"},
+		{Alias: "provider-2", ShopID: "2"},
+		{Alias: "provider-3", ShopID: "3"},
+	}
+
+	tests := [...]struct {
+		name     string
+		filter   []string
+		expected []string
+	}{
+		{
+			name:     "all",
+			filter:   nil,
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "include by shop id and alias",
+			filter:   []string{"1", "Provider-3"},
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "exclude",
+			filter:   []string{"-provider-2"},
+			expected: []string{"1", "3"},
+		},
+		{
+			name:     "include and exclude",
+			filter:   []string{"1", "2", "-2"},
+			expected: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			clientMock := mocks.NewClient(mockCtrl)
+			repo := books.New(clientMock, "", "", books.WithProviders(tt.filter))
+
+			clientMock.EXPECT().
+				Providers(gomock.Any(), gomock.Any()).
+				Return(providers, nil)
+
+			var (
+				mu  sync.Mutex
+				got []string
+			)
+
+			clientMock.EXPECT().
+				Login(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, req pbclient.LoginRequest) (pbclient.Token, error) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					got = append(got, req.ShopID)
+
+					return pbclient.Token{}, nil
+				}).
+				Times(len(tt.expected))
+
+			clientMock.EXPECT().
+				Books(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				Return(pbclient.Books{}, nil).
+				Times(len(tt.expected))
+
+			_, err := repo.Books(t.Context())
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tt.expected, got)
+		})
+	}
+}
+
+type httpErrorMock struct {
+	code int
+}
+
+func (e httpErrorMock) Error() string {
+	return "http error"
+}
+
+func (e httpErrorMock) Code() int {
+	return e.code
+}
+
+func TestRepository_Books_CachedToken(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	tokensMock := mocks.NewTokens(mockCtrl)
+	repo := books.New(clientMock, "login", "password", books.WithTokens(tokensMock))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	tokensMock.EXPECT().
+		Token("login/1").
+		Return(pbclient.Token{AccessToken: "cached", ExpiresIn: time.Now().Add(time.Hour)}, true)
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "cached", 0, 0).
+		Return(pbclient.Books{}, nil)
+
+	_, err := repo.Books(t.Context())
+	require.NoError(t, err)
+}
+
+func TestRepository_Books_CachedToken_Expired(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	tokensMock := mocks.NewTokens(mockCtrl)
+	repo := books.New(clientMock, "login", "password", books.WithTokens(tokensMock))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	tokensMock.EXPECT().
+		Token("login/1").
+		Return(pbclient.Token{AccessToken: "cached", ExpiresIn: time.Now()}, true)
+
+	token := pbclient.Token{AccessToken: "new", ExpiresIn: time.Now().Add(time.Hour)}
+
+	clientMock.EXPECT().
+		Login(gomock.Any(), gomock.Any()).
+		Return(token, nil)
+
+	tokensMock.EXPECT().
+		Save("login/1", token).
+		Return(nil)
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "new", 0, 0).
+		Return(pbclient.Books{}, nil)
+
+	_, err := repo.Books(t.Context())
+	require.NoError(t, err)
+}
+
+func TestRepository_Books_CachedToken_Unauthorized(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	tokensMock := mocks.NewTokens(mockCtrl)
+	repo := books.New(clientMock, "login", "password", books.WithTokens(tokensMock))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	tokensMock.EXPECT().
+		Token("login/1").
+		Return(pbclient.Token{AccessToken: "cached", ExpiresIn: time.Now().Add(time.Hour)}, true)
+
+	token := pbclient.Token{AccessToken: "new", ExpiresIn: time.Now().Add(time.Hour)}
+
+	gomock.InOrder(
+		clientMock.EXPECT().
+			Books(gomock.Any(), "cached", 0, 0).
+			Return(pbclient.Books{}, httpErrorMock{code: http.StatusUnauthorized}),
+		clientMock.EXPECT().
+			Login(gomock.Any(), gomock.Any()).
+			Return(token, nil),
+		tokensMock.EXPECT().
+			Save("login/1", token).
+			Return(nil),
+		clientMock.EXPECT().
+			Books(gomock.Any(), "new", 0, 0).
+			Return(pbclient.Books{}, nil),
+	)
+
 	_, err := repo.Books(t.Context())
 	require.NoError(t, err)
 }
+
+func TestRepository_Books_Token(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	repo := books.New(clientMock, "login", "", books.WithToken("pre-obtained"))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "pre-obtained", 0, 0).
+		Return(pbclient.Books{}, nil)
+
+	_, err := repo.Books(t.Context())
+	require.NoError(t, err)
+}
+
+func TestRepository_Books_Token_Cached(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		name   string
+		cached pbclient.Token
+		ok     bool
+		expect string
+	}{
+		{
+			name:   "valid cached token",
+			cached: pbclient.Token{AccessToken: "cached", ExpiresIn: time.Now().Add(time.Hour)},
+			ok:     true,
+			expect: "cached",
+		},
+		{
+			name:   "expired cached token",
+			cached: pbclient.Token{AccessToken: "cached", ExpiresIn: time.Now().Add(-time.Hour)},
+			ok:     true,
+			expect: "pre-obtained",
+		},
+		{
+			name:   "no cached token",
+			expect: "pre-obtained",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockCtrl := gomock.NewController(t)
+			clientMock := mocks.NewClient(mockCtrl)
+			tokensMock := mocks.NewTokens(mockCtrl)
+			repo := books.New(clientMock, "login", "password", books.WithToken("pre-obtained"), books.WithTokens(tokensMock))
+
+			clientMock.EXPECT().
+				Providers(gomock.Any(), gomock.Any()).
+				Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+			tokensMock.EXPECT().
+				Token("login/1").
+				Return(tt.cached, tt.ok)
+
+			clientMock.EXPECT().
+				Books(gomock.Any(), tt.expect, 0, 0).
+				Return(pbclient.Books{}, nil)
+
+			_, err := repo.Books(t.Context())
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRepository_Books_Token_Stale(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	tokensMock := mocks.NewTokens(mockCtrl)
+	repo := books.New(clientMock, "login", "password", books.WithToken("stale"), books.WithTokens(tokensMock))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil).
+		Times(2)
+
+	// The first run finds no cached token, the stale token is rejected and the login token is cached.
+	tokensMock.EXPECT().
+		Token("login/1").
+		Return(pbclient.Token{}, false)
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "stale", 0, 0).
+		Return(pbclient.Books{}, httpErrorMock{code: http.StatusUnauthorized})
+
+	token := pbclient.Token{AccessToken: "logged-in", ExpiresIn: time.Now().Add(time.Hour)}
+
+	clientMock.EXPECT().
+		Login(gomock.Any(), gomock.Any()).
+		Return(token, nil)
+
+	tokensMock.EXPECT().
+		Save("login/1", token).
+		Return(nil)
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "logged-in", 0, 0).
+		Return(pbclient.Books{}, nil).
+		Times(2)
+
+	_, err := repo.Books(t.Context())
+	require.NoError(t, err)
+
+	// The next run uses the cached token without sending the stale one.
+	tokensMock.EXPECT().
+		Token("login/1").
+		Return(token, true)
+
+	_, err = repo.Books(t.Context())
+	require.NoError(t, err)
+}
+
+func TestRepository_Books_Token_Unauthorized(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	repo := books.New(clientMock, "login", "", books.WithToken("pre-obtained"))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	errExpected := httpErrorMock{code: http.StatusUnauthorized}
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), "pre-obtained", 0, 0).
+		Return(pbclient.Books{}, errExpected)
+
+	_, err := repo.Books(t.Context())
+	require.ErrorIs(t, err, errExpected)
+}
+
+func TestRepository_Books_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	repo := books.New(clientMock, "", "", books.WithConcurrency(2))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}, {ShopID: "2"}}, nil)
+
+	clientMock.EXPECT().
+		Login(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req pbclient.LoginRequest) (pbclient.Token, error) {
+			return pbclient.Token{AccessToken: req.ShopID}, nil
+		}).
+		Times(2)
+
+	secondDone := make(chan struct{})
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, token string, limit, _ int) (pbclient.Books, error) {
+			// The first provider answers only after the second one, the result order must be kept.
+			switch {
+			case token == "1" && limit == 0:
+				<-secondDone
+			case token == "2" && limit > 0:
+				defer close(secondDone)
+			}
+
+			return pbclient.Books{
+				Total: 1,
+				Books: []pbclient.Book{{Name: token + ".txt", Link: "https://example.com/" + token}},
+			}, nil
+		}).
+		Times(4)
+
+	got, err := repo.Books(t.Context())
+	require.NoError(t, err)
+
+	expected := []domain.Book{
+		{FileName: "1.txt", Link: "https://example.com/1"},
+		{FileName: "2.txt", Link: "https://example.com/2"},
+	}
+
+	require.Equal(t, expected, got)
+}
+
+func TestRepository_Books_Concurrency_Error(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	repo := books.New(clientMock, "", "", books.WithConcurrency(2))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}, {ShopID: "2"}}, nil)
+
+	clientMock.EXPECT().
+		Login(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req pbclient.LoginRequest) (pbclient.Token, error) {
+			return pbclient.Token{AccessToken: req.ShopID}, nil
+		}).
+		Times(2)
+
+	secondStarted := make(chan struct{})
+
+	clientMock.EXPECT().
+		Books(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, token string, _, _ int) (pbclient.Books, error) {
+			if token == "1" {
+				<-secondStarted
+
+				return pbclient.Books{}, errStub
+			}
+
+			close(secondStarted)
+
+			// The second provider hangs until the context is canceled.
+			<-ctx.Done()
+
+			return pbclient.Books{}, ctx.Err()
+		}).
+		Times(2)
+
+	_, err := repo.Books(t.Context())
+	require.ErrorIs(t, err, errStub)
+	require.ErrorContains(t, err, "provider 1")
+}
+
+func TestRepository_Books_Metrics(t *testing.T) {
+	t.Parallel()
+
+	mockCtrl := gomock.NewController(t)
+	clientMock := mocks.NewClient(mockCtrl)
+	m := metrics.New()
+	repo := books.New(clientMock, "login", "password", books.WithToken("pre-obtained"), books.WithMetrics(m))
+
+	clientMock.EXPECT().
+		Providers(gomock.Any(), gomock.Any()).
+		Return([]pbclient.Provider{{ShopID: "1"}}, nil)
+
+	gomock.InOrder(
+		clientMock.EXPECT().
+			Books(gomock.Any(), "pre-obtained", 0, 0).
+			Return(pbclient.Books{}, httpErrorMock{code: http.StatusUnauthorized}),
+		clientMock.EXPECT().
+			Login(gomock.Any(), gomock.Any()).
+			Return(pbclient.Token{AccessToken: "token"}, nil),
+		clientMock.EXPECT().
+			Books(gomock.Any(), "token", 0, 0).
+			Return(pbclient.Books{Total: 2}, nil),
+		clientMock.EXPECT().
+			Books(gomock.Any(), "token", 2, 0).
+			Return(pbclient.Books{Books: []pbclient.Book{
+				{Name: "first.txt", Link: "https://example.com/first.txt"},
+				{Name: "second.txt", Link: "https://example.com/second.txt"},
+			}}, nil),
+	)
+
+	_, err := repo.Books(t.Context())
+	require.NoError(t, err)
+
+	var b strings.Builder
+
+	_, err = m.Registry().WriteTo(&b)
+	require.NoError(t, err)
+
+	assert.Contains(t, b.String(), "pbcsync_api_errors_total{code=\"401\"} 1\n")
+	assert.Contains(t, b.String(), "pbcsync_books_listed_total 2\n")
+	assert.Contains(t, b.String(), "pbcsync_listing_duration_seconds_count 1\n")
+}
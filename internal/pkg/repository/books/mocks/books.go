@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source books.go -typed -destination mocks/books.go -package mocks -typed -mock_names client=Client
+//	mockgen -source books.go -typed -destination mocks/books.go -package mocks -typed -mock_names client=Client,tokens=Tokens
 //
 
 // Package mocks is a generated GoMock package.
@@ -157,3 +157,104 @@ func (c *ClientProvidersCall) DoAndReturn(f func(context.Context, string) ([]poc
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
+
+// Tokens is a mock of tokens interface.
+type Tokens struct {
+	ctrl     *gomock.Controller
+	recorder *TokensMockRecorder
+	isgomock struct{}
+}
+
+// TokensMockRecorder is the mock recorder for Tokens.
+type TokensMockRecorder struct {
+	mock *Tokens
+}
+
+// NewTokens creates a new mock instance.
+func NewTokens(ctrl *gomock.Controller) *Tokens {
+	mock := &Tokens{ctrl: ctrl}
+	mock.recorder = &TokensMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Tokens) EXPECT() *TokensMockRecorder {
+	return m.recorder
+}
+
+// Save mocks base method.
+func (m *Tokens) Save(key string, token pocketbook_cloud_client.Token) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", key, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *TokensMockRecorder) Save(key, token any) *TokensSaveCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*Tokens)(nil).Save), key, token)
+	return &TokensSaveCall{Call: call}
+}
+
+// TokensSaveCall wrap *gomock.Call
+type TokensSaveCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *TokensSaveCall) Return(arg0 error) *TokensSaveCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *TokensSaveCall) Do(f func(string, pocketbook_cloud_client.Token) error) *TokensSaveCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *TokensSaveCall) DoAndReturn(f func(string, pocketbook_cloud_client.Token) error) *TokensSaveCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Token mocks base method.
+func (m *Tokens) Token(key string) (pocketbook_cloud_client.Token, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Token", key)
+	ret0, _ := ret[0].(pocketbook_cloud_client.Token)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// Token indicates an expected call of Token.
+func (mr *TokensMockRecorder) Token(key any) *TokensTokenCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Token", reflect.TypeOf((*Tokens)(nil).Token), key)
+	return &TokensTokenCall{Call: call}
+}
+
+// TokensTokenCall wrap *gomock.Call
+type TokensTokenCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *TokensTokenCall) Return(arg0 pocketbook_cloud_client.Token, arg1 bool) *TokensTokenCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *TokensTokenCall) Do(f func(string) (pocketbook_cloud_client.Token, bool)) *TokensTokenCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *TokensTokenCall) DoAndReturn(f func(string) (pocketbook_cloud_client.Token, bool)) *TokensTokenCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
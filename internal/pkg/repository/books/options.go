@@ -0,0 +1,43 @@
+package books
+
+import "github.com/micronull/pocketbook-cloud-sync/internal/pkg/metrics"
+
+type Option func(*Repository)
+
+// WithProviders limits synchronization to the selected providers.
+// Each item is a shop ID or a provider alias. Items with the "-" prefix exclude the provider.
+// If there is at least one including item, all providers not listed are skipped.
+func WithProviders(providers []string) Option {
+	return func(r *Repository) {
+		r.providers = newProviderFilter(providers)
+	}
+}
+
+// WithToken sets a pre-obtained access token.
+// The token is used for all providers, the password is only needed if the token is rejected.
+func WithToken(token string) Option {
+	return func(r *Repository) {
+		r.token = token
+	}
+}
+
+// WithTokens sets the storage to cache tokens between calls and runs.
+func WithTokens(tokens tokens) Option {
+	return func(r *Repository) {
+		r.tokens = tokens
+	}
+}
+
+// WithConcurrency sets how many providers are processed at the same time.
+func WithConcurrency(n int) Option {
+	return func(r *Repository) {
+		r.concurrency = n
+	}
+}
+
+// WithMetrics sets the metrics of listing and API errors.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(r *Repository) {
+		r.metrics = m
+	}
+}
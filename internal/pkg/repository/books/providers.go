@@ -0,0 +1,61 @@
+package books
+
+import (
+	"strings"
+
+	pbclient "github.com/micronull/pocketbook-cloud-client"
+)
+
+const excludePrefix = "-"
+
+type providerFilter struct {
+	include map[string]struct{}
+	exclude map[string]struct{}
+}
+
+func newProviderFilter(providers []string) providerFilter {
+	f := providerFilter{
+		include: make(map[string]struct{}),
+		exclude: make(map[string]struct{}),
+	}
+
+	for _, p := range providers {
+		p = strings.ToLower(strings.TrimSpace(p))
+
+		name, exclude := strings.CutPrefix(p, excludePrefix)
+
+		switch {
+		case name == "":
+			continue
+		case exclude:
+			f.exclude[name] = struct{}{}
+		default:
+			f.include[name] = struct{}{}
+		}
+	}
+
+	return f
+}
+
+// skip reports whether the provider must be skipped and why.
+func (f providerFilter) skip(p pbclient.Provider) (string, bool) {
+	if f.match(f.exclude, p) {
+		return "excluded", true
+	}
+
+	if len(f.include) > 0 && !f.match(f.include, p) {
+		return "not included", true
+	}
+
+	return "", false
+}
+
+func (f providerFilter) match(set map[string]struct{}, p pbclient.Provider) bool {
+	if _, ok := set[strings.ToLower(p.ShopID)]; ok {
+		return true
+	}
+
+	_, ok := set[strings.ToLower(p.Alias)]
+
+	return ok
+}
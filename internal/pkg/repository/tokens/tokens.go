@@ -0,0 +1,84 @@
+package tokens
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	pbclient "github.com/micronull/pocketbook-cloud-client"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+// Repository keeps access tokens of providers.
+// Tokens are kept in memory and, if the path is set, in the file readable only by the owner.
+type Repository struct {
+	path string
+
+	mu     sync.Mutex
+	loaded bool
+	tokens map[string]pbclient.Token
+}
+
+// New construct for [Repository].
+// path - file to persist tokens, empty value keeps tokens only in memory.
+func New(path string) *Repository {
+	return &Repository{
+		path:   path,
+		tokens: make(map[string]pbclient.Token),
+	}
+}
+
+// Token returns the saved token by key.
+func (r *Repository) Token(key string) (pbclient.Token, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.load()
+
+	token, ok := r.tokens[key]
+
+	return token, ok
+}
+
+// Save stores the token by key.
+func (r *Repository) Save(key string, token pbclient.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.load()
+
+	r.tokens[key] = token
+
+	if r.path == "" {
+		return nil
+	}
+
+	if err := statefile.Save(r.path, r.tokens); err != nil {
+		return fmt.Errorf("save tokens: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) load() {
+	if r.loaded || r.path == "" {
+		return
+	}
+
+	r.loaded = true
+
+	tokens := make(map[string]pbclient.Token)
+
+	if err := statefile.Load(r.path, &tokens); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("cached tokens are ignored", "error", err)
+		}
+
+		return
+	}
+
+	r.tokens = tokens
+}
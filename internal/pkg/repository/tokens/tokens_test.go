@@ -0,0 +1,75 @@
+package tokens_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pbclient "github.com/micronull/pocketbook-cloud-client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/repository/tokens"
+)
+
+func TestRepository(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	token := pbclient.Token{
+		AccessToken:  "some access token",
+		TokenType:    pbclient.TokenTypeBearer,
+		ExpiresIn:    time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC),
+		RefreshToken: "some refresh token",
+	}
+
+	repo := tokens.New(path)
+
+	_, ok := repo.Token("some key")
+	assert.False(t, ok)
+
+	err := repo.Save("some key", token)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	got, ok := tokens.New(path).Token("some key")
+	require.True(t, ok)
+
+	assert.Equal(t, token, got)
+}
+
+func TestRepository_InMemory(t *testing.T) {
+	t.Parallel()
+
+	repo := tokens.New("")
+
+	err := repo.Save("some key", pbclient.Token{AccessToken: "some token"})
+	require.NoError(t, err)
+
+	got, ok := repo.Token("some key")
+	require.True(t, ok)
+
+	assert.Equal(t, "some token", got.AccessToken)
+}
+
+func TestRepository_Corrupted(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	err := os.WriteFile(path, []byte("{corrupted"), 0o600)
+	require.NoError(t, err)
+
+	repo := tokens.New(path)
+
+	_, ok := repo.Token("some key")
+	assert.False(t, ok)
+
+	err = repo.Save("some key", pbclient.Token{AccessToken: "some token"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,224 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidCron = errors.New("invalid cron expression")
+
+// maxSearch limits the search of the next activation, e.g. for "0 0 30 2 *" which never happens.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{
+		"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// Sunday is both 0 and 7.
+	dowField = field{name: "day of week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+// Cron is the schedule of the standard 5-field cron expression:
+// minute, hour, day of month, month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true if the day fields start with "*", e.g. "*/2",
+	// otherwise a day matches any of them like in the classic cron.
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+// ParseCron parses the cron expression. Shortcuts like @daily and @hourly are supported.
+// loc - time zone of the expression, [time.Local] if nil.
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	expr = strings.TrimSpace(expr)
+
+	if s, ok := shortcuts[strings.ToLower(expr)]; ok {
+		expr = s
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", errInvalidCron, len(fields))
+	}
+
+	c := &Cron{
+		loc:     loc,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}
+
+	var err error
+
+	for i, p := range []struct {
+		bits *uint64
+		f    field
+	}{
+		{&c.minute, minuteField},
+		{&c.hour, hourField},
+		{&c.dom, domField},
+		{&c.month, monthField},
+		{&c.dow, dowField},
+	} {
+		if *p.bits, err = parseField(fields[i], p.f); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(s, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s %q: %w", errInvalidCron, f.name, s, err)
+		}
+
+		bits |= b
+	}
+
+	return bits, nil
+}
+
+func parseRange(s string, f field) (uint64, error) {
+	rng, stepStr, hasStep := strings.Cut(s, "/")
+
+	step := 1
+
+	if hasStep {
+		var err error
+
+		if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+			return 0, fmt.Errorf("invalid step %q", stepStr)
+		}
+	}
+
+	lo, hi := f.min, f.max
+
+	switch {
+	case rng == "*":
+	case strings.Contains(rng, "-"):
+		l, h, _ := strings.Cut(rng, "-")
+
+		var err error
+
+		if lo, err = f.value(l); err != nil {
+			return 0, err
+		}
+
+		if hi, err = f.value(h); err != nil {
+			return 0, err
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range %q", rng)
+		}
+	default:
+		var err error
+
+		if lo, err = f.value(rng); err != nil {
+			return 0, err
+		}
+
+		if hasStep {
+			hi = f.max
+		} else {
+			hi = lo
+		}
+	}
+
+	var bits uint64
+
+	for v := lo; v <= hi; v += step {
+		bits |= 1 << v
+	}
+
+	return bits, nil
+}
+
+func (f field) value(s string) (int, error) {
+	for i, n := range f.names {
+		if n != "" && strings.EqualFold(s, n) {
+			return i, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// Next returns the first matching minute after t.
+// The zero time is returned if there is no such minute in the next 5 years.
+func (c *Cron) Next(t time.Time) time.Time {
+	orig := t.Location()
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Truncate(time.Minute).Add(time.Minute)
+		default:
+			return t.In(orig)
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
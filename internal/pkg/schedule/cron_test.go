@@ -0,0 +1,103 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+)
+
+func TestCron_Next(t *testing.T) {
+	t.Parallel()
+
+	// Monday.
+	now := time.Date(2025, time.February, 24, 10, 30, 15, 0, time.UTC)
+
+	tests := [...]struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, time.February, 24, 10, 31, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, time.February, 25, 3, 0, 0, 0, time.UTC)},
+		{"45 10 * * *", time.Date(2025, time.February, 24, 10, 45, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2025, time.February, 24, 10, 40, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, time.February, 24, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * sun", time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * jun mon-fri", time.Date(2025, time.June, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day of month or day of week matches.
+		{"0 0 1 * 3", time.Date(2025, time.February, 26, 0, 0, 0, 0, time.UTC)},
+		// The day field starting with "*" doesn't widen the other one.
+		{"0 0 */2 * mon", time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 10 * */3", time.Date(2025, time.May, 10, 0, 0, 0, 0, time.UTC)},
+		{"30 1,2 * * *", time.Date(2025, time.February, 25, 1, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, time.February, 24, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, time.February, 25, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := schedule.ParseCron(tt.expr, time.UTC)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, c.Next(now))
+		})
+	}
+}
+
+func TestCron_Next_Location(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	c, err := schedule.ParseCron("0 3 * * *", loc)
+	require.NoError(t, err)
+
+	now := time.Date(2025, time.March, 29, 12, 0, 0, 0, time.UTC)
+
+	// Daylight saving time starts on March 30.
+	assert.Equal(t, time.Date(2025, time.March, 30, 1, 0, 0, 0, time.UTC), c.Next(now).UTC())
+	assert.Equal(t, time.UTC, c.Next(now).Location())
+
+	next := c.Next(c.Next(now))
+	assert.Equal(t, time.Date(2025, time.March, 31, 1, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseCron_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		expr   string
+		expect string
+	}{
+		{"", "invalid cron expression: expected 5 fields, got 0"},
+		{"* * * *", "invalid cron expression: expected 5 fields, got 4"},
+		{"60 * * * *", `invalid cron expression: minute "60": value "60" out of range 0-59`},
+		{"* 5-1 * * *", `invalid cron expression: hour "5-1": invalid range "5-1"`},
+		{"*/0 * * * *", `invalid cron expression: minute "*/0": invalid step "0"`},
+		{"* * 0 * *", `invalid cron expression: day of month "0": value "0" out of range 1-31`},
+		{"* * * foo *", `invalid cron expression: month "foo": value "foo" out of range 1-12`},
+		{"@often", "invalid cron expression: expected 5 fields, got 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := schedule.ParseCron(tt.expr, time.UTC)
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
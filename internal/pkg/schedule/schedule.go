@@ -0,0 +1,30 @@
+// Package schedule computes times of the next synchronizations.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errInvalidInterval = errors.New("invalid interval")
+
+type Schedule interface {
+	// Next returns the next activation time, later than t.
+	Next(t time.Time) time.Time
+}
+
+type every time.Duration
+
+// Every returns the schedule activating the duration after the given time, the duration must be positive.
+func Every(d time.Duration) (Schedule, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("%w %s: must be positive", errInvalidInterval, d)
+	}
+
+	return every(d), nil
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
@@ -0,0 +1,43 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/schedule"
+)
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.February, 24, 10, 30, 15, 0, time.UTC)
+
+	s, err := schedule.Every(time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, now.Add(time.Hour), s.Next(now))
+}
+
+func TestEvery_Error(t *testing.T) {
+	t.Parallel()
+
+	tests := [...]struct {
+		d      time.Duration
+		expect string
+	}{
+		{0, "invalid interval 0s: must be positive"},
+		{-time.Minute, "invalid interval -1m0s: must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			t.Parallel()
+
+			_, err := schedule.Every(tt.d)
+			require.EqualError(t, err, tt.expect)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Package shutdown implements the two-phase shutdown.
+// The first signal starts draining: the running downloads are finished, but no new ones are started.
+// The second signal or the end of the grace period aborts the running downloads.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// ExitCodeAborted is the exit status of the process after the aborted shutdown.
+const ExitCodeAborted = 3
+
+var (
+	// ErrDrained is returned by the work stopped gracefully by the draining.
+	ErrDrained = errors.New("stopped by shutdown")
+	// ErrAborted is the cause of the context cancelled by the second signal or the end of the grace period.
+	ErrAborted = errors.New("shutdown is aborted")
+)
+
+type drainKey struct{}
+
+// WithDrain returns the context carrying the drain channel and the function starting the draining.
+func WithDrain(parent context.Context) (context.Context, func()) {
+	ch := make(chan struct{})
+
+	var once sync.Once
+
+	return context.WithValue(parent, drainKey{}, ch), func() { once.Do(func() { close(ch) }) }
+}
+
+// Draining returns the channel closed when the draining starts.
+// It is nil for the context without the drain, so it never fires.
+func Draining(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(drainKey{}).(chan struct{})
+
+	return ch
+}
+
+// IsDraining reports whether the draining has started.
+func IsDraining(ctx context.Context) bool {
+	select {
+	case <-Draining(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// Notify returns the context drained on the first of the signals
+// and cancelled with [ErrAborted] on the second signal or after the grace period.
+func Notify(parent context.Context, grace time.Duration, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ch := make(chan os.Signal, 1)
+
+	signal.Notify(ch, signals...)
+
+	ctx, cancel := context.WithCancelCause(parent)
+	ctx, drain := WithDrain(ctx)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			slog.Info("shutdown, waiting for running downloads", "signal", sig, "grace", grace)
+			drain()
+		}
+
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-ch:
+			slog.Warn("shutdown is forced", "signal", sig)
+		case <-timer.C:
+			slog.Warn("shutdown grace period is over")
+		}
+
+		cancel(ErrAborted)
+	}()
+
+	return ctx, func() { cancel(context.Canceled) }
+}
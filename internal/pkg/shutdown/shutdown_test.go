@@ -0,0 +1,31 @@
+package shutdown_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+)
+
+func TestWithDrain(t *testing.T) {
+	t.Parallel()
+
+	ctx, drain := shutdown.WithDrain(t.Context())
+
+	assert.False(t, shutdown.IsDraining(ctx))
+
+	drain()
+	drain()
+
+	assert.True(t, shutdown.IsDraining(ctx))
+	assert.True(t, shutdown.IsDraining(context.WithoutCancel(ctx)))
+}
+
+func TestDraining_NoDrain(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, shutdown.Draining(t.Context()))
+	assert.False(t, shutdown.IsDraining(t.Context()))
+}
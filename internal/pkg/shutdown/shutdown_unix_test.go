@@ -0,0 +1,78 @@
+//go:build unix
+
+package shutdown_test
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/shutdown"
+)
+
+// The tests send the signal to the own process, so they are not parallel.
+
+func notify(t *testing.T, grace time.Duration) context.Context {
+	t.Helper()
+
+	// Keeps the process alive if the signal comes after the shutdown stops watching.
+	guard := make(chan os.Signal, 1)
+
+	signal.Notify(guard, syscall.SIGUSR2)
+	t.Cleanup(func() { signal.Stop(guard) })
+
+	ctx, stop := shutdown.Notify(t.Context(), grace, syscall.SIGUSR2)
+	t.Cleanup(stop)
+
+	return ctx
+}
+
+func kill(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+}
+
+func TestNotify_SecondSignal(t *testing.T) {
+	ctx := notify(t, time.Hour)
+
+	kill(t)
+
+	<-shutdown.Draining(ctx)
+
+	assert.NoError(t, ctx.Err())
+
+	kill(t)
+
+	<-ctx.Done()
+
+	assert.ErrorIs(t, context.Cause(ctx), shutdown.ErrAborted)
+}
+
+func TestNotify_GracePeriod(t *testing.T) {
+	ctx := notify(t, 50*time.Millisecond)
+
+	kill(t)
+
+	<-ctx.Done()
+
+	assert.True(t, shutdown.IsDraining(ctx))
+	assert.ErrorIs(t, context.Cause(ctx), shutdown.ErrAborted)
+}
+
+func TestNotify_Stop(t *testing.T) {
+	ctx, stop := shutdown.Notify(t.Context(), time.Hour, syscall.SIGUSR2)
+
+	stop()
+
+	<-ctx.Done()
+
+	assert.False(t, shutdown.IsDraining(ctx))
+	assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+}
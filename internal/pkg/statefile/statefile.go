@@ -0,0 +1,83 @@
+// Package statefile stores small JSON documents on disk.
+// Files are readable only by the owner because they can contain secrets.
+package statefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirName is the name of the state directory inside the sync directory by default.
+const DirName = ".pbcsync"
+
+const (
+	dirPerm  = 0o700
+	filePerm = 0o600
+)
+
+// Load reads the JSON document from path into v.
+// A missing file is reported as an error wrapping [os.ErrNotExist].
+func Load(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unmarshal %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Save writes v to path as JSON.
+// The file is replaced atomically by [WriteFile].
+func Save(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	return WriteFile(path, data, filePerm)
+}
+
+// WriteFile replaces the file atomically with the data, so readers never see a partially written file.
+// The missing directory is created readable only by the owner.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err = tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package statefile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/statefile"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "state", "test.json")
+
+	type doc struct {
+		Value string `json:"value"`
+	}
+
+	err := statefile.Save(path, doc{Value: "some value"})
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	var got doc
+
+	err = statefile.Load(path, &got)
+	require.NoError(t, err)
+
+	assert.Equal(t, doc{Value: "some value"}, got)
+}
+
+func TestLoad_NotExist(t *testing.T) {
+	t.Parallel()
+
+	var v struct{}
+
+	err := statefile.Load(filepath.Join(t.TempDir(), "unknown.json"), &v)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,134 @@
+// Package systemd implements the notification protocol of systemd services with Type=notify:
+// readiness, status text, watchdog pings and stopping.
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables set by systemd.
+const (
+	EnvNotifySocket = "NOTIFY_SOCKET"
+	EnvWatchdogUsec = "WATCHDOG_USEC"
+	EnvWatchdogPID  = "WATCHDOG_PID"
+)
+
+var errInvalidWatchdog = errors.New("invalid watchdog interval")
+
+// Notifier sends the notifications to the socket of the service manager, it is nil outside systemd.
+type Notifier struct {
+	socket   string
+	watchdog time.Duration
+}
+
+// FromEnv returns the notifier of the service manager or nil if the process isn't started by systemd with Type=notify.
+// The watchdog is enabled if the interval is set for this process.
+func FromEnv() (*Notifier, error) {
+	socket := os.Getenv(EnvNotifySocket)
+	if socket == "" {
+		return nil, nil
+	}
+
+	n := &Notifier{socket: socket}
+
+	usec := os.Getenv(EnvWatchdogUsec)
+	if usec == "" {
+		return n, nil
+	}
+
+	if pid := os.Getenv(EnvWatchdogPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return n, nil
+	}
+
+	us, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || us <= 0 {
+		return nil, fmt.Errorf("%w %q", errInvalidWatchdog, usec)
+	}
+
+	n.watchdog = time.Duration(us) * time.Microsecond
+
+	return n, nil
+}
+
+// Notify sends the state, e.g. "READY=1". The state may contain several newline-separated assignments.
+func (n *Notifier) Notify(state string) error {
+	if n == nil {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: n.socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("dial notify socket: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return fmt.Errorf("write notify socket: %w", err)
+	}
+
+	return nil
+}
+
+// Ready tells that the service has started.
+func (n *Notifier) Ready() {
+	n.send("READY=1")
+}
+
+// Stopping tells that the service is stopping.
+func (n *Notifier) Stopping() {
+	n.send("STOPPING=1")
+}
+
+// Status sets the status text shown by systemctl status.
+func (n *Notifier) Status(text string) {
+	n.send("STATUS=" + text)
+}
+
+func (n *Notifier) send(state string) {
+	if err := n.Notify(state); err != nil {
+		slog.Debug("failed to notify systemd", "error", err)
+	}
+}
+
+// WatchdogInterval returns the interval of the watchdog, zero if it is disabled.
+func (n *Notifier) WatchdogInterval() time.Duration {
+	if n == nil {
+		return 0
+	}
+
+	return n.watchdog
+}
+
+// Watchdog pings the watchdog twice per its interval while healthy reports true, until the context is done.
+// Without pings systemd considers the service hung and restarts it.
+func (n *Notifier) Watchdog(ctx context.Context, healthy func() bool) {
+	interval := n.WatchdogInterval()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		if healthy() {
+			n.send("WATCHDOG=1")
+		} else {
+			slog.Warn("watchdog isn't pinged, the service is unhealthy")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
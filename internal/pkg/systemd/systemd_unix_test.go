@@ -0,0 +1,141 @@
+//go:build unix
+
+package systemd_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/systemd"
+)
+
+// listen creates the notify socket and returns the channel of the received states.
+func listen(t *testing.T) <-chan string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	t.Setenv(systemd.EnvNotifySocket, path)
+
+	states := make(chan string, 100)
+
+	go func() {
+		buf := make([]byte, 1024)
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+
+			states <- string(buf[:n])
+		}
+	}()
+
+	return states
+}
+
+func TestNotifier(t *testing.T) {
+	states := listen(t)
+
+	n, err := systemd.FromEnv()
+	require.NoError(t, err)
+	require.NotNil(t, n)
+	assert.Zero(t, n.WatchdogInterval())
+
+	n.Ready()
+	n.Status("downloading 12/340")
+	n.Stopping()
+
+	assert.Equal(t, "READY=1", <-states)
+	assert.Equal(t, "STATUS=downloading 12/340", <-states)
+	assert.Equal(t, "STOPPING=1", <-states)
+}
+
+func TestNotifier_Watchdog(t *testing.T) {
+	states := listen(t)
+
+	t.Setenv(systemd.EnvWatchdogUsec, "20000")
+	t.Setenv(systemd.EnvWatchdogPID, strconv.Itoa(os.Getpid()))
+
+	n, err := systemd.FromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Millisecond, n.WatchdogInterval())
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	// Healthy twice, then unhealthy until stopped.
+	health := []bool{true, true, false, false}
+	checks := 0
+
+	n.Watchdog(ctx, func() bool {
+		h := health[checks]
+
+		if checks++; checks == len(health) {
+			cancel()
+		}
+
+		return h
+	})
+
+	assert.Equal(t, "WATCHDOG=1", <-states)
+	assert.Equal(t, "WATCHDOG=1", <-states)
+	assert.Never(t, func() bool { return len(states) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := [...]struct {
+		name    string
+		socket  string
+		usec    string
+		pid     string
+		want    time.Duration
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "no socket", wantNil: true},
+		{name: "no watchdog", socket: "/run/notify"},
+		{name: "watchdog", socket: "/run/notify", usec: "30000000", want: 30 * time.Second},
+		{name: "watchdog of another process", socket: "/run/notify", usec: "30000000", pid: "1"},
+		{name: "invalid watchdog", socket: "/run/notify", usec: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(systemd.EnvNotifySocket, tt.socket)
+			t.Setenv(systemd.EnvWatchdogUsec, tt.usec)
+			t.Setenv(systemd.EnvWatchdogPID, tt.pid)
+
+			n, err := systemd.FromEnv()
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			if tt.wantNil {
+				assert.Nil(t, n)
+
+				return
+			}
+
+			require.NotNil(t, n)
+			assert.Equal(t, tt.want, n.WatchdogInterval())
+		})
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package trigger
+
+import "context"
+
+// WatchSignal does nothing, because SIGUSR1 is not supported on this platform.
+func (t *Trigger) WatchSignal(ctx context.Context) {
+	<-ctx.Done()
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package trigger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WatchSignal fires the trigger on SIGUSR1 until the context is done.
+func (t *Trigger) WatchSignal(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+
+	signal.Notify(ch, syscall.SIGUSR1)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			slog.Info("sync triggered by signal")
+			t.Fire()
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package trigger_test
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/trigger"
+)
+
+func TestTrigger_WatchSignal(t *testing.T) {
+	// Keeps the process alive if the signal comes before the trigger starts watching.
+	guard := make(chan os.Signal, 1)
+
+	signal.Notify(guard, syscall.SIGUSR1)
+	t.Cleanup(func() { signal.Stop(guard) })
+
+	trg := trigger.New()
+
+	go trg.WatchSignal(t.Context())
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	timeout := time.After(5 * time.Second)
+
+	for {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+		select {
+		case <-trg.C():
+			return
+		case <-timeout:
+			require.Fail(t, "trigger is not fired")
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package trigger requests on-demand synchronizations from outside the process.
+package trigger
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+)
+
+// DefaultInterval is how often the trigger file is checked by default.
+const DefaultInterval = 5 * time.Second
+
+// Trigger collects requests of synchronization.
+// Requests made before the previous one is handled are coalesced into one.
+type Trigger struct {
+	c chan struct{}
+}
+
+func New() *Trigger {
+	return &Trigger{c: make(chan struct{}, 1)}
+}
+
+// Fire requests the synchronization, it never blocks.
+func (t *Trigger) Fire() {
+	select {
+	case t.c <- struct{}{}:
+	default:
+	}
+}
+
+// C returns the channel receiving the requests.
+func (t *Trigger) C() <-chan struct{} {
+	return t.c
+}
+
+// WatchFile fires the trigger when the file appears and removes the file.
+// It checks the file every interval until the context is done.
+func (t *Trigger) WatchFile(ctx context.Context, path string, interval time.Duration, clk clock.Clock) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clk.After(interval):
+		}
+
+		_, err := os.Stat(path)
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			slog.Warn("failed to check trigger file", "path", path, "error", err)
+
+			continue
+		}
+
+		// The file is removed before the firing, so a file left in place doesn't cause endless synchronizations.
+		if err = os.Remove(path); err != nil {
+			slog.Warn("failed to remove trigger file", "path", path, "error", err)
+
+			continue
+		}
+
+		slog.Info("sync triggered by file", "path", path)
+		t.Fire()
+	}
+}
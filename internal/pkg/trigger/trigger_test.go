@@ -0,0 +1,75 @@
+package trigger_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/clock"
+	"github.com/micronull/pocketbook-cloud-sync/internal/pkg/trigger"
+)
+
+func TestTrigger_Fire(t *testing.T) {
+	t.Parallel()
+
+	trg := trigger.New()
+
+	trg.Fire()
+	trg.Fire()
+	trg.Fire()
+
+	select {
+	case <-trg.C():
+	default:
+		require.Fail(t, "trigger is not fired")
+	}
+
+	select {
+	case <-trg.C():
+		require.Fail(t, "triggers are not coalesced")
+	default:
+	}
+}
+
+func TestTrigger_WatchFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "trigger")
+	fake := clock.NewFake(time.Now())
+	trg := trigger.New()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		trg.WatchFile(ctx, path, time.Second, fake)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	fake.BlockUntil(1)
+
+	select {
+	case <-trg.C():
+		require.Fail(t, "trigger is fired without file")
+	default:
+	}
+
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	fake.Advance(time.Second)
+
+	<-trg.C()
+
+	assert.NoFileExists(t, path)
+
+	cancel()
+	<-done
+}